@@ -1,6 +1,9 @@
 package analyzer
 
-import "math/big"
+import (
+	"context"
+	"math/big"
+)
 
 // Analyzer provides gas and fee analysis capabilities.
 //
@@ -8,7 +11,7 @@ import "math/big"
 // normalized GasAnalysis that focuses on execution-layer fee behavior.
 type Analyzer interface {
 	// AnalyzeGas analyzes gas usage and fees for a single transaction hash.
-	AnalyzeGas(txHash string) (*GasAnalysis, error)
+	AnalyzeGas(ctx context.Context, txHash string) (*GasAnalysis, error)
 }
 
 // GasComponent represents a labeled fee component (e.g. base, priority, blob).
@@ -26,6 +29,7 @@ type GasAnalysis struct {
 	TxHash      string // 32-byte transaction hash (0x-prefixed)
 	BlockHash   string // containing block hash (0x-prefixed)
 	BlockNumber uint64
+	Fork        string // hardfork whose rules applied, empty if unknown
 
 	// Gas usage.
 	GasUsed  uint64
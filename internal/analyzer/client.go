@@ -0,0 +1,249 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/luckify/getho/internal/chain"
+	"github.com/luckify/getho/internal/client"
+	"github.com/luckify/getho/internal/decoder"
+)
+
+// ClientAnalyzer implements the Analyzer interface on top of a client.Client.
+type ClientAnalyzer struct {
+	client client.Client
+	chain  *chain.Chain
+}
+
+// NewClientAnalyzer creates an analyzer that fetches transaction data through
+// c and applies the fork rules of ch. A nil ch is detected from c on first
+// use.
+func NewClientAnalyzer(c client.Client, ch *chain.Chain) *ClientAnalyzer {
+	return &ClientAnalyzer{client: c, chain: ch}
+}
+
+// AnalyzeGas analyzes gas usage and fees for a single transaction hash.
+func (a *ClientAnalyzer) AnalyzeGas(ctx context.Context, txHash string) (*GasAnalysis, error) {
+	hash := common.HexToHash(txHash)
+	bundle, err := client.GetTransactionBundle(ctx, a.client, hash)
+	if errors.Is(err, types.ErrTxTypeNotSupported) {
+		return a.analyzeRaw(ctx, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return nil, fmt.Errorf("transaction not found: %s", txHash)
+	}
+	if bundle.IsPending {
+		return nil, errors.New("transaction is pending; gas analysis requires a receipt")
+	}
+	if bundle.ReceiptErr != nil {
+		return nil, fmt.Errorf("transaction receipt not available: %w", bundle.ReceiptErr)
+	}
+	if bundle.Receipt == nil {
+		return nil, errors.New("transaction receipt not available")
+	}
+	if a.chain == nil {
+		if a.chain, err = client.DetectChain(ctx, a.client); err != nil {
+			return nil, err
+		}
+	}
+	return Analyze(a.chain, bundle.Transaction, bundle.Receipt, bundle.Header)
+}
+
+// analyzeRaw is AnalyzeGas for transaction types that go-ethereum cannot
+// decode, such as EIP-7702 set-code transactions: the transaction is fetched
+// in its binary encoding and decoded by getho.
+func (a *ClientAnalyzer) analyzeRaw(ctx context.Context, hash common.Hash) (*GasAnalysis, error) {
+	raw, err := client.GetRawTransaction(ctx, a.client, hash)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("transaction not found: %s", hash.Hex())
+	}
+	receipt, err := a.client.GetTransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("transaction receipt not available: %w", err)
+	}
+	if receipt == nil {
+		return nil, errors.New("transaction is pending; gas analysis requires a receipt")
+	}
+	header, err := a.client.GetBlockHeader(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if a.chain == nil {
+		if a.chain, err = client.DetectChain(ctx, a.client); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := decoder.NewEthereumDecoderForChain(a.chain).DecodeTransactionAt(raw, receipt, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	return AnalyzeDecoded(a.chain, tx, receipt, header)
+}
+
+// txFees are the fields of a transaction that its fees are derived from.
+type txFees struct {
+	hash    common.Hash
+	chainID *big.Int
+	gas     uint64
+
+	gasPrice *big.Int // legacy and access list transactions
+	feeCap   *big.Int // fee market (EIP-1559) transactions, nil for the others
+	tipCap   *big.Int
+
+	blobGas    uint64
+	blobFeeCap *big.Int // blob transactions, nil for the others
+}
+
+// Analyze builds a GasAnalysis from a mined transaction on ch, its receipt
+// and the header of its containing block. header may be nil, in which case
+// base fee dependent values are omitted and a note is recorded.
+func Analyze(ch *chain.Chain, tx *types.Transaction, receipt *types.Receipt, header *types.Header) (*GasAnalysis, error) {
+	if tx == nil || receipt == nil {
+		return nil, errors.New("transaction and receipt are required")
+	}
+	fees := txFees{hash: tx.Hash(), chainID: tx.ChainId(), gas: tx.Gas()}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		fees.gasPrice = tx.GasPrice()
+	default:
+		fees.feeCap, fees.tipCap = tx.GasFeeCap(), tx.GasTipCap()
+	}
+	if tx.Type() == types.BlobTxType {
+		fees.blobGas, fees.blobFeeCap = tx.BlobGas(), tx.BlobGasFeeCap()
+	}
+	return analyze(ch, fees, receipt, header)
+}
+
+// AnalyzeDecoded is Analyze for a transaction decoded by getho, which may be
+// of a type go-ethereum cannot represent.
+func AnalyzeDecoded(ch *chain.Chain, tx *decoder.Transaction, receipt *types.Receipt, header *types.Header) (*GasAnalysis, error) {
+	if tx == nil || receipt == nil {
+		return nil, errors.New("transaction and receipt are required")
+	}
+	fees := txFees{hash: common.HexToHash(tx.Hash), chainID: tx.ChainID, gas: tx.GasLimit}
+	switch tx.Type {
+	case decoder.TransactionTypeLegacy, decoder.TransactionTypeAccessList:
+		fees.gasPrice = tx.GasPrice
+	default:
+		fees.feeCap, fees.tipCap = tx.MaxFeePerGas, tx.MaxPriorityFeePerGas
+	}
+	if tx.Type == decoder.TransactionTypeBlob {
+		fees.blobGas = uint64(len(tx.BlobHashes)) * params.BlobTxBlobGasPerBlob
+		fees.blobFeeCap = tx.BlobGasFeeCap
+	}
+	return analyze(ch, fees, receipt, header)
+}
+
+// analyze implements Analyze and AnalyzeDecoded.
+func analyze(ch *chain.Chain, tx txFees, receipt *types.Receipt, header *types.Header) (*GasAnalysis, error) {
+	if ch == nil {
+		ch = chain.Unknown(tx.chainID)
+	}
+
+	result := &GasAnalysis{
+		TxHash:    tx.hash.Hex(),
+		BlockHash: receipt.BlockHash.Hex(),
+		GasUsed:   receipt.GasUsed,
+		GasLimit:  tx.gas,
+	}
+	if receipt.BlockNumber != nil {
+		result.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	var rules params.Rules
+	if header != nil {
+		rules = ch.Rules(header.Number, header.Time)
+		result.Fork = chain.ForkName(rules)
+	}
+
+	// Fee configuration.
+	result.GasPrice = tx.gasPrice
+	result.MaxFeePerGas = tx.feeCap
+	result.MaxPriorityFeePerGas = tx.tipCap
+
+	// Base fee context.
+	if header == nil {
+		result.Notes = append(result.Notes, "block header unavailable; base fee breakdown omitted")
+	} else if !rules.IsLondon || header.BaseFee == nil {
+		result.Notes = append(result.Notes, "pre-EIP-1559 block: no base fee, entire fee goes to the miner")
+	} else {
+		result.BaseFeePerGas = header.BaseFee
+	}
+
+	// Effective price, preferring what the node reports.
+	result.EffectiveGasPrice = receipt.EffectiveGasPrice
+	if result.EffectiveGasPrice == nil {
+		result.EffectiveGasPrice = effectiveGasPrice(tx, result.BaseFeePerGas)
+		result.Notes = append(result.Notes, "effective gas price derived from transaction fields")
+	}
+
+	gasUsed := new(big.Int).SetUint64(result.GasUsed)
+	result.TotalFeePaid = new(big.Int).Mul(result.EffectiveGasPrice, gasUsed)
+	if result.BaseFeePerGas != nil {
+		result.BaseFeeBurnt = new(big.Int).Mul(result.BaseFeePerGas, gasUsed)
+		result.PriorityFee = new(big.Int).Sub(result.TotalFeePaid, result.BaseFeeBurnt)
+	} else if header != nil {
+		result.PriorityFee = new(big.Int).Set(result.TotalFeePaid)
+	}
+
+	// Blob gas (EIP-4844).
+	if tx.blobFeeCap != nil {
+		result.BlobGasUsed = receipt.BlobGasUsed
+		if result.BlobGasUsed == 0 {
+			result.BlobGasUsed = tx.blobGas
+		}
+		result.BlobGasFeeCap = tx.blobFeeCap
+		result.BlobGasPrice = receipt.BlobGasPrice
+		if result.BlobGasPrice != nil {
+			result.TotalBlobFeePaid = new(big.Int).Mul(result.BlobGasPrice, new(big.Int).SetUint64(result.BlobGasUsed))
+		} else {
+			result.Notes = append(result.Notes, "receipt does not report blob gas price; blob fee omitted")
+		}
+	}
+
+	result.TotalExecutionAndBlob = new(big.Int).Set(result.TotalFeePaid)
+	if result.TotalBlobFeePaid != nil {
+		result.TotalExecutionAndBlob.Add(result.TotalExecutionAndBlob, result.TotalBlobFeePaid)
+	}
+
+	// Presentation breakdown.
+	if result.BaseFeeBurnt != nil {
+		result.Components = append(result.Components, GasComponent{Label: "base", Value: result.BaseFeeBurnt})
+	}
+	if result.PriorityFee != nil {
+		result.Components = append(result.Components, GasComponent{Label: "priority", Value: result.PriorityFee})
+	}
+	if result.TotalBlobFeePaid != nil {
+		result.Components = append(result.Components, GasComponent{Label: "blob", Value: result.TotalBlobFeePaid})
+	}
+
+	return result, nil
+}
+
+// effectiveGasPrice computes the per-gas price paid by tx given the block
+// base fee (nil for pre-London blocks).
+func effectiveGasPrice(tx txFees, baseFee *big.Int) *big.Int {
+	if tx.feeCap == nil {
+		return new(big.Int).Set(tx.gasPrice)
+	}
+	if baseFee == nil || tx.feeCap.Cmp(baseFee) < 0 {
+		// Fee cap below base fee; the transaction could not have been included
+		// under these rules, so fall back to the cap itself.
+		return new(big.Int).Set(tx.feeCap)
+	}
+	tip := new(big.Int).Sub(tx.feeCap, baseFee)
+	if tip.Cmp(tx.tipCap) > 0 {
+		tip.Set(tx.tipCap)
+	}
+	return tip.Add(tip, baseFee)
+}
@@ -0,0 +1,318 @@
+// Package chain provides a registry of known Ethereum networks and their
+// fork schedules, so that transactions can be interpreted under the rules
+// that applied at their block.
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Chain describes a network and its fork schedule.
+type Chain struct {
+	// Name is the network name, e.g. "mainnet".
+	Name string
+
+	// Config holds the fork schedule.
+	Config *params.ChainConfig
+
+	// MergeBlock is the first proof-of-stake block, or nil if the chain has
+	// not transitioned (or its transition block is unknown). Block-number
+	// based configs cannot express the merge, so it is tracked separately.
+	MergeBlock *big.Int
+
+	// BlobSchedule lists the blob parameters from Cancun on, ordered by
+	// activation time. The go-ethereum release getho is built with predates
+	// blob parameter only (BPO) forks, so they are tracked separately too.
+	BlobSchedule []BlobFork
+}
+
+// BlobConfig holds the blob parameters of a fork.
+type BlobConfig struct {
+	Target         uint64 // target blobs per block
+	Max            uint64 // maximum blobs per block
+	UpdateFraction uint64 // blob base fee update fraction
+}
+
+// BlobFork activates a BlobConfig at a timestamp.
+type BlobFork struct {
+	Name   string
+	Time   uint64
+	Config BlobConfig
+}
+
+// Blob parameters of the forks that changed them: EIP-4844 in Cancun,
+// EIP-7691 in Prague and the BPO forks following Osaka (EIP-7892).
+var (
+	BlobCancun = BlobConfig{Target: 3, Max: 6, UpdateFraction: 3338477}
+	BlobPrague = BlobConfig{Target: 6, Max: 9, UpdateFraction: 5007716}
+	BlobBPO1   = BlobConfig{Target: 10, Max: 15, UpdateFraction: 8346193}
+	BlobBPO2   = BlobConfig{Target: 14, Max: 21, UpdateFraction: 11684671}
+)
+
+// Known networks. Their configs extend go-ethereum's with forks scheduled
+// after the go-ethereum release getho is built with.
+var (
+	Mainnet = &Chain{
+		Name:       "mainnet",
+		Config:     mainnetConfig,
+		MergeBlock: big.NewInt(15537394),
+		BlobSchedule: append(blobSchedule(mainnetConfig),
+			BlobFork{Name: "BPO1", Time: 1765290071, Config: BlobBPO1},
+			BlobFork{Name: "BPO2", Time: 1767747671, Config: BlobBPO2}),
+	}
+	Sepolia = &Chain{
+		Name:       "sepolia",
+		Config:     sepoliaConfig,
+		MergeBlock: big.NewInt(1735371),
+		BlobSchedule: append(blobSchedule(sepoliaConfig),
+			BlobFork{Name: "BPO1", Time: 1761017184, Config: BlobBPO1},
+			BlobFork{Name: "BPO2", Time: 1761607008, Config: BlobBPO2}),
+	}
+	Holesky = &Chain{
+		Name:       "holesky",
+		Config:     holeskyConfig,
+		MergeBlock: big.NewInt(0),
+		BlobSchedule: append(blobSchedule(holeskyConfig),
+			BlobFork{Name: "BPO1", Time: 1759800000, Config: BlobBPO1},
+			BlobFork{Name: "BPO2", Time: 1760389824, Config: BlobBPO2}),
+	}
+
+	mainnetConfig = withPrague(params.MainnetChainConfig, 1746612311)
+	sepoliaConfig = withPrague(params.SepoliaChainConfig, 1741159776)
+	holeskyConfig = withPrague(params.HoleskyChainConfig, 1740434112)
+)
+
+// withPrague returns a copy of config with Prague scheduled at time.
+func withPrague(config *params.ChainConfig, time uint64) *params.ChainConfig {
+	copied := *config
+	copied.PragueTime = &time
+	return &copied
+}
+
+// blobSchedule returns the blob schedule of the Cancun and Prague times of
+// config.
+func blobSchedule(config *params.ChainConfig) []BlobFork {
+	var schedule []BlobFork
+	if config.CancunTime != nil {
+		schedule = append(schedule, BlobFork{Name: "Cancun", Time: *config.CancunTime, Config: BlobCancun})
+	}
+	if config.PragueTime != nil {
+		schedule = append(schedule, BlobFork{Name: "Prague", Time: *config.PragueTime, Config: BlobPrague})
+	}
+	return schedule
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]*Chain{}
+)
+
+func init() {
+	for _, c := range []*Chain{Mainnet, Sepolia, Holesky} {
+		Register(c)
+	}
+}
+
+// Register adds c to the registry, replacing any chain with the same chain
+// ID.
+func Register(c *Chain) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[c.Config.ChainID.String()] = c
+}
+
+// ByID returns the registered chain with the given chain ID.
+func ByID(id *big.Int) (*Chain, bool) {
+	if id == nil {
+		return nil, false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := registry[id.String()]
+	return c, ok
+}
+
+// ByName returns the registered chain with the given name.
+func ByName(name string) (*Chain, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, c := range registry {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// All returns the registered chains, ordered by chain ID.
+func All() []*Chain {
+	mu.RLock()
+	defer mu.RUnlock()
+	chains := make([]*Chain, 0, len(registry))
+	for _, c := range registry {
+		chains = append(chains, c)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i].Config.ChainID.Cmp(chains[j].Config.ChainID) < 0 })
+	return chains
+}
+
+// Unknown returns a chain for an unregistered chain ID, on which every fork
+// known to go-ethereum is assumed active from genesis.
+func Unknown(id *big.Int) *Chain {
+	config := *params.AllDevChainProtocolChanges
+	config.ChainID = new(big.Int).Set(id)
+	prague := uint64(0)
+	config.PragueTime = &prague
+	return &Chain{Name: "unknown", Config: &config, MergeBlock: big.NewInt(0), BlobSchedule: blobSchedule(&config)}
+}
+
+// FromConfig returns the registered chain matching config's chain ID, or a
+// chain named after the ID using config otherwise.
+func FromConfig(config *params.ChainConfig) *Chain {
+	if c, ok := ByID(config.ChainID); ok {
+		return c
+	}
+	return &Chain{Name: "chain " + config.ChainID.String(), Config: config, MergeBlock: mergeBlock(config), BlobSchedule: blobSchedule(config)}
+}
+
+// Load reads a custom chain from a genesis file (as passed to geth init) or
+// a bare chain config JSON file. The chain is named after the file unless
+// the genesis names it.
+func Load(path string) (*Chain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var genesis struct {
+		Name   string              `json:"name"`
+		Config *params.ChainConfig `json:"config"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("invalid chain config %s: %w", path, err)
+	}
+	config := genesis.Config
+	if config == nil {
+		config = new(params.ChainConfig)
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("invalid chain config %s: %w", path, err)
+		}
+	}
+	if config.ChainID == nil {
+		return nil, fmt.Errorf("invalid chain config %s: missing chainId", path)
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		return nil, fmt.Errorf("invalid chain config %s: %w", path, err)
+	}
+
+	name := genesis.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	return &Chain{Name: name, Config: config, MergeBlock: mergeBlock(config), BlobSchedule: blobSchedule(config)}, nil
+}
+
+// mergeBlock infers the merge block of a custom config: the netsplit block
+// if one is set, or genesis for chains that start out post-merge.
+func mergeBlock(config *params.ChainConfig) *big.Int {
+	switch {
+	case config.MergeNetsplitBlock != nil:
+		return config.MergeNetsplitBlock
+	case config.TerminalTotalDifficulty != nil && config.TerminalTotalDifficulty.Sign() == 0:
+		return big.NewInt(0)
+	}
+	return nil
+}
+
+// IsMerged reports whether block number is a proof-of-stake block.
+func (c *Chain) IsMerged(number *big.Int) bool {
+	return c.MergeBlock != nil && number != nil && number.Cmp(c.MergeBlock) >= 0
+}
+
+// farFuture stands in for the block of pending transactions, which are
+// judged by the rules of the latest scheduled fork.
+var farFuture = new(big.Int).SetUint64(math.MaxUint64)
+
+// at resolves a nil block number to the far future.
+func at(number *big.Int, time uint64) (*big.Int, uint64) {
+	if number == nil {
+		return farFuture, math.MaxUint64
+	}
+	return number, time
+}
+
+// Rules returns the protocol rules active at a block. A nil number selects
+// the latest scheduled fork, e.g. for pending transactions.
+func (c *Chain) Rules(number *big.Int, time uint64) params.Rules {
+	number, time = at(number, time)
+	return c.Config.Rules(number, c.IsMerged(number), time)
+}
+
+// Signer returns the transaction signer valid at a block. A nil number
+// selects the latest scheduled fork.
+func (c *Chain) Signer(number *big.Int, time uint64) types.Signer {
+	number, time = at(number, time)
+	return types.MakeSigner(c.Config, number, time)
+}
+
+// Blob returns the blob parameters active at a block, or false before
+// Cancun. A nil number selects the latest scheduled fork.
+func (c *Chain) Blob(number *big.Int, time uint64) (BlobConfig, bool) {
+	_, time = at(number, time)
+	for i := len(c.BlobSchedule) - 1; i >= 0; i-- {
+		if c.BlobSchedule[i].Time <= time {
+			return c.BlobSchedule[i].Config, true
+		}
+	}
+	return BlobConfig{}, false
+}
+
+// Fork returns the name of the latest hardfork active at a block. A nil
+// number selects the latest scheduled fork.
+func (c *Chain) Fork(number *big.Int, time uint64) string {
+	return ForkName(c.Rules(number, time))
+}
+
+// ForkName returns the name of the latest hardfork enabled in rules.
+func ForkName(rules params.Rules) string {
+	switch {
+	case rules.IsPrague:
+		return "Prague"
+	case rules.IsCancun:
+		return "Cancun"
+	case rules.IsShanghai:
+		return "Shanghai"
+	case rules.IsMerge:
+		return "Paris"
+	case rules.IsLondon:
+		return "London"
+	case rules.IsBerlin:
+		return "Berlin"
+	case rules.IsIstanbul:
+		return "Istanbul"
+	case rules.IsPetersburg:
+		return "Petersburg"
+	case rules.IsConstantinople:
+		return "Constantinople"
+	case rules.IsByzantium:
+		return "Byzantium"
+	case rules.IsEIP158:
+		return "Spurious Dragon"
+	case rules.IsEIP150:
+		return "Tangerine Whistle"
+	case rules.IsHomestead:
+		return "Homestead"
+	}
+	return "Frontier"
+}
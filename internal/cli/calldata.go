@@ -1,23 +1,166 @@
 package cli
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/luckify/getho/internal/client"
+	"github.com/luckify/getho/internal/decoder"
+	"github.com/luckify/getho/internal/sigdb"
 	"github.com/spf13/cobra"
 )
 
 func newCalldataCmd() *cobra.Command {
+	var (
+		abiPath string
+		to      string
+		tx      string
+	)
+
 	cmd := &cobra.Command{
-		Use:   "calldata [tx_hash]",
+		Use:   "calldata [calldata | --tx tx_hash]",
 		Short: "Decode calldata from a transaction",
 		Long: `Decode function selectors and parse arguments from transaction calldata.
-Highlights unknown or malformed calldata.`,
-		Args: cobra.ExactArgs(1),
+Highlights unknown or malformed calldata.
+
+The argument is the calldata in hex, which is decoded without contacting a
+node. To decode the calldata of a transaction instead, give its hash with
+--tx; 32 bytes of calldata are never taken for a hash.
+
+Arguments are decoded against the ABI given with --abi: a JSON file, which
+applies to every contract, or a directory of files named after the address
+of their contract (e.g. 0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48.json).
+A file may hold a bare ABI or a Hardhat, Foundry or Truffle artifact. The
+ABI of the called contract is used (see --to for bare calldata), else any
+ABI in the directory defining the selector.
+
+Without an ABI for the called contract, the function is looked up in the
+selector database (see getho sig). When several signatures share the
+selector, the calldata is decoded against each: the best match is shown,
+preferring signatures it decodes against cleanly and exactly, followed by
+the other candidates.
+
+Calldata that does not match its function is reported with the byte offset
+where decoding failed, marked in a dump of its 32-byte words.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			txHash := args[0]
+			var abis *decoder.ABISet
+			if abiPath != "" {
+				var err error
+				if abis, err = decoder.LoadABIs(abiPath); err != nil {
+					return fmt.Errorf("failed to load ABI: %w", err)
+				}
+			}
+
+			var target *common.Address
+			if to != "" {
+				if !common.IsHexAddress(to) {
+					return fmt.Errorf("invalid address: %s", to)
+				}
+				address := common.HexToAddress(to)
+				target = &address
+			}
+
+			if (len(args) == 1) == (tx != "") {
+				return errors.New("expected either calldata or a transaction hash given with --tx")
+			}
+			var data []byte
+			if tx != "" {
+				txHash, err := parseTxHash(tx)
+				if err != nil {
+					return err
+				}
+
+				// Create client
+				ctx := cmd.Context()
+				ethClient, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				defer ethClient.Close()
 
-			cmd.Printf("Decoding calldata for transaction: %s\n", txHash)
+				recipient, input, err := fetchCalldata(ctx, ethClient, txHash)
+				if err != nil {
+					return err
+				}
+				if target == nil {
+					target = recipient
+				}
+				data = input
+			} else {
+				var err error
+				if data, err = hex.DecodeString(strings.TrimPrefix(args[0], "0x")); err != nil {
+					return fmt.Errorf("invalid calldata: %w", err)
+				}
+			}
+
+			dec := decoder.NewEthereumDecoder()
+			if abis != nil && len(data) >= 4 {
+				if contract, name := abis.Lookup(target, data[:4]); contract != nil {
+					calldata, err := dec.WithABI(contract).DecodeCalldata(data)
+					if err != nil {
+						return err
+					}
+					cmd.Print(FormatCalldata(calldata, name, nil))
+					return nil
+				}
+			}
+
+			// Without an ABI, try the signatures the selector may stand for
+			db, err := sigdb.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load selector database: %w", err)
+			}
+			if candidates := dec.DecodeCalldataCandidates(data, db.Lookup(data)); len(candidates) > 0 {
+				cmd.Print(FormatCalldata(candidates[0], "selector database", candidates[1:]))
+				return nil
+			}
+			calldata, err := dec.DecodeCalldata(data)
+			if err != nil {
+				return err
+			}
+			cmd.Print(FormatCalldata(calldata, "", nil))
 			return nil
 		},
 	}
 
+	cmd.Flags().StringVar(&abiPath, "abi", "", "decode arguments with the ABI JSON `file`, or a directory of ABIs named by contract address")
+	cmd.Flags().StringVar(&to, "to", "", "`address` of the called contract, to pick its ABI for bare calldata")
+	cmd.Flags().StringVar(&tx, "tx", "", "decode the calldata of the transaction with this `hash`")
+
 	return cmd
 }
+
+// fetchCalldata returns the recipient (nil for contract creations) and the
+// input data of a transaction.
+func fetchCalldata(ctx context.Context, ethClient client.Client, txHash common.Hash) (*common.Address, []byte, error) {
+	tx, _, err := ethClient.GetTransaction(ctx, txHash)
+	if errors.Is(err, types.ErrTxTypeNotSupported) {
+		// Set-code transactions, which go-ethereum cannot decode
+		raw, err := client.GetRawTransaction(ctx, ethClient, txHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch raw transaction: %w", err)
+		}
+		if raw == nil {
+			return nil, nil, fmt.Errorf("transaction not found: %s", txHash.Hex())
+		}
+		decoded, err := decoder.NewEthereumDecoder().DecodeTransaction(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		recipient := common.HexToAddress(decoded.To)
+		return &recipient, decoded.Input, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if tx == nil {
+		return nil, nil, fmt.Errorf("transaction not found: %s", txHash.Hex())
+	}
+	return tx.To(), tx.Data(), nil
+}
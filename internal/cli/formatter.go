@@ -3,11 +3,18 @@ package cli
 import (
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/luckify/getho/internal/analyzer"
+	"github.com/luckify/getho/internal/client"
 	"github.com/luckify/getho/internal/decoder"
+	"github.com/luckify/getho/internal/tracer"
 )
 
 // FormatTransaction displays a decoded transaction in a human-readable format.
@@ -53,6 +60,9 @@ func FormatTransaction(tx *decoder.Transaction, receipt *types.Receipt, isPendin
 	if tx.ChainID != nil && tx.ChainID.Sign() > 0 {
 		b.WriteString("Chain ID:    " + tx.ChainID.String() + "\n")
 	}
+	if tx.Fork != "" {
+		b.WriteString("Fork:        " + tx.Fork + "\n")
+	}
 	b.WriteString("\n")
 
 	// Gas Information
@@ -62,7 +72,8 @@ func FormatTransaction(tx *decoder.Transaction, receipt *types.Receipt, isPendin
 	if receipt != nil {
 		b.WriteString("Gas Used:    " + formatUint64(receipt.GasUsed) + " (" + formatPercentage(receipt.GasUsed, tx.GasLimit) + ")\n")
 	}
-	b.WriteString("Intrinsic:   " + formatUint64(tx.EstimatedIntrinsicGas) + "\n")
+	b.WriteString("Intrinsic:   " + formatUint64(tx.Intrinsic.Total) + "\n")
+	writeIntrinsicGas(&b, &tx.Intrinsic)
 	b.WriteString("\n")
 
 	// Fee Information
@@ -105,16 +116,94 @@ func FormatTransaction(tx *decoder.Transaction, receipt *types.Receipt, isPendin
 		b.WriteString("\n")
 	}
 
+	// Authorizations (EIP-7702)
+	if len(tx.Authorizations) > 0 {
+		b.WriteString("Authorizations (EIP-7702)\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		for i, auth := range tx.Authorizations {
+			authority := auth.Authority
+			if authority == "" {
+				authority = "[unknown authority]"
+			}
+			target := "delegates to " + auth.Address
+			if common.HexToAddress(auth.Address) == (common.Address{}) {
+				target = "clears its delegation"
+			}
+			b.WriteString(fmt.Sprintf("  [%d] %s %s\n", i+1, authority, target))
+			chainID := "any"
+			if auth.ChainID != nil && auth.ChainID.Sign() != 0 {
+				chainID = auth.ChainID.String()
+			}
+			b.WriteString(fmt.Sprintf("      Chain ID: %s, Nonce: %d\n", chainID, auth.Nonce))
+			if auth.Invalid != "" {
+				b.WriteString("      SKIPPED: " + auth.Invalid + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
 	// Blob Gas (EIP-4844)
 	if tx.Type == decoder.TransactionTypeBlob {
 		b.WriteString("Blob Gas (EIP-4844)\n")
 		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("Blobs:         %d\n", len(tx.BlobHashes)))
 		if tx.BlobGasUsed > 0 {
 			b.WriteString("Blob Gas Used: " + formatUint64(tx.BlobGasUsed) + "\n")
 		}
-		if tx.MaxFeePerBlobGas != nil {
-			b.WriteString("Max Fee/Blob:  " + formatWei(tx.MaxFeePerBlobGas) + " gwei\n")
+		if tx.BlobGasFeeCap != nil {
+			b.WriteString("Max Fee/Blob:  " + formatGwei(tx.BlobGasFeeCap) + " gwei\n")
+		}
+		if tx.BlobBaseFee != nil {
+			b.WriteString("Blob Base Fee: " + formatGwei(tx.BlobBaseFee) + " gwei\n")
+		}
+		if tx.BlobFee != nil {
+			b.WriteString("Blob Fee:      " + formatEther(tx.BlobFee) + " ETH\n")
+		}
+		if tx.Sidecar != nil {
+			if tx.Sidecar.Valid() {
+				b.WriteString("Sidecar:       VALID\n")
+			} else {
+				b.WriteString("Sidecar:       INVALID\n")
+			}
+		}
+		if len(tx.BlobHashes) > 0 {
+			b.WriteString("Versioned Hashes:\n")
 		}
+		for i, hash := range tx.BlobHashes {
+			b.WriteString(fmt.Sprintf("  [%d] %s\n", i+1, hash))
+			if tx.Sidecar == nil {
+				continue
+			}
+			if check := tx.Sidecar.Blobs[i]; check.Error != "" {
+				b.WriteString("      " + check.Error + "\n")
+			} else {
+				b.WriteString("      KZG proof valid\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	// Signature
+	if sig := tx.Signature; sig != nil {
+		b.WriteString("Signature\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("V:           %s (y-parity %d)\n", sig.V, sig.YParity))
+		b.WriteString(fmt.Sprintf("R:           %#066x\n", sig.R))
+		b.WriteString(fmt.Sprintf("S:           %#066x\n", sig.S))
+		switch {
+		case tx.Type != decoder.TransactionTypeLegacy:
+			b.WriteString("Replay:      protected (chain ID in typed envelope)\n")
+		case sig.Protected:
+			b.WriteString("Replay:      protected (EIP-155)\n")
+		default:
+			b.WriteString("Replay:      unprotected (pre-EIP-155, valid on any chain)\n")
+		}
+		if sig.LowS {
+			b.WriteString("S Value:     low (EIP-2)\n")
+		} else {
+			b.WriteString("S Value:     high (valid only before Homestead, EIP-2)\n")
+		}
+		b.WriteString("Signed Hash: " + sig.SigningHash + "\n")
 		b.WriteString("\n")
 	}
 
@@ -150,6 +239,557 @@ func FormatTransaction(tx *decoder.Transaction, receipt *types.Receipt, isPendin
 	return b.String()
 }
 
+// writeIntrinsicGas writes the itemized intrinsic gas of a transaction,
+// omitting charges that do not apply.
+func writeIntrinsicGas(b *strings.Builder, g *decoder.IntrinsicGas) {
+	b.WriteString(fmt.Sprintf("  Base:        %d\n", g.Base))
+	if g.Creation > 0 {
+		b.WriteString(fmt.Sprintf("  Creation:    %d\n", g.Creation))
+	}
+	if g.ZeroBytes+g.NonZeroBytes > 0 {
+		b.WriteString(fmt.Sprintf("  Calldata:    %d (%d zero, %d non-zero bytes)\n", g.Calldata, g.ZeroBytes, g.NonZeroBytes))
+	}
+	if g.AccessListAddresses > 0 {
+		b.WriteString(fmt.Sprintf("  Access List: %d (%d addresses, %d storage keys)\n", g.AccessList, g.AccessListAddresses, g.AccessListKeys))
+	}
+	if g.InitcodeWords > 0 {
+		b.WriteString(fmt.Sprintf("  Initcode:    %d (%d words)\n", g.Initcode, g.InitcodeWords))
+	}
+	if g.Authorizations > 0 {
+		b.WriteString(fmt.Sprintf("  Set Code:    %d (%d authorizations)\n", g.AuthorizationList, g.Authorizations))
+	}
+	if g.Floor > 0 {
+		note := "below intrinsic gas"
+		if g.Floor > g.Total {
+			note = "applies, above intrinsic gas"
+		}
+		b.WriteString(fmt.Sprintf("  Floor:       %d (EIP-7623, %s)\n", g.Floor, note))
+	}
+}
+
+// calldataWindow is the number of 32-byte words of calldata shown around
+// the offset where decoding failed.
+const calldataWindow = 16
+
+// FormatCalldata displays decoded calldata as a tree of its arguments.
+// source names the ABI file or database the function was resolved with, if
+// any, and alternatives are the other candidate decodings, best first.
+func FormatCalldata(cd *decoder.Calldata, source string, alternatives []*decoder.Calldata) string {
+	var b strings.Builder
+
+	// Header
+	b.WriteString("Calldata\n")
+	b.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	b.WriteString("Selector:    " + orUnknown(cd.Selector) + "\n")
+	if cd.Signature != "" {
+		b.WriteString("Function:    " + cd.Signature + "\n")
+	}
+	if source != "" {
+		b.WriteString("Source:      " + source + "\n")
+	}
+	b.WriteString(fmt.Sprintf("Length:      %d bytes\n", len(cd.Raw)))
+	if cd.TrailingBytes > 0 {
+		b.WriteString(fmt.Sprintf("Trailing:    %d bytes after the arguments\n", cd.TrailingBytes))
+	}
+	b.WriteString("Status:      ")
+	switch {
+	case len(cd.Raw) == 0:
+		b.WriteString("NONE (no input data)\n")
+	case cd.Error != "" && cd.Signature != "":
+		b.WriteString("MALFORMED\n")
+	case cd.Unknown:
+		b.WriteString("UNKNOWN (no ABI for this selector)\n")
+	default:
+		b.WriteString("DECODED\n")
+	}
+	b.WriteString("\n")
+
+	if len(cd.Arguments) > 0 {
+		b.WriteString("Arguments\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		writeArguments(&b, cd.Arguments, "")
+		b.WriteString("\n")
+	}
+
+	if cd.Error != "" {
+		b.WriteString("Error\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("%s at byte %d (0x%x)\n\n", cd.Error, cd.ErrorOffset, cd.ErrorOffset))
+	}
+
+	if len(alternatives) > 0 {
+		b.WriteString("Other Candidates\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		for _, alt := range alternatives {
+			switch {
+			case alt.Error != "":
+				b.WriteString(fmt.Sprintf("  %s\n      fails at byte %d: %s\n", alt.Signature, alt.ErrorOffset, alt.Error))
+			case alt.TrailingBytes > 0:
+				b.WriteString(fmt.Sprintf("  %s\n      decodes, leaving %d trailing bytes\n", alt.Signature, alt.TrailingBytes))
+			default:
+				b.WriteString(fmt.Sprintf("  %s\n      decodes cleanly\n", alt.Signature))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if cd.Error != "" || cd.Unknown {
+		mark := -1
+		if cd.Error != "" {
+			mark = cd.ErrorOffset
+		}
+		b.WriteString("Raw Calldata\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		writeCalldataWords(&b, cd.Raw, mark)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// writeArguments writes decoded arguments as an indented tree.
+func writeArguments(b *strings.Builder, args []decoder.Argument, indent string) {
+	for _, arg := range args {
+		b.WriteString(indent)
+		if arg.Name != "" {
+			b.WriteString(arg.Name + ": ")
+		}
+		b.WriteString(arg.Type)
+		children, composite := arg.Value.([]decoder.Argument)
+		switch {
+		case arg.Error != "":
+			b.WriteString(fmt.Sprintf("  <-- %s at byte %d (0x%x)\n", arg.Error, arg.Offset, arg.Offset))
+		case composite:
+			if strings.HasSuffix(arg.Type, "]") && len(children) == 1 {
+				b.WriteString(" (1 element)")
+			} else if strings.HasSuffix(arg.Type, "]") {
+				b.WriteString(fmt.Sprintf(" (%d elements)", len(children)))
+			}
+			b.WriteString("\n")
+		default:
+			b.WriteString(" = " + formatArgumentValue(arg.Value) + "\n")
+		}
+		if composite {
+			writeArguments(b, children, indent+"  ")
+		}
+	}
+}
+
+// formatArgumentValue formats a decoded argument value.
+func formatArgumentValue(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		if len(v) == 0 {
+			return "0x"
+		}
+		return hexutil.Encode(v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeCalldataWords writes calldata as its selector followed by 32-byte
+// words, marking the word containing offset mark (-1 for none). Long
+// calldata is cut to the words around the mark.
+func writeCalldataWords(b *strings.Builder, raw []byte, mark int) {
+	if len(raw) == 0 {
+		b.WriteString("(no input data)\n")
+		return
+	}
+	// Rows are the selector and the words after it
+	type row struct{ start, end int }
+	rows := []row{{0, min(4, len(raw))}}
+	for start := 4; start < len(raw); start += 32 {
+		rows = append(rows, row{start, min(start+32, len(raw))})
+	}
+
+	marked := -1
+	for i, r := range rows {
+		if mark >= r.start && mark < r.end {
+			marked = i
+		}
+	}
+	first, last := 0, min(len(rows), calldataWindow)
+	switch {
+	case marked >= 0:
+		first = max(0, marked-calldataWindow/2)
+		last = min(len(rows), first+calldataWindow)
+	case mark >= len(raw):
+		first, last = max(0, len(rows)-calldataWindow/2), len(rows)
+	}
+
+	if first > 0 {
+		b.WriteString(fmt.Sprintf("    ... %d earlier words\n", first))
+	}
+	for i := first; i < last; i++ {
+		prefix := "    "
+		if i == marked {
+			prefix = ">>> "
+		}
+		r := rows[i]
+		b.WriteString(fmt.Sprintf("%s0x%04x: %x\n", prefix, r.start, raw[r.start:r.end]))
+	}
+	if last < len(rows) {
+		b.WriteString(fmt.Sprintf("    ... %d more words\n", len(rows)-last))
+	}
+	if mark >= len(raw) {
+		b.WriteString(fmt.Sprintf(">>> 0x%04x: (end of calldata)\n", len(raw)))
+	}
+}
+
+// FormatTrace displays an execution trace as an indented call tree.
+func FormatTrace(trace *tracer.Trace) string {
+	var b strings.Builder
+
+	// Header
+	b.WriteString("Execution Trace\n")
+	b.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	b.WriteString("Hash:        " + trace.TxHash + "\n")
+	b.WriteString("Gas Used:    " + formatUint64(trace.TotalGasUsed) + "\n")
+	b.WriteString("Frames:      " + fmt.Sprintf("%d", len(trace.Frames)) + "\n")
+	if trace.Error != "" {
+		b.WriteString("Error:       " + trace.Error + "\n")
+	}
+	b.WriteString("\n")
+
+	// Call Frames
+	b.WriteString("Call Frames\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	for _, frame := range trace.Frames {
+		indent := strings.Repeat("  ", frame.Depth)
+		to := frame.To
+		if to == "" {
+			to = "?"
+		}
+		b.WriteString(fmt.Sprintf("%s[%d] %s %s", indent, frame.Depth, frame.Type, to))
+		if frame.Value != nil && frame.Value.Sign() > 0 {
+			b.WriteString(" value=" + formatWei(frame.Value) + " ETH")
+		}
+		b.WriteString(fmt.Sprintf(" gas=%s/%s\n", formatUint64(frame.GasUsed), formatUint64(frame.GasLimit)))
+
+		if ops := frame.Opcodes; ops.Total > 0 {
+			b.WriteString(fmt.Sprintf("%s    ops=%d calls=%d sload=%d sstore=%d logs=%d\n",
+				indent, ops.Total, ops.Calls, ops.SLoads, ops.SStores, ops.Logs))
+		}
+		if frame.Error != "" {
+			b.WriteString(indent + "    error: " + frame.Error + "\n")
+		}
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// FormatPrestate displays the accounts touched by a transaction as reported
+// by the prestate tracer.
+func FormatPrestate(txHash string, state *client.PrestateTrace) string {
+	var b strings.Builder
+
+	// Header
+	b.WriteString("Prestate Trace\n")
+	b.WriteString(strings.Repeat("=", 80) + "\n\n")
+	b.WriteString("Hash:        " + txHash + "\n\n")
+
+	if state == nil {
+		b.WriteString("(no state)\n")
+		return b.String()
+	}
+
+	if state.Post == nil {
+		writeAccounts(&b, "Accounts", state.Pre)
+	} else {
+		writeAccounts(&b, "Pre-State", state.Pre)
+		writeAccounts(&b, "Post-State", state.Post)
+	}
+
+	return b.String()
+}
+
+// writeAccounts writes a section of prestate tracer accounts, sorted by address.
+func writeAccounts(b *strings.Builder, title string, accounts map[common.Address]*client.PrestateAccount) {
+	b.WriteString(title + "\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	if len(accounts) == 0 {
+		b.WriteString("(none)\n\n")
+		return
+	}
+
+	addrs := make([]common.Address, 0, len(accounts))
+	for addr := range accounts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Cmp(addrs[j]) < 0 })
+
+	for _, addr := range addrs {
+		account := accounts[addr]
+		b.WriteString(addr.Hex() + "\n")
+		if account.Balance != nil {
+			b.WriteString("  Balance:   " + formatWei(account.Balance.ToInt()) + " ETH\n")
+		}
+		b.WriteString("  Nonce:     " + formatUint64(account.Nonce) + "\n")
+		if len(account.Code) > 0 {
+			b.WriteString(fmt.Sprintf("  Code:      %d bytes\n", len(account.Code)))
+		}
+		keys := make([]common.Hash, 0, len(account.Storage))
+		for key := range account.Storage {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Cmp(keys[j]) < 0 })
+		for _, key := range keys {
+			b.WriteString("  " + key.Hex() + " => " + account.Storage[key].Hex() + "\n")
+		}
+	}
+	b.WriteString("\n")
+}
+
+// FormatGasAnalysis displays a gas and fee breakdown in a human-readable format.
+func FormatGasAnalysis(a *analyzer.GasAnalysis) string {
+	var b strings.Builder
+
+	// Header
+	b.WriteString("Gas Analysis\n")
+	b.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	b.WriteString("Hash:         " + a.TxHash + "\n")
+	b.WriteString("Block:        " + formatUint64(a.BlockNumber) + " (" + a.BlockHash + ")\n")
+	if a.Fork != "" {
+		b.WriteString("Fork:         " + a.Fork + "\n")
+	}
+	b.WriteString("\n")
+
+	// Gas Usage
+	b.WriteString("Gas Usage\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	b.WriteString("Gas Limit:    " + formatUint64(a.GasLimit) + "\n")
+	b.WriteString("Gas Used:     " + formatUint64(a.GasUsed) + " (" + formatPercentage(a.GasUsed, a.GasLimit) + ")\n")
+	b.WriteString("\n")
+
+	// Per-gas prices
+	b.WriteString("Prices (per gas)\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	if a.BaseFeePerGas != nil {
+		b.WriteString("Base Fee:     " + formatGwei(a.BaseFeePerGas) + " gwei\n")
+	}
+	if a.GasPrice != nil {
+		b.WriteString("Gas Price:    " + formatGwei(a.GasPrice) + " gwei\n")
+	}
+	if a.MaxFeePerGas != nil {
+		b.WriteString("Max Fee:      " + formatGwei(a.MaxFeePerGas) + " gwei\n")
+	}
+	if a.MaxPriorityFeePerGas != nil {
+		b.WriteString("Max Priority: " + formatGwei(a.MaxPriorityFeePerGas) + " gwei\n")
+	}
+	b.WriteString("Effective:    " + formatGwei(a.EffectiveGasPrice) + " gwei\n")
+	b.WriteString("\n")
+
+	// Fee totals
+	b.WriteString("Fees\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	for _, c := range a.Components {
+		b.WriteString(fmt.Sprintf("%-13s %s ETH\n", strings.ToUpper(c.Label[:1])+c.Label[1:]+":", formatEther(c.Value)))
+	}
+	b.WriteString("Execution:    " + formatEther(a.TotalFeePaid) + " ETH\n")
+	if a.TotalBlobFeePaid != nil {
+		b.WriteString("Total:        " + formatEther(a.TotalExecutionAndBlob) + " ETH\n")
+	}
+	b.WriteString("\n")
+
+	// Blob Gas (EIP-4844)
+	if a.BlobGasUsed > 0 || a.BlobGasFeeCap != nil {
+		b.WriteString("Blob Gas (EIP-4844)\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString("Blob Gas Used: " + formatUint64(a.BlobGasUsed) + "\n")
+		if a.BlobGasPrice != nil {
+			b.WriteString("Blob Price:    " + formatGwei(a.BlobGasPrice) + " gwei\n")
+		}
+		if a.BlobGasFeeCap != nil {
+			b.WriteString("Max Fee/Blob:  " + formatGwei(a.BlobGasFeeCap) + " gwei\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// Notes
+	if len(a.Notes) > 0 {
+		b.WriteString("Notes\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		for _, note := range a.Notes {
+			b.WriteString("  * " + note + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// FormatNodeInfo formats the probed capabilities of an endpoint.
+func FormatNodeInfo(info *client.NodeInfo) string {
+	var b strings.Builder
+
+	b.WriteString("Node " + info.Endpoint + "\n")
+	b.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	b.WriteString("Client:        " + orUnknown(info.ClientVersion) + "\n")
+	if info.ChainID != nil {
+		b.WriteString("Chain ID:      " + info.ChainID.String() + "\n")
+	} else {
+		b.WriteString("Chain ID:      unknown\n")
+	}
+	b.WriteString("Latest Block:  " + formatUint64(info.LatestBlock) + "\n")
+	if _, failed := info.Errors["syncing"]; failed {
+		b.WriteString("Sync:          unknown\n")
+	} else if info.Sync == nil {
+		b.WriteString("Sync:          in sync\n")
+	} else {
+		b.WriteString(fmt.Sprintf("Sync:          syncing (block %d of %d)\n", info.Sync.CurrentBlock, info.Sync.HighestBlock))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Namespaces\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	for _, name := range client.ProbedNamespaces() {
+		status := "no"
+		if served, ok := info.Namespaces[name]; !ok {
+			status = "unknown"
+		} else if served {
+			status = "yes"
+		}
+		b.WriteString(fmt.Sprintf("  %-8s %s\n", name, status))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("History\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	switch {
+	case info.Archive():
+		b.WriteString("Oldest State:  0 (archive)\n")
+	case info.OldestState != nil:
+		b.WriteString(fmt.Sprintf("Oldest State:  %d (%d blocks)\n", *info.OldestState, info.LatestBlock-*info.OldestState+1))
+	default:
+		b.WriteString("Oldest State:  unknown\n")
+	}
+	if _, failed := info.Errors["blockReceipts"]; failed {
+		b.WriteString("Block Receipts: unknown\n")
+	} else if info.BlockReceipts {
+		b.WriteString("Block Receipts: supported\n")
+	} else {
+		b.WriteString("Block Receipts: not supported\n")
+	}
+	b.WriteString("\n")
+
+	if len(info.Errors) > 0 {
+		b.WriteString("Probe Errors\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		probes := make([]string, 0, len(info.Errors))
+		for probe := range info.Errors {
+			probes = append(probes, probe)
+		}
+		sort.Strings(probes)
+		for _, probe := range probes {
+			b.WriteString("  * " + probe + ": " + info.Errors[probe] + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// FormatAccountState displays the state of an account at a block.
+func FormatAccountState(state *AccountState, showCode bool) string {
+	var b strings.Builder
+
+	b.WriteString("Account State\n")
+	b.WriteString(strings.Repeat("=", 80) + "\n\n")
+	b.WriteString("Address:     " + state.Address.Hex() + "\n")
+	b.WriteString("Block:       " + state.Block + "\n")
+	b.WriteString("Balance:     " + formatEther(state.Balance) + " ETH\n")
+	b.WriteString("Nonce:       " + formatUint64(state.Nonce) + "\n")
+	if len(state.Code) == 0 {
+		b.WriteString("Code:        none\n")
+	} else {
+		b.WriteString(fmt.Sprintf("Code:        %d bytes (hash %s)\n", len(state.Code), crypto.Keccak256Hash(state.Code).Hex()))
+	}
+	b.WriteString("\n")
+
+	if len(state.Storage) > 0 {
+		b.WriteString("Storage\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		for _, entry := range state.Storage {
+			b.WriteString(entry[0].Hex() + " => " + entry[1].Hex() + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if showCode && len(state.Code) > 0 {
+		b.WriteString("Bytecode\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(hexutil.Encode(state.Code) + "\n\n")
+	}
+
+	return b.String()
+}
+
+// FormatRPCStats displays per-method RPC statistics, grouped by endpoint.
+// Latency percentiles are estimated from the histogram buckets. Transports
+// other than HTTP do not expose sizes, which show as zero.
+func FormatRPCStats(stats []client.MethodStats) string {
+	var b strings.Builder
+
+	b.WriteString("RPC Statistics\n")
+	b.WriteString(strings.Repeat("=", 80) + "\n\n")
+	if len(stats) == 0 {
+		b.WriteString("No RPC requests were sent.\n\n")
+		return b.String()
+	}
+
+	for i, s := range stats {
+		if i == 0 || s.Endpoint != stats[i-1].Endpoint {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(s.Endpoint + "\n")
+			b.WriteString(strings.Repeat("-", 80) + "\n")
+			b.WriteString(fmt.Sprintf("%-30s %6s %7s %6s %9s %9s %7s %7s %7s\n",
+				"Method", "Calls", "Retries", "Errors", "Sent", "Received", "p50", "p95", "Max"))
+		}
+		b.WriteString(fmt.Sprintf("%-30s %6d %7d %6d %9s %9s %7s %7s %7s\n",
+			s.Method, s.Calls, s.Retries, s.Errors, formatBytes(s.RequestBytes), formatBytes(s.ResponseBytes),
+			formatLatency(s.Latency.Quantile(0.5)), formatLatency(s.Latency.Quantile(0.95)), formatLatency(s.Latency.Max)))
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// orUnknown returns s, or "unknown" if s is empty.
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// formatGwei converts a per-gas wei amount to a decimal gwei string.
+func formatGwei(wei *big.Int) string {
+	if wei == nil {
+		return "0"
+	}
+	gwei := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9))
+	return strings.TrimRight(strings.TrimRight(gwei.Text('f', 9), "0"), ".")
+}
+
+// formatEther converts a wei amount to a decimal ETH string.
+func formatEther(wei *big.Int) string {
+	if wei == nil {
+		return "0"
+	}
+	eth := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18))
+	return strings.TrimRight(strings.TrimRight(eth.Text('f', 18), "0"), ".")
+}
+
 // formatWei converts wei to a human-readable string (ETH or gwei).
 func formatWei(wei *big.Int) string {
 	if wei == nil {
@@ -174,6 +814,25 @@ func formatUint64(n uint64) string {
 	return fmt.Sprintf("%d", n)
 }
 
+// formatBytes formats a byte count with a binary unit.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// formatLatency formats a latency in milliseconds.
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.0fms", float64(d)/float64(time.Millisecond))
+}
+
 // formatPercentage calculates and formats a percentage.
 func formatPercentage(part, total uint64) string {
 	if total == 0 {
@@ -194,6 +853,8 @@ func formatTransactionType(t decoder.TransactionType) string {
 		return "EIP-1559 (0x2)"
 	case decoder.TransactionTypeBlob:
 		return "Blob (EIP-4844) (0x3)"
+	case decoder.TransactionTypeSetCode:
+		return "Set Code (EIP-7702) (0x4)"
 	default:
 		return fmt.Sprintf("Unknown (%d)", t)
 	}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/luckify/getho/internal/decoder"
+)
+
+func TestFormatBlobFeeCap(t *testing.T) {
+	tests := []struct {
+		cap  int64
+		want string
+	}{
+		{1, "Max Fee/Blob:  0.000000001 gwei"},
+		{1_500_000_000, "Max Fee/Blob:  1.5 gwei"},
+		{30_000_000_000, "Max Fee/Blob:  30 gwei"},
+	}
+	for _, tt := range tests {
+		tx := &decoder.Transaction{Type: decoder.TransactionTypeBlob, BlobGasFeeCap: big.NewInt(tt.cap)}
+		checkOutput(t, "FormatTransaction", FormatTransaction(tx, nil, true), tt.want+"\n")
+	}
+}
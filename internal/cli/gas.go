@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"fmt"
+
+	"github.com/luckify/getho/internal/analyzer"
+	"github.com/luckify/getho/internal/client"
 	"github.com/spf13/cobra"
 )
 
@@ -13,9 +17,30 @@ Displays base fee, priority fee (tip), blob fee (EIP-4844),
 and gas used vs gas limit.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			txHash := args[0]
+			txHash, err := parseTxHash(args[0])
+			if err != nil {
+				return err
+			}
+
+			// Create client
+			ctx := cmd.Context()
+			ethClient, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+			defer ethClient.Close()
+
+			ch, err := client.DetectChain(ctx, ethClient)
+			if err != nil {
+				return err
+			}
+
+			analysis, err := analyzer.NewClientAnalyzer(ethClient, ch).AnalyzeGas(ctx, txHash.Hex())
+			if err != nil {
+				return fmt.Errorf("failed to analyze gas: %w", err)
+			}
 
-			cmd.Printf("Analyzing gas for transaction: %s\n", txHash)
+			cmd.Print(FormatGasAnalysis(analysis))
 			return nil
 		},
 	}
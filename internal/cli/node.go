@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/luckify/getho/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func newNodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Inspect the connected nodes",
+		Long:  "Inspect the capabilities of the configured JSON-RPC endpoints",
+	}
+
+	infoCmd := &cobra.Command{
+		Use:   "info",
+		Short: "Probe endpoint capabilities",
+		Long: `Probe each configured endpoint and report its client version, chain ID,
+sync status, the JSON-RPC namespaces it serves, the oldest block whose state
+it still holds and whether it supports eth_getBlockReceipts.
+
+Results are saved for a day so that other commands can fail early, e.g. trace
+on an endpoint without the debug namespace or on a transaction whose state
+has been pruned.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Create client
+			ctx := cmd.Context()
+			ethClient, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+			defer ethClient.Close()
+
+			dir, err := client.DefaultNodeInfoDir()
+			if err != nil {
+				return err
+			}
+
+			var errs []error
+			for _, e := range client.ProbeTargets(ethClient) {
+				info, err := client.Probe(ctx, e)
+				if ctx.Err() != nil {
+					// Show what was probed before the interruption, but do
+					// not cache it.
+					if info != nil {
+						cmd.Print(FormatNodeInfo(info))
+					}
+					return err
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to probe %s: %w", e.Name, err))
+					continue
+				}
+				if err := client.SaveNodeInfo(dir, info); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to save probe result: %v\n", err)
+				}
+				cmd.Print(FormatNodeInfo(info))
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	cmd.AddCommand(infoCmd)
+	return cmd
+}
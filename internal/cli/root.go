@@ -1,15 +1,100 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/luckify/getho/internal/chain"
+	"github.com/luckify/getho/internal/client"
 	"github.com/spf13/cobra"
 )
 
 var (
-	// rpcURL is the Ethereum JSON-RPC endpoint URL
-	rpcURL string
+	// rpcURLs are the Ethereum JSON-RPC endpoint URLs
+	rpcURLs []string
+
+	// rpcMode selects how several endpoints are combined
+	rpcMode string
+
+	// quorum is the number of endpoints asked in quorum mode
+	quorum int
+
+	// rpcRetries is the number of retries of temporary RPC failures
+	rpcRetries int
+
+	// rpcRateLimit caps requests per second per endpoint
+	rpcRateLimit float64
+
+	// noCache disables the on-disk cache of finalized chain data
+	noCache bool
+
+	// cacheClear empties the cache before running the command
+	cacheClear bool
+
+	// cacheDir overrides the cache location
+	cacheDir string
+
+	// cacheMaxSize caps the cache size in megabytes
+	cacheMaxSize int64
+
+	// recordPath is the cassette file that JSON-RPC traffic is recorded to
+	recordPath string
+
+	// replayPath is the cassette file that JSON-RPC traffic is replayed from
+	replayPath string
+
+	// datadir is a stopped geth node's datadir to read instead of RPC
+	datadir string
+
+	// era1Dir is a directory of era1 archives to read instead of RPC
+	era1Dir string
+
+	// profile selects a named profile of the config file
+	profile string
+
+	// rpcHeaders are extra "Name: value" HTTP headers sent to endpoints
+	rpcHeaders []string
+
+	// rpcBasicAuth is a "user:password" basic auth credential
+	rpcBasicAuth string
+
+	// rpcBearer is a bearer token sent to endpoints
+	rpcBearer string
+
+	// rpcJWTSecret is the path of a geth JWT secret file
+	rpcJWTSecret string
+
+	// chainConfig is a genesis or chain config file of a custom chain
+	chainConfig string
+
+	// blockSelector selects the block that state is read at
+	blockSelector string
+
+	// rpcStats prints RPC statistics after the command: text or json
+	rpcStats string
+
+	// debugRPC is the verbosity of the RPC debug log on stderr: 0 (off),
+	// 1 (calls), 2 (with arguments and results) or 3 (with JSON-RPC bodies)
+	debugRPC int
+
+	// timeout bounds the run time of a command
+	timeout time.Duration
+
+	// telemetry collects RPC statistics for --rpc-stats and --debug-rpc
+	telemetry *client.Telemetry
+
+	// cleanups run after the command finishes, whether it failed or not
+	cleanups []func()
 )
 
 var rootCmd = &cobra.Command{
@@ -21,30 +106,442 @@ for inspecting execution-layer behavior.
 It provides transaction inspection, gas analysis, execution tracing,
 and calldata decoding capabilities.`,
 	Version: "0.1.0",
+	// Execute prints errors, with the reason a cancelled command stopped.
+	SilenceErrors: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Flags and arguments are valid by now; a failure past this point
+		// is not a usage error.
+		cmd.SilenceUsage = true
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeoutCause(cmd.Context(), timeout,
+				fmt.Errorf("timed out after %s (see --timeout)", timeout))
+			cmd.SetContext(ctx)
+			cleanups = append(cleanups, cancel)
+		}
+	},
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&rpcURL, "rpc", "", "Ethereum JSON-RPC endpoint URL (default: $GETHO_RPC_URL or http://localhost:8545)")
+	rootCmd.PersistentFlags().StringArrayVar(&rpcURLs, "rpc", nil, "Ethereum JSON-RPC endpoint URL, repeatable (default: $GETHO_RPC_URL, config file or http://localhost:8545)")
+	rootCmd.PersistentFlags().StringVar(&rpcMode, "rpc-mode", "", "how to combine several endpoints: failover or quorum (default: config file or failover)")
+	rootCmd.PersistentFlags().IntVar(&quorum, "quorum", 0, "number of endpoints asked in quorum mode (default: all)")
+	rootCmd.PersistentFlags().IntVar(&rpcRetries, "rpc-retries", client.DefaultRetryPolicy.MaxRetries, "retries of rate-limited, failed or lagging RPC requests")
+	rootCmd.PersistentFlags().Float64Var(&rpcRateLimit, "rpc-rate-limit", 0, "maximum requests per second per endpoint (0: unlimited)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "do not read or write the on-disk cache of finalized chain data")
+	rootCmd.PersistentFlags().BoolVar(&cacheClear, "cache-clear", false, "empty the on-disk cache before running")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "on-disk cache directory (default: user cache dir/getho)")
+	rootCmd.PersistentFlags().Int64Var(&cacheMaxSize, "cache-max-size", 256, "maximum on-disk cache size in MB (0: unlimited)")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "record every JSON-RPC request/response to a cassette file (HTTP endpoints only)")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "serve JSON-RPC requests from a cassette file, without network access")
+	rootCmd.PersistentFlags().StringVar(&datadir, "datadir", "", "read chain data from a stopped geth node's datadir instead of RPC")
+	rootCmd.PersistentFlags().StringVar(&era1Dir, "era1", "", "read pre-merge history from a directory of era1 archives instead of RPC")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config file profile to use (default: $GETHO_PROFILE)")
+	rootCmd.PersistentFlags().StringArrayVar(&rpcHeaders, "rpc-header", nil, "extra \"Name: value\" HTTP header sent to endpoints, repeatable")
+	rootCmd.PersistentFlags().StringVar(&rpcBasicAuth, "rpc-basic-auth", "", "\"user:password\" HTTP basic auth (default: $GETHO_RPC_BASIC_AUTH)")
+	rootCmd.PersistentFlags().StringVar(&rpcBearer, "rpc-bearer", "", "bearer token sent to endpoints (default: $GETHO_RPC_BEARER)")
+	rootCmd.PersistentFlags().StringVar(&rpcJWTSecret, "rpc-jwt-secret", "", "geth JWT secret file for authenticated endpoints (default: $GETHO_RPC_JWT_SECRET)")
+	rootCmd.PersistentFlags().StringVar(&chainConfig, "chain-config", "", "genesis or chain config JSON of a custom chain (default: $GETHO_CHAIN_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&blockSelector, "block", "latest", "block to read state at: a number, hash, tag (latest, safe, finalized, pending, earliest) or time (@unix seconds, RFC 3339 or YYYY-MM-DD)")
+	rootCmd.PersistentFlags().StringVar(&rpcStats, "rpc-stats", "", "print per-method JSON-RPC request counts, retries, errors, sizes and latencies to stderr after the command, as text or json")
+	rootCmd.PersistentFlags().Lookup("rpc-stats").NoOptDefVal = "text"
+	rootCmd.PersistentFlags().IntVar(&debugRPC, "debug-rpc", 0, "log JSON-RPC requests to stderr: 1 each attempt with its latency, sizes and error, 2 also the bodies exchanged with HTTP endpoints")
+	rootCmd.PersistentFlags().Lookup("debug-rpc").NoOptDefVal = "1"
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "maximum run time of a command, e.g. 2m (0: unlimited)")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.MarkFlagsMutuallyExclusive("datadir", "rpc")
+	rootCmd.MarkFlagsMutuallyExclusive("datadir", "replay")
+	rootCmd.MarkFlagsMutuallyExclusive("datadir", "record")
+	rootCmd.MarkFlagsMutuallyExclusive("era1", "rpc")
+	rootCmd.MarkFlagsMutuallyExclusive("era1", "replay")
+	rootCmd.MarkFlagsMutuallyExclusive("era1", "record")
+	rootCmd.MarkFlagsMutuallyExclusive("era1", "datadir")
+	rootCmd.MarkFlagsMutuallyExclusive("rpc-basic-auth", "rpc-bearer", "rpc-jwt-secret")
 }
 
 // GetRPCURL returns the configured RPC URL, falling back to environment variable or default.
+// If several endpoints are configured, the first one is returned.
+//
+// Deprecated: commands connect with newClient, which honours every --rpc
+// endpoint, the config file and the --rpc-mode flag.
 func GetRPCURL() string {
-	if rpcURL != "" {
-		return rpcURL
+	if len(rpcURLs) > 0 {
+		return rpcURLs[0]
+	}
+	return client.GetRPCURL()
+}
+
+// newClient connects to the configured endpoints. Flags take precedence over
+// $GETHO_RPC_URL, which takes precedence over the config file.
+//
+// The custom chain given by --chain-config or $GETHO_CHAIN_CONFIG, if any,
+// is registered first, so that the client identifies it.
+func newClient(ctx context.Context) (client.Client, error) {
+	if err := registerChainConfig(); err != nil {
+		return nil, err
 	}
-	return getRPCURLFromEnv()
+
+	if _, err := rpcTelemetry(); err != nil {
+		return nil, err
+	}
+
+	// Local data needs none of the RPC settings below.
+	if datadir != "" || era1Dir != "" {
+		return client.NewClient(ctx, client.Options{Datadir: datadir, Era1Dir: era1Dir, Telemetry: telemetry})
+	}
+
+	cfg, err := client.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = os.Getenv("GETHO_PROFILE")
+	}
+	if cfg, err = cfg.Profile(profile); err != nil {
+		return nil, err
+	}
+
+	opts := client.Options{
+		RPCURLs: rpcURLs,
+		Mode:    cfg.Mode,
+		Quorum:  cfg.Quorum,
+		OnDisagreement: func(d client.Disagreement) {
+			fmt.Fprintf(os.Stderr, "Warning: endpoints disagree on %s\n", d)
+		},
+		Telemetry: telemetry,
+	}
+	if len(opts.RPCURLs) == 0 && os.Getenv("GETHO_RPC_URL") == "" {
+		opts.RPCURLs = cfg.RPC
+	}
+	if rpcMode != "" {
+		if opts.Mode, err = client.ParseMode(rpcMode); err != nil {
+			return nil, err
+		}
+	}
+	if quorum > 0 {
+		opts.Quorum = quorum
+	}
+
+	rpcOpts := client.DefaultRPCOptions()
+	rpcOpts.RateLimit = cfg.RateLimit
+	if cfg.Retries != nil {
+		rpcOpts.Retry.MaxRetries = *cfg.Retries
+	}
+	if rootCmd.PersistentFlags().Changed("rpc-retries") {
+		rpcOpts.Retry.MaxRetries = rpcRetries
+	}
+	if rpcRateLimit > 0 {
+		rpcOpts.RateLimit = rpcRateLimit
+	}
+	if rpcOpts.Auth, err = rpcAuth(cfg); err != nil {
+		return nil, err
+	}
+	opts.RPC = &rpcOpts
+
+	// Recording and replaying bypass the cache so that the cassette holds
+	// the whole session and replay never depends on local state.
+	switch {
+	case recordPath != "":
+		recorder, err := client.NewRecorder(recordPath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cassette: %w", err)
+		}
+		cleanups = append(cleanups, func() { _ = recorder.Close() })
+		rpcOpts.Transport = recorder
+		return client.NewClient(ctx, opts)
+	case replayPath != "":
+		replayer, err := client.NewReplayer(replayPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cassette: %w", err)
+		}
+		rpcOpts.Transport = replayer
+		rpcOpts.Auth = nil
+		opts.RPCURLs = []string{client.ReplayURL}
+		return client.NewClient(ctx, opts)
+	}
+
+	if cacheClear || !noCache {
+		dir, err := resolveCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		if cacheClear {
+			if err := client.ClearCache(dir); err != nil {
+				return nil, fmt.Errorf("failed to clear cache: %w", err)
+			}
+		}
+		if !noCache {
+			opts.Cache = &client.CacheOptions{Dir: dir, MaxSize: cacheMaxSize << 20}
+		}
+	}
+
+	return client.NewClient(ctx, opts)
 }
 
-func getRPCURLFromEnv() string {
-	if url := os.Getenv("GETHO_RPC_URL"); url != "" {
-		return url
+// rpcTelemetry returns the collector for --rpc-stats and --debug-rpc, or nil
+// if neither is set. The statistics are printed once the command finishes.
+func rpcTelemetry() (*client.Telemetry, error) {
+	if rpcStats != "" && rpcStats != "text" && rpcStats != "json" {
+		return nil, fmt.Errorf("invalid --rpc-stats format: %s (expected text or json)", rpcStats)
+	}
+	if debugRPC < int(client.DebugOff) || debugRPC > int(client.DebugWire) {
+		return nil, fmt.Errorf("invalid --debug-rpc level: %d (expected 0 to %d)", debugRPC, client.DebugWire)
+	}
+	if telemetry != nil || (rpcStats == "" && debugRPC == 0) {
+		return telemetry, nil
+	}
+
+	telemetry = client.NewTelemetry(os.Stderr, client.DebugLevel(debugRPC))
+	if rpcStats != "" {
+		cleanups = append(cleanups, func() {
+			stats := telemetry.Stats()
+			if rpcStats == "json" {
+				out, err := json.MarshalIndent(stats, "", "  ")
+				if err == nil {
+					fmt.Fprintln(os.Stderr, string(out))
+				}
+				return
+			}
+			fmt.Fprint(os.Stderr, FormatRPCStats(stats))
+		})
 	}
-	return "http://localhost:8545"
+	return telemetry, nil
 }
 
-// Execute runs the root command
+// resolveCacheDir returns the on-disk cache directory.
+func resolveCacheDir() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	return client.DefaultCacheDir()
+}
+
+// nodeInfos returns the saved probe results of the endpoints behind c, or
+// nil unless every endpoint has a fresh one.
+func nodeInfos(c client.Client) []*client.NodeInfo {
+	dir, err := client.DefaultNodeInfoDir()
+	if err != nil {
+		return nil
+	}
+	targets := client.ProbeTargets(c)
+	infos := make([]*client.NodeInfo, 0, len(targets))
+	for _, e := range targets {
+		info, ok := client.LoadNodeInfo(dir, e)
+		if !ok || !info.Fresh() {
+			return nil
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// requireNamespace fails early if the saved probe results of every
+// endpoint behind c say that it does not serve a JSON-RPC namespace. Without
+// fresh probe results the check passes and the call itself reports failure.
+func requireNamespace(c client.Client, namespace string) error {
+	var missing []string
+	for _, info := range nodeInfos(c) {
+		if served, probed := info.Namespaces[namespace]; !probed || served {
+			return nil
+		}
+		missing = append(missing, info.Endpoint)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s does not serve the %s namespace (per \"getho node info\"; rerun it if the node changed)",
+		strings.Join(missing, ", "), namespace)
+}
+
+// requireTxState fails early if the saved probe results of every endpoint
+// behind c say that the state a transaction executed on, that of its parent
+// block, has been pruned, as tracing it requires. The transaction's block is
+// only looked up with fresh probe results at hand.
+func requireTxState(ctx context.Context, c client.Client, txHash common.Hash) error {
+	if nodeInfos(c) == nil {
+		return nil
+	}
+	receipt, err := c.GetTransactionReceipt(ctx, txHash)
+	if err != nil || receipt == nil || receipt.BlockNumber.Sign() == 0 {
+		return nil // the trace reports what is wrong
+	}
+	return requireState(c, receipt.BlockNumber.Uint64()-1)
+}
+
+// txNotFound returns the error for a transaction no endpoint behind c knows,
+// pointing out endpoints that were still syncing when last probed.
+func txNotFound(c client.Client, txHash common.Hash) error {
+	var syncing []string
+	for _, info := range nodeInfos(c) {
+		if info.Sync == nil {
+			return fmt.Errorf("transaction not found: %s", txHash.Hex())
+		}
+		syncing = append(syncing, fmt.Sprintf("%s at block %d of %d", info.Endpoint, info.Sync.CurrentBlock, info.Sync.HighestBlock))
+	}
+	if len(syncing) == 0 {
+		return fmt.Errorf("transaction not found: %s", txHash.Hex())
+	}
+	return fmt.Errorf("transaction not found: %s (still syncing per \"getho node info\": %s)", txHash.Hex(), strings.Join(syncing, ", "))
+}
+
+// blockRef resolves --block. Times select the last block produced at or
+// before them, found by binary search over the headers of c.
+func blockRef(ctx context.Context, c client.Client) (client.BlockRef, error) {
+	t, ok, err := parseBlockTime(blockSelector)
+	if err != nil {
+		return client.BlockRef{}, err
+	}
+	if !ok {
+		return client.ParseBlockRef(blockSelector)
+	}
+	header, err := client.BlockAtTime(ctx, c, t)
+	if err != nil {
+		return client.BlockRef{}, fmt.Errorf("failed to find the block at %s: %w", blockSelector, err)
+	}
+	return client.NumberRef(header.Number.Uint64()), nil
+}
+
+// parseBlockTime parses a --block time: "@" followed by Unix seconds, an
+// RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC). It reports false
+// for anything else, such as block numbers.
+func parseBlockTime(s string) (time.Time, bool, error) {
+	if strings.HasPrefix(s, "@") {
+		secs, err := strconv.ParseInt(s[1:], 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid block time: %s (expected @ followed by Unix seconds)", s)
+		}
+		return time.Unix(secs, 0), true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// requireState fails early if the saved probe results of every endpoint
+// behind c say that the state of block number has been pruned. Like
+// requireNamespace, it passes without fresh probe results.
+func requireState(c client.Client, number uint64) error {
+	var missing []string
+	for _, info := range nodeInfos(c) {
+		if info.OldestState != nil && *info.OldestState <= number {
+			return nil
+		}
+		missing = append(missing, info.Endpoint)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s does not hold the state of block %d (per \"getho node info\"; rerun it if the node changed)",
+		strings.Join(missing, ", "), number)
+}
+
+// registerChainConfig registers the custom chain given by --chain-config or
+// $GETHO_CHAIN_CONFIG, if any.
+func registerChainConfig() error {
+	path := chainConfig
+	if path == "" {
+		path = os.Getenv("GETHO_CHAIN_CONFIG")
+	}
+	if path == "" {
+		return nil
+	}
+	custom, err := chain.Load(path)
+	if err != nil {
+		return err
+	}
+	chain.Register(custom)
+	return nil
+}
+
+// rpcAuth assembles endpoint credentials. Headers are merged by name, with
+// flags overriding $GETHO_RPC_HEADERS (one "Name: value" per line), which
+// overrides the config file. For the Authorization scheme the first source
+// to set one wins, in the same order.
+func rpcAuth(cfg *client.Config) (*client.Auth, error) {
+	auth := &client.Auth{Headers: make(http.Header)}
+
+	specs := strings.Split(os.Getenv("GETHO_RPC_HEADERS"), "\n")
+	specs = append(specs, rpcHeaders...)
+	for name, value := range cfg.Headers {
+		auth.Headers.Set(name, value)
+	}
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		name, value, err := client.ParseHeader(spec)
+		if err != nil {
+			return nil, err
+		}
+		auth.Headers.Set(name, value)
+	}
+
+	sources := []struct{ basic, bearer, jwt string }{
+		{rpcBasicAuth, rpcBearer, rpcJWTSecret},
+		{os.Getenv("GETHO_RPC_BASIC_AUTH"), os.Getenv("GETHO_RPC_BEARER"), os.Getenv("GETHO_RPC_JWT_SECRET")},
+		{cfg.BasicAuth, cfg.Bearer, cfg.JWTSecret},
+	}
+	for _, src := range sources {
+		if src.basic == "" && src.bearer == "" && src.jwt == "" {
+			continue
+		}
+		if src.basic != "" {
+			user, password, err := client.ParseBasicAuth(src.basic)
+			if err != nil {
+				return nil, err
+			}
+			auth.BasicUser, auth.BasicPassword = user, password
+		}
+		auth.BearerToken, auth.JWTSecretFile = src.bearer, src.jwt
+		break
+	}
+	return auth, nil
+}
+
+// Execute runs the root command and prints its error, if any. Its context
+// is cancelled on SIGINT or SIGTERM and after --timeout; commands then stop,
+// keeping what they have already printed, and the error says why.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signalContext()
+	defer stop()
+	defer func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}()
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err == nil {
+		return nil
+	}
+	if cmdCtx := cmd.Context(); cmdCtx != nil && cmdCtx.Err() != nil {
+		if cause := context.Cause(cmdCtx); !errors.Is(err, cause) {
+			err = fmt.Errorf("%w: %w", cause, err)
+		}
+	}
+	cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+	return err
+}
+
+// signalContext returns a context that is cancelled on the first SIGINT or
+// SIGTERM. A second signal terminates the process as usual, in case a
+// command does not stop.
+func signalContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel(errors.New("interrupted"))
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, func() { cancel(nil) }
 }
 
 func init() {
@@ -53,8 +550,8 @@ func init() {
 	rootCmd.AddCommand(newGasCmd())
 	rootCmd.AddCommand(newTraceCmd())
 	rootCmd.AddCommand(newRLPCmd())
-}
-
-func er(msg interface{}) {
-	fmt.Fprintf(os.Stderr, "Error: %v\n", msg)
+	rootCmd.AddCommand(newNodeCmd())
+	rootCmd.AddCommand(newSimCmd())
+	rootCmd.AddCommand(newStateCmd())
+	rootCmd.AddCommand(newSigCmd())
 }
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecuteErrors(t *testing.T) {
+	c := newSimChain(t)
+	hash := c.txs[1].Hash().Hex()
+
+	tests := []struct {
+		name    string
+		args    []string
+		printed []string // parts of the error line
+		is      error
+	}{
+		{
+			// The deadline passes before the first call; the line says why
+			// the call failed.
+			name:    "timeout",
+			args:    []string{"tx", hash, "--timeout", "1ns"},
+			printed: []string{"Error: timed out after 1ns (see --timeout): ", "context deadline exceeded"},
+			is:      context.DeadlineExceeded,
+		},
+		{name: "unknown flag", args: []string{"tx", hash, "--no-such-flag"}, printed: []string{"Error: unknown flag: --no-such-flag"}},
+		{name: "invalid hash", args: []string{"tx", "0x12"}, printed: []string{"Error: "}},
+	}
+	for _, tt := range tests {
+		_, errOut, err := execute(tt.args...)
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		if tt.is != nil && !errors.Is(err, tt.is) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.is)
+		}
+		if n := strings.Count(errOut, "Error:"); n != 1 || !strings.Contains(errOut, "Error: "+err.Error()+"\n") {
+			t.Errorf("%s: printed %q, want the error once", tt.name, errOut)
+		}
+		for _, part := range tt.printed {
+			if !strings.Contains(errOut, part) {
+				t.Errorf("%s: printed %q, want %q", tt.name, errOut, part)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/luckify/getho/internal/sigdb"
+	"github.com/spf13/cobra"
+)
+
+func newSigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sig",
+		Short: "Function selector database utilities",
+		Long: `Look up and extend the offline database mapping 4-byte function selectors
+to signatures, which decodes calldata without an ABI.
+
+getho embeds the selectors of widely used functions. Imported signatures
+are added to a local database, getho/selectors.txt in the user
+configuration directory ($GETHO_SELECTORS overrides the location).`,
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import [file...]",
+		Short: "Import signatures into the local selector database",
+		Long: `Import function signatures into the local selector database. Accepted
+formats are 4byte.directory API responses (/api/v1/signatures/) or arrays of
+their results, openchain.xyz lookups (/signature-database/v1/lookup), and
+text with one signature per line, optionally preceded by its selector.
+Signatures are read from stdin without a file or with "-".
+
+Malformed signatures and signatures whose selector does not match are
+skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := sigdb.Load()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			for _, path := range args {
+				var data []byte
+				if path == "-" {
+					data, err = io.ReadAll(cmd.InOrStdin())
+				} else {
+					data, err = os.ReadFile(path)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to read signatures: %w", err)
+				}
+				entries, skipped, err := sigdb.Parse(data)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				added, err := sigdb.Import(db, entries)
+				if err != nil {
+					return fmt.Errorf("failed to import signatures: %w", err)
+				}
+				cmd.Printf("%s: %d signatures added, %d already known, %d skipped\n", path, added, len(entries)-added, skipped)
+			}
+			return nil
+		},
+	}
+
+	lookupCmd := &cobra.Command{
+		Use:   "lookup [selector]",
+		Short: "List the signatures of a function selector",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selector, err := hex.DecodeString(strings.TrimPrefix(args[0], "0x"))
+			if err != nil || len(selector) < 4 {
+				return fmt.Errorf("invalid selector: %s", args[0])
+			}
+			db, err := sigdb.Load()
+			if err != nil {
+				return err
+			}
+			signatures := db.Lookup(selector[:4])
+			if len(signatures) == 0 {
+				return fmt.Errorf("no signature known for selector 0x%x", selector[:4])
+			}
+			for _, signature := range signatures {
+				cmd.Println(signature)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(importCmd)
+	cmd.AddCommand(lookupCmd)
+	return cmd
+}
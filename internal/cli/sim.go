@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/luckify/getho/internal/analyzer"
+	"github.com/luckify/getho/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// simCounter is the init code of a contract whose runtime code stores the
+// call value in slot 0: CALLVALUE PUSH1 0 SSTORE STOP.
+var simCounter = common.FromHex("0x6005600c60003960056000f3" + "3460005500")
+
+func newSimCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sim",
+		Short: "Inspect transactions on a simulated chain",
+		Long: `Start an in-process simulated chain, send one transaction of each type
+(a contract deployment, legacy, EIP-2930 access list, EIP-1559 and EIP-4844
+blob transactions), mine them and inspect each one as the tx and gas
+commands would. Needs neither a node nor network access.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			sim, err := client.NewSimClient(client.SimOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to start simulated chain: %w", err)
+			}
+			defer sim.Close()
+
+			txs, err := seedSimChain(ctx, sim)
+			if err != nil {
+				return fmt.Errorf("failed to seed simulated chain: %w", err)
+			}
+
+			// Only the inspection is reported by --rpc-stats and --debug-rpc,
+			// not the seeding.
+			telemetry, err := rpcTelemetry()
+			if err != nil {
+				return err
+			}
+			if telemetry != nil {
+				sim.Instrument(telemetry, "simulated")
+			}
+
+			ch, err := client.DetectChain(ctx, sim)
+			if err != nil {
+				return err
+			}
+			gasAnalyzer := analyzer.NewClientAnalyzer(sim, ch)
+			for _, tx := range txs {
+				output, err := inspectTransaction(ctx, sim, tx.Hash(), nil)
+				if err != nil {
+					return err
+				}
+				analysis, err := gasAnalyzer.AnalyzeGas(ctx, tx.Hash().Hex())
+				if err != nil {
+					return fmt.Errorf("failed to analyze gas: %w", err)
+				}
+				cmd.Print(output)
+				cmd.Print(FormatGasAnalysis(analysis))
+				cmd.Println()
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// seedSimChain sends one transaction of each type and mines them. The
+// contract deployment is mined first, in a block of its own, so that the
+// gas of the calls to the contract can be estimated.
+func seedSimChain(ctx context.Context, sim *client.SimClient) ([]*types.Transaction, error) {
+	accounts := sim.Accounts()
+	gwei := big.NewInt(params.GWei)
+
+	deploy, counter, err := sim.Deploy(ctx, 0, simCounter)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sim.Mine(ctx); err != nil {
+		return nil, err
+	}
+	legacy, err := sim.SendLegacy(ctx, 1, &counter, gwei, nil)
+	if err != nil {
+		return nil, err
+	}
+	accessList, err := sim.SendAccessList(ctx, 1, &counter, gwei, nil, types.AccessList{
+		{Address: counter, StorageKeys: []common.Hash{{}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	dynamicFee, err := sim.SendDynamicFee(ctx, 2, &accounts[3], big.NewInt(params.Ether), nil)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := sim.SendBlob(ctx, 3, accounts[0], 2)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sim.Mine(ctx); err != nil {
+		return nil, err
+	}
+	return []*types.Transaction{deploy, legacy, accessList, dynamicFee, blob}, nil
+}
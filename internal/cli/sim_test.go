@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/luckify/getho/internal/client"
+	"github.com/spf13/pflag"
+)
+
+// simChain is a simulated chain seeded by seedSimChain and served over
+// HTTP, so that commands reach it as they reach a node.
+type simChain struct {
+	sim *client.SimClient
+	txs []*types.Transaction // deployment, legacy, access list, dynamic fee, blob
+}
+
+// newSimChain starts a simulated chain and points the commands at it, with
+// no config file, cache or saved probe results in the way.
+func newSimChain(t *testing.T) *simChain {
+	t.Helper()
+	sim, err := client.NewSimClient(client.SimOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sim.Close)
+	txs, err := seedSimChain(context.Background(), sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := sim.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("XDG_CACHE_HOME", home)
+	t.Setenv("GETHO_RPC_URL", server.URL)
+	return &simChain{sim: sim, txs: txs}
+}
+
+// run executes getho with args and returns what it printed.
+func (c *simChain) run(t *testing.T, args ...string) string {
+	t.Helper()
+	out, _, err := execute(args...)
+	if err != nil {
+		t.Fatalf("getho %s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+// execute executes getho with args and returns what it printed on stdout
+// and stderr.
+func execute(args ...string) (string, string, error) {
+	// Commands keep the context and flags of their last run.
+	for _, cmd := range rootCmd.Commands() {
+		cmd.SetContext(nil)
+		cmd.Flags().VisitAll(resetFlag)
+	}
+	rootCmd.PersistentFlags().VisitAll(resetFlag)
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	rootCmd.SetArgs(append(args, "--no-cache"))
+	err := Execute()
+	cleanups, telemetry = nil, nil
+	return out.String(), errOut.String(), err
+}
+
+// resetFlag restores the default value of a flag set by a previous run.
+func resetFlag(f *pflag.Flag) {
+	if !f.Changed {
+		return
+	}
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		_ = slice.Replace(nil)
+	} else {
+		_ = f.Value.Set(f.DefValue)
+	}
+	f.Changed = false
+}
+
+// receipt returns the receipt of a seeded transaction.
+func (c *simChain) receipt(t *testing.T, tx *types.Transaction) *types.Receipt {
+	t.Helper()
+	receipt, err := c.sim.GetTransactionReceipt(context.Background(), tx.Hash())
+	if err != nil || receipt == nil {
+		t.Fatalf("receipt of %s: %v", tx.Hash().Hex(), err)
+	}
+	return receipt
+}
+
+// checkOutput reports the lines of want missing from the output of a
+// command.
+func checkOutput(t *testing.T, command, output string, want ...string) {
+	t.Helper()
+	for _, line := range want {
+		if !strings.Contains(output, line) {
+			t.Errorf("%s output lacks %q:\n%s", command, line, output)
+		}
+	}
+}
+
+func TestSimTx(t *testing.T) {
+	c := newSimChain(t)
+	txTypes := []string{"EIP-1559 (0x2)", "Legacy (0x0)", "Access List (0x1)", "EIP-1559 (0x2)", "Blob (EIP-4844) (0x3)"}
+	for i, tx := range c.txs {
+		receipt := c.receipt(t, tx)
+		output := c.run(t, "tx", tx.Hash().Hex())
+		checkOutput(t, "tx", output,
+			"Hash:        "+tx.Hash().Hex(),
+			"Status:      SUCCESS",
+			"Type:        "+txTypes[i],
+			"Chain ID:    1337",
+			fmt.Sprintf("Gas Used:    %d ", receipt.GasUsed),
+			fmt.Sprintf("Block Number: %d", receipt.BlockNumber),
+			"Block Hash:   "+receipt.BlockHash.Hex(),
+		)
+	}
+	checkOutput(t, "tx", c.run(t, "tx", c.txs[0].Hash().Hex()), "To:          [Contract Creation]")
+	checkOutput(t, "tx", c.run(t, "tx", c.txs[4].Hash().Hex()), "Blobs:         2", "Blob Gas Used: 262144")
+}
+
+func TestSimGas(t *testing.T) {
+	c := newSimChain(t)
+	for _, tx := range c.txs {
+		receipt := c.receipt(t, tx)
+		output := c.run(t, "gas", tx.Hash().Hex())
+		checkOutput(t, "gas", output,
+			"Hash:         "+tx.Hash().Hex(),
+			fmt.Sprintf("Block:        %d (%s)", receipt.BlockNumber, receipt.BlockHash.Hex()),
+			fmt.Sprintf("Gas Limit:    %d", tx.Gas()),
+			fmt.Sprintf("Gas Used:     %d ", receipt.GasUsed),
+			"Base Fee:     ",
+		)
+	}
+	checkOutput(t, "gas", c.run(t, "gas", c.txs[3].Hash().Hex()), "Gas Used:     21000 (100.00%)")
+	checkOutput(t, "gas", c.run(t, "gas", c.txs[4].Hash().Hex()), "Blob Gas Used: 262144")
+}
+
+func TestSimTrace(t *testing.T) {
+	c := newSimChain(t)
+	legacy := c.txs[1]
+	counter := legacy.To().Hex()
+
+	output := c.run(t, "trace", legacy.Hash().Hex(), "--tracer", "call")
+	checkOutput(t, "trace", output,
+		"Hash:        "+legacy.Hash().Hex(),
+		"Frames:      1",
+		"[0] CALL "+counter,
+	)
+
+	output = c.run(t, "trace", legacy.Hash().Hex(), "--tracer", "struct")
+	checkOutput(t, "trace", output, "[0] CALL "+counter, "sload=0 sstore=1")
+
+	output = c.run(t, "trace", c.txs[0].Hash().Hex(), "--tracer", "struct")
+	checkOutput(t, "trace", output, "[0] CREATE "+c.receipt(t, c.txs[0]).ContractAddress.Hex())
+
+	output = c.run(t, "trace", legacy.Hash().Hex(), "--tracer", "prestate")
+	checkOutput(t, "trace", output, counter)
+}
+
+func TestSimCalldata(t *testing.T) {
+	c := newSimChain(t)
+	deploy := c.txs[0]
+
+	output := c.run(t, "calldata", "--tx", deploy.Hash().Hex())
+	checkOutput(t, "calldata", output, fmt.Sprintf("Length:      %d bytes", len(deploy.Data())))
+
+	// 32 bytes of calldata are decoded as such, not fetched as a transaction.
+	output = c.run(t, "calldata", deploy.Hash().Hex())
+	checkOutput(t, "calldata", output, "Length:      32 bytes")
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/luckify/getho/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func newStateCmd() *cobra.Command {
+	var (
+		proof    bool
+		showCode bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "state [address] [slot...]",
+		Short: "Inspect account state",
+		Long: `Inspect the state of an account: its balance, nonce, code and the given
+storage slots (decimal or 0x-prefixed hex).
+
+The state is read at the block selected by --block, the latest block by
+default. With --proof the account and slots are fetched with eth_getProof
+and printed as JSON, with their Merkle proofs against the block's state
+root.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !common.IsHexAddress(args[0]) {
+				return fmt.Errorf("invalid address: %s", args[0])
+			}
+			account := common.HexToAddress(args[0])
+			slots := make([]common.Hash, 0, len(args)-1)
+			for _, arg := range args[1:] {
+				slot, err := parseSlot(arg)
+				if err != nil {
+					return err
+				}
+				slots = append(slots, slot)
+			}
+
+			// Create client
+			ctx := cmd.Context()
+			ethClient, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+			defer ethClient.Close()
+
+			ref, label, err := stateBlock(ctx, ethClient)
+			if err != nil {
+				return err
+			}
+
+			if proof {
+				result, err := ethClient.GetProof(ctx, account, slots, ref)
+				if err != nil {
+					return fmt.Errorf("failed to fetch proof: %w", err)
+				}
+				out, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(out))
+				return nil
+			}
+
+			state := &AccountState{Address: account, Block: label}
+			if state.Balance, err = ethClient.GetBalance(ctx, account, ref); err != nil {
+				return fmt.Errorf("failed to fetch balance: %w", err)
+			}
+			if state.Nonce, err = ethClient.GetNonce(ctx, account, ref); err != nil {
+				return fmt.Errorf("failed to fetch nonce: %w", err)
+			}
+			if state.Code, err = ethClient.GetCode(ctx, account, ref); err != nil {
+				return fmt.Errorf("failed to fetch code: %w", err)
+			}
+			for _, slot := range slots {
+				value, err := ethClient.GetStorageAt(ctx, account, slot, ref)
+				if err != nil {
+					// Show the slots read before the interruption.
+					if ctx.Err() != nil {
+						cmd.Print(FormatAccountState(state, showCode))
+					}
+					return fmt.Errorf("failed to fetch storage slot %s: %w", slot.Hex(), err)
+				}
+				state.Storage = append(state.Storage, [2]common.Hash{slot, value})
+			}
+			cmd.Print(FormatAccountState(state, showCode))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&proof, "proof", false, "fetch the account and slots with eth_getProof and print the result as JSON")
+	cmd.Flags().BoolVar(&showCode, "code", false, "print the account's full bytecode")
+
+	return cmd
+}
+
+// stateBlock resolves --block for a state read. Block numbers and tags
+// other than pending are pinned to the number of the block they refer to,
+// so that successive reads see the same state even as the chain advances.
+// It also returns a description of the block for display.
+func stateBlock(ctx context.Context, c client.Client) (client.BlockRef, string, error) {
+	ref, err := blockRef(ctx, c)
+	if err != nil {
+		return client.BlockRef{}, "", err
+	}
+	if ref.Hash != nil || (ref.Number != nil && ref.Number.Int64() == int64(rpc.PendingBlockNumber)) {
+		return ref, ref.String(), nil
+	}
+
+	header, err := c.GetBlockHeader(ctx, ref.Number)
+	if err != nil {
+		return client.BlockRef{}, "", fmt.Errorf("failed to fetch block %s: %w", ref, err)
+	}
+	if err := requireState(c, header.Number.Uint64()); err != nil {
+		return client.BlockRef{}, "", err
+	}
+	label := fmt.Sprintf("%d (%s)", header.Number, header.Hash().Hex())
+	if ref.Number == nil || ref.Number.Sign() < 0 {
+		label += " [" + ref.String() + "]"
+	}
+	return client.NumberRef(header.Number.Uint64()), label, nil
+}
+
+// parseSlot parses a storage slot given in decimal or 0x-prefixed hex.
+func parseSlot(s string) (common.Hash, error) {
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		return common.Hash{}, fmt.Errorf("invalid storage slot: %s (expected a 256-bit decimal or 0x-prefixed hex number)", s)
+	}
+	return common.BigToHash(n), nil
+}
+
+// AccountState is the state of an account at a block, as shown by the state
+// command.
+type AccountState struct {
+	Address common.Address
+	Block   string
+	Balance *big.Int
+	Nonce   uint64
+	Code    []byte
+	Storage [][2]common.Hash // slot, value
+}
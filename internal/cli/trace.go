@@ -1,24 +1,137 @@
 package cli
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/luckify/getho/internal/client"
+	"github.com/luckify/getho/internal/tracer"
 	"github.com/spf13/cobra"
 )
 
 func newTraceCmd() *cobra.Command {
+	var (
+		tracerName   string
+		traceTimeout time.Duration
+		diffMode     bool
+		onlyTopCall  bool
+		traceAPI     string
+	)
+
 	cmd := &cobra.Command{
 		Use:   "trace [tx_hash]",
 		Short: "Generate full execution trace",
 		Long: `Generate an opcode-level execution trace for a transaction.
 Tracks CALL, DELEGATECALL, STATICCALL, SSTORE, SLOAD operations
-and identifies execution paths and state changes.`,
+and identifies execution paths and state changes.
+
+Requires a node with the debug namespace enabled. The --tracer flag selects
+the geth tracer: "call" (call tree), "struct" (opcode-level struct logger)
+or "prestate" (accounts and storage touched by the transaction).
+
+Erigon and Nethermind nodes can be traced through the Parity-style trace
+namespace instead: --trace-api parity maps the call tracer to
+trace_transaction, the struct logger to trace_replayTransaction's trace and
+vmTrace, and the prestate tracer to its stateDiff, with the output
+normalized to what geth reports. The default, auto, uses the debug namespace
+and falls back to the trace namespace where debug is not served.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			txHash := args[0]
+			txHash, err := parseTxHash(args[0])
+			if err != nil {
+				return err
+			}
+
+			tracerType, err := parseTracer(tracerName)
+			if err != nil {
+				return err
+			}
+			api, err := tracer.ParseAPI(traceAPI)
+			if err != nil {
+				return err
+			}
+			opts := &client.TraceOptions{
+				Tracer:      tracerType,
+				Timeout:     traceTimeout,
+				DiffMode:    diffMode,
+				OnlyTopCall: onlyTopCall,
+			}
+
+			// Create client
+			ctx := cmd.Context()
+			ethClient, err := newClient(ctx)
+			if err != nil {
+				return err
+			}
+			defer ethClient.Close()
+
+			if api, err = resolveTraceAPI(ethClient, api); err != nil {
+				return err
+			}
+			if err := requireTxState(ctx, ethClient, txHash); err != nil {
+				return err
+			}
+			traceClient := tracer.NewClientTracerForAPI(ethClient, opts, api)
+
+			// Prestate output has no call frames; display it directly.
+			if tracerType == client.TracerPrestate {
+				state, err := traceClient.Prestate(ctx, txHash.Hex())
+				if err != nil {
+					return fmt.Errorf("failed to trace transaction: %w", err)
+				}
+				cmd.Print(FormatPrestate(txHash.Hex(), state))
+				return nil
+			}
+
+			trace, err := traceClient.Trace(ctx, txHash.Hex())
+			if err != nil {
+				return fmt.Errorf("failed to trace transaction: %w", err)
+			}
 
-			cmd.Printf("Tracing execution for transaction: %s\n", txHash)
+			cmd.Print(FormatTrace(trace))
 			return nil
 		},
 	}
 
+	cmd.Flags().StringVar(&tracerName, "tracer", "call", "geth tracer to use: call, struct or prestate")
+	cmd.Flags().DurationVar(&traceTimeout, "trace-timeout", client.DefaultTraceTimeout, "maximum time the node may spend tracing")
+	cmd.Flags().BoolVar(&diffMode, "diff", false, "show pre/post state diff (prestate tracer only)")
+	cmd.Flags().BoolVar(&onlyTopCall, "only-top-call", false, "do not trace sub-calls (call tracer only)")
+	cmd.Flags().StringVar(&traceAPI, "trace-api", "auto", "JSON-RPC namespace to trace with: geth (debug_*), parity (trace_*) or auto")
+
 	return cmd
 }
+
+// parseTracer maps a --tracer flag value to a client tracer type.
+func parseTracer(name string) (client.TracerType, error) {
+	switch name {
+	case "call":
+		return client.TracerCall, nil
+	case "struct":
+		return client.TracerStructLog, nil
+	case "prestate":
+		return client.TracerPrestate, nil
+	default:
+		return "", fmt.Errorf("unknown tracer %q (expected call, struct or prestate)", name)
+	}
+}
+
+// resolveTraceAPI checks that the endpoints behind c serve the namespace of
+// api, per cached probe results. In auto mode the trace namespace is chosen
+// up front when only it is known to be served.
+func resolveTraceAPI(c client.Client, api tracer.API) (tracer.API, error) {
+	switch api {
+	case tracer.APIGeth:
+		return api, requireNamespace(c, "debug")
+	case tracer.APIParity:
+		return api, requireNamespace(c, "trace")
+	}
+	err := requireNamespace(c, "debug")
+	if err == nil {
+		return api, nil
+	}
+	if requireNamespace(c, "trace") == nil {
+		return tracer.APIParity, nil
+	}
+	return api, err
+}
@@ -2,8 +2,12 @@ package cli
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -13,80 +17,230 @@ import (
 )
 
 func newTxCmd() *cobra.Command {
+	var (
+		raw     bool
+		sidecar string
+	)
+
 	cmd := &cobra.Command{
-		Use:   "tx [tx_hash]",
+		Use:   "tx [tx_hash | raw_tx]",
 		Short: "Inspect a transaction",
 		Long: `Inspect a transaction by its hash. Displays sender, recipient,
-value, nonce, type, and decodes RLP-encoded transaction data.`,
-		Args: cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			txHashStr := args[0]
+value, nonce, type, and decodes RLP-encoded transaction data.
 
-			// Validate and parse transaction hash
-			if len(txHashStr) < 2 || txHashStr[:2] != "0x" {
-				return fmt.Errorf("invalid transaction hash: %s (must start with 0x)", txHashStr)
-			}
-			hexPart := txHashStr[2:]
-			if len(hexPart) != 64 {
-				return fmt.Errorf("invalid transaction hash: %s (expected 64 hex characters after 0x, got %d)", txHashStr, len(hexPart))
-			}
+With --raw the argument is a signed transaction in hex, as passed to
+eth_sendRawTransaction, and is decoded without contacting a node; without
+an argument it is read from stdin, in hex or binary. Legacy and all typed
+transactions are supported, including blob transactions in their network
+form with sidecar. A raw transaction is interpreted under the latest fork
+rules of its chain.
 
-			txHash := common.HexToHash(txHashStr)
-			if txHash == (common.Hash{}) {
-				return fmt.Errorf("invalid transaction hash: %s", txHashStr)
+The blobs of a blob transaction are verified against its versioned hashes
+(commitments and KZG proofs) when a sidecar is available: carried by a raw
+transaction in network form, or given with --sidecar as a JSON file, either
+{"blobs": [...], "commitments": [...], "proofs": [...]} or the response of
+the beacon API's /eth/v1/beacon/blob_sidecars/{block_id}.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if raw {
+				return cobra.MaximumNArgs(1)(cmd, args)
 			}
-
-			// Get RPC URL
-			rpcURL := GetRPCURL()
-
-			// Create client
-			ctx := context.Background()
-			ethClient, err := client.NewClient(ctx, rpcURL)
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sc, err := readSidecar(sidecar)
 			if err != nil {
-				return fmt.Errorf("failed to connect to Ethereum node at %s: %w", rpcURL, err)
+				return err
 			}
-			defer ethClient.Close()
 
-			// Fetch transaction
-			tx, isPending, err := ethClient.GetTransaction(ctx, txHash)
-			if err != nil {
-				return fmt.Errorf("failed to fetch transaction: %w", err)
+			if raw {
+				data, err := readRawTx(cmd, args)
+				if err != nil {
+					return err
+				}
+				if err := registerChainConfig(); err != nil {
+					return err
+				}
+				decodedTx, err := decoder.NewEthereumDecoder().DecodeTransaction(data)
+				if err != nil {
+					return err
+				}
+				if sc != nil {
+					if err := decodedTx.VerifySidecar(sc); err != nil {
+						return err
+					}
+				}
+				cmd.Print(FormatTransaction(decodedTx, nil, false))
+				return nil
 			}
 
-			if tx == nil {
-				return fmt.Errorf("transaction not found: %s", txHashStr)
-			}
+			txHashStr := args[0]
 
-			// Get sender address
-			sender, err := decoder.GetSender(tx)
+			// Validate and parse transaction hash
+			txHash, err := parseTxHash(txHashStr)
 			if err != nil {
-				return fmt.Errorf("failed to extract sender address: %w", err)
+				return err
 			}
 
-			// Fetch receipt if transaction is not pending
-			var receipt *types.Receipt
-			if !isPending {
-				receipt, err = ethClient.GetTransactionReceipt(ctx, txHash)
-				if err != nil {
-					// Receipt might not be available yet, continue without it
-					fmt.Fprintf(os.Stderr, "Warning: could not fetch receipt: %v\n", err)
-				}
+			// Create client
+			ctx := cmd.Context()
+			ethClient, err := newClient(ctx)
+			if err != nil {
+				return err
 			}
+			defer ethClient.Close()
 
-			// Decode transaction
-			dec := decoder.NewEthereumDecoder()
-			decodedTx, err := dec.FromGoEthereumTransaction(tx, receipt, sender)
+			output, err := inspectTransaction(ctx, ethClient, txHash, sc)
 			if err != nil {
-				return fmt.Errorf("failed to decode transaction: %w", err)
+				return err
 			}
-
-			// Display formatted transaction
-			output := FormatTransaction(decodedTx, receipt, isPending)
 			cmd.Print(output)
 
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVar(&raw, "raw", false, "decode a signed raw transaction given in hex or on stdin, without RPC")
+	cmd.Flags().StringVar(&sidecar, "sidecar", "", "verify the blobs of a blob transaction against a sidecar JSON `file`")
+
 	return cmd
 }
+
+// readRawTx returns the raw transaction given as the argument or on stdin.
+// Hex input may omit the 0x prefix; stdin input that is not hex is taken as
+// binary.
+func readRawTx(cmd *cobra.Command, args []string) ([]byte, error) {
+	if len(args) == 1 {
+		data, err := hex.DecodeString(strings.TrimPrefix(args[0], "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw transaction: %w", err)
+		}
+		return data, nil
+	}
+
+	input, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw transaction: %w", err)
+	}
+	text := strings.TrimPrefix(strings.TrimSpace(string(input)), "0x")
+	if data, err := hex.DecodeString(text); err == nil {
+		return data, nil
+	}
+	return input, nil
+}
+
+// readSidecar reads the blob sidecar file at path; it returns nil if path is
+// empty.
+func readSidecar(path string) (*types.BlobTxSidecar, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sidecar: %w", err)
+	}
+	return decoder.ParseSidecar(data)
+}
+
+// inspectTransaction fetches and decodes a transaction and returns its
+// formatted description. If sidecar is not nil, the blobs of the transaction
+// are verified against it.
+func inspectTransaction(ctx context.Context, ethClient client.Client, txHash common.Hash, sidecar *types.BlobTxSidecar) (string, error) {
+	// Fetch transaction, receipt and header in as few round trips as possible
+	bundle, err := client.GetTransactionBundle(ctx, ethClient, txHash)
+	if errors.Is(err, types.ErrTxTypeNotSupported) {
+		return inspectRawTransaction(ctx, ethClient, txHash, sidecar)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+
+	if bundle == nil {
+		return "", txNotFound(ethClient, txHash)
+	}
+	tx, isPending, receipt, header := bundle.Transaction, bundle.IsPending, bundle.Receipt, bundle.Header
+	if bundle.ReceiptErr != nil {
+		// Receipt might not be available yet, continue without it
+		fmt.Fprintf(os.Stderr, "Warning: could not fetch receipt: %v\n", bundle.ReceiptErr)
+	}
+
+	// Identify the chain to apply the fork rules of the transaction's block
+	ch, err := client.DetectChain(ctx, ethClient)
+	if err != nil {
+		return "", err
+	}
+
+	// Get sender address
+	sender, err := decoder.GetSenderAt(tx, ch, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract sender address: %w", err)
+	}
+
+	// Decode transaction
+	dec := decoder.NewEthereumDecoderForChain(ch)
+	decodedTx, err := dec.FromGoEthereumTransaction(tx, receipt, header, sender)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if sidecar != nil {
+		if err := decodedTx.VerifySidecar(sidecar); err != nil {
+			return "", err
+		}
+	}
+
+	return FormatTransaction(decodedTx, receipt, isPending), nil
+}
+
+// inspectRawTransaction is inspectTransaction for transaction types that
+// go-ethereum cannot decode, such as EIP-7702 set-code transactions: the
+// transaction is fetched in its binary encoding and decoded by getho.
+func inspectRawTransaction(ctx context.Context, ethClient client.Client, txHash common.Hash, sidecar *types.BlobTxSidecar) (string, error) {
+	raw, err := client.GetRawTransaction(ctx, ethClient, txHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch raw transaction: %w", err)
+	}
+	if raw == nil {
+		return "", txNotFound(ethClient, txHash)
+	}
+	receipt, err := ethClient.GetTransactionReceipt(ctx, txHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch receipt: %w", err)
+	}
+	var header *types.Header
+	if receipt != nil {
+		if header, err = ethClient.GetBlockHeader(ctx, receipt.BlockNumber); err != nil {
+			return "", fmt.Errorf("failed to fetch block %d: %w", receipt.BlockNumber, err)
+		}
+	}
+
+	ch, err := client.DetectChain(ctx, ethClient)
+	if err != nil {
+		return "", err
+	}
+	decodedTx, err := decoder.NewEthereumDecoderForChain(ch).DecodeTransactionAt(raw, receipt, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if sidecar != nil {
+		if err := decodedTx.VerifySidecar(sidecar); err != nil {
+			return "", err
+		}
+	}
+	return FormatTransaction(decodedTx, receipt, receipt == nil), nil
+}
+
+// parseTxHash validates and parses a 0x-prefixed transaction hash.
+func parseTxHash(txHashStr string) (common.Hash, error) {
+	if len(txHashStr) < 2 || txHashStr[:2] != "0x" {
+		return common.Hash{}, fmt.Errorf("invalid transaction hash: %s (must start with 0x)", txHashStr)
+	}
+	hexPart := txHashStr[2:]
+	if len(hexPart) != 64 {
+		return common.Hash{}, fmt.Errorf("invalid transaction hash: %s (expected 64 hex characters after 0x, got %d)", txHashStr, len(hexPart))
+	}
+
+	txHash := common.HexToHash(txHashStr)
+	if txHash == (common.Hash{}) {
+		return common.Hash{}, fmt.Errorf("invalid transaction hash: %s", txHashStr)
+	}
+	return txHash, nil
+}
@@ -0,0 +1,293 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Auth holds the credentials sent with every request to an endpoint.
+//
+// At most one of basic auth, BearerToken and JWTSecretFile may be set, since
+// each of them produces the Authorization header.
+type Auth struct {
+	// Headers are added to every HTTP request and to the WebSocket handshake,
+	// e.g. an API key required by an auth proxy.
+	Headers http.Header
+
+	// BasicUser and BasicPassword enable HTTP basic authentication.
+	BasicUser     string
+	BasicPassword string
+
+	// BearerToken is sent as "Authorization: Bearer <token>".
+	BearerToken string
+
+	// JWTSecretFile is the path of a hex-encoded 32-byte secret, as passed
+	// to geth's --authrpc.jwtsecret. A fresh HS256 token carrying the
+	// current time is signed for every request.
+	JWTSecretFile string
+}
+
+// ParseHeader parses a "Name: value" header specification.
+func ParseHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid header %q (expected \"Name: value\")", redactHeaderSpec(s))
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// redactHeaderSpec hides everything but the name of a header specification.
+func redactHeaderSpec(s string) string {
+	if name, _, ok := strings.Cut(s, ":"); ok {
+		return name + ": xxxxx"
+	}
+	return "xxxxx"
+}
+
+// ParseBasicAuth parses a "user:password" basic auth specification.
+func ParseBasicAuth(s string) (string, string, error) {
+	user, password, ok := strings.Cut(s, ":")
+	if !ok || user == "" {
+		return "", "", errors.New("invalid basic auth (expected \"user:password\")")
+	}
+	return user, password, nil
+}
+
+// empty reports whether a carries no credentials.
+func (a *Auth) empty() bool {
+	return a == nil || (len(a.Headers) == 0 && a.BasicUser == "" && a.BearerToken == "" && a.JWTSecretFile == "")
+}
+
+// options converts a into dial options. The returned secrets are the
+// credential values that must never appear in error messages.
+func (a *Auth) options() ([]rpc.ClientOption, []string, error) {
+	if a.empty() {
+		return nil, nil, nil
+	}
+
+	schemes := 0
+	for _, set := range []bool{a.BasicUser != "", a.BearerToken != "", a.JWTSecretFile != ""} {
+		if set {
+			schemes++
+		}
+	}
+	if schemes > 1 {
+		return nil, nil, errors.New("basic auth, bearer token and JWT secret are mutually exclusive")
+	}
+
+	var (
+		opts    []rpc.ClientOption
+		secrets []string
+	)
+	if len(a.Headers) > 0 {
+		opts = append(opts, rpc.WithHeaders(a.Headers))
+		for name, values := range a.Headers {
+			for _, value := range values {
+				if isCredentialHeader(name, value) {
+					secrets = append(secrets, value)
+				}
+			}
+		}
+	}
+
+	var auth rpc.HTTPAuth
+	if a.BasicUser != "" {
+		user, password := a.BasicUser, a.BasicPassword
+		auth = func(h http.Header) error {
+			h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+			return nil
+		}
+		secrets = append(secrets, password)
+	}
+	if a.BearerToken != "" {
+		token := a.BearerToken
+		auth = func(h http.Header) error {
+			h.Set("Authorization", "Bearer "+token)
+			return nil
+		}
+		secrets = append(secrets, token)
+	}
+	if a.JWTSecretFile != "" {
+		secret, err := readJWTSecret(a.JWTSecretFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		auth = func(h http.Header) error {
+			h.Set("Authorization", "Bearer "+signJWT(secret, time.Now()))
+			return nil
+		}
+		secrets = append(secrets, hex.EncodeToString(secret))
+	}
+	if auth != nil {
+		if a.Headers.Get("Authorization") != "" {
+			return nil, nil, errors.New("an Authorization header conflicts with basic auth, bearer token or JWT secret")
+		}
+		opts = append(opts, rpc.WithHTTPAuth(auth))
+	}
+	return opts, secrets, nil
+}
+
+// credentialHeaderWords are the words whose presence in a header name marks
+// it as carrying credentials, as in Authorization, X-API-Key, X-Auth-Token
+// or Cookie.
+var credentialHeaderWords = []string{"auth", "key", "token", "secret", "password", "cookie", "session", "signature"}
+
+// isCredentialHeader reports whether a header value must be hidden in error
+// messages: values of credential headers, and values that look like API
+// keys. Other values, such as the 1 of "X-Version: 1", are left alone, as
+// redacting them would garble every message containing them.
+func isCredentialHeader(name, value string) bool {
+	if value == "" {
+		return false
+	}
+	name = strings.ToLower(name)
+	for _, word := range credentialHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return isKeyLike(value)
+}
+
+// readJWTSecret reads a hex-encoded 32-byte JWT secret file.
+func readJWTSecret(path string) ([]byte, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT secret: %w", err)
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil || len(secret) != 32 {
+		// The file content is deliberately not quoted.
+		return nil, fmt.Errorf("invalid JWT secret in %s (expected 32 hex-encoded bytes)", path)
+	}
+	return secret, nil
+}
+
+// jwtHeader is the base64url-encoded JOSE header of HS256 tokens.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// signJWT returns an HS256 token whose only claim is the issued-at time, as
+// expected by the engine API authentication of execution clients.
+func signJWT(secret []byte, now time.Time) string {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"iat":%d}`, now.Unix())))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(jwtHeader + "." + claims))
+	return jwtHeader + "." + claims + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RedactURL hides the credentials of an endpoint URL for display, as listed
+// by urlSecrets. IPC paths and unparsable URLs are returned unchanged.
+func RedactURL(rpcURL string) string {
+	u, err := url.Parse(rpcURL)
+	if err != nil || u.Scheme == "" {
+		return rpcURL
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if isKeyLike(segment) {
+			segments[i] = "xxxxx"
+		}
+	}
+	u.Path, u.RawPath = strings.Join(segments, "/"), ""
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			query.Set(name, "xxxxx")
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+// urlSecrets returns the parts of an endpoint URL that may be credentials:
+// the password, path segments that look like API keys (as in Infura's
+// /v3/<key> and Alchemy's /v2/<key>) and query parameter values.
+func urlSecrets(rpcURL string) []string {
+	u, err := url.Parse(rpcURL)
+	if err != nil || u.Scheme == "" {
+		return nil
+	}
+	var secrets []string
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if isKeyLike(segment) {
+			secrets = append(secrets, segment)
+		}
+	}
+	for _, values := range u.Query() {
+		for _, value := range values {
+			if value != "" {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	return secrets
+}
+
+// isKeyLike reports whether a URL path segment looks like an API key: a long
+// token of letters, digits, dashes and underscores that is not a plain word.
+func isKeyLike(segment string) bool {
+	if len(segment) < 16 {
+		return false
+	}
+	digits := false
+	for _, c := range segment {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return digits || len(segment) >= 32
+}
+
+// redactedError hides secrets in the message of the wrapped error.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redact replaces every occurrence of secrets in err's message. The error
+// chain is kept intact for errors.Is and errors.As, and an *RPCError stays
+// on top so its method and category remain visible.
+func redact(err error, secrets []string) error {
+	if err == nil || len(secrets) == 0 {
+		return err
+	}
+	msg := err.Error()
+	redacted := redactString(msg, secrets)
+	if redacted == msg {
+		return err
+	}
+	if e, ok := err.(*RPCError); ok {
+		copied := *e
+		copied.Err = redact(e.Err, secrets)
+		return &copied
+	}
+	return &redactedError{msg: redacted, err: err}
+}
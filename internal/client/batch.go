@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TransactionBundle groups a transaction with its receipt and the header of
+// its containing block, which together are what most commands need.
+type TransactionBundle struct {
+	Transaction *types.Transaction
+	IsPending   bool
+
+	// Receipt and Header are nil for pending transactions.
+	Receipt *types.Receipt
+	Header  *types.Header
+
+	// ReceiptErr is set if the receipt of a mined transaction could not be
+	// fetched. Receipt and Header are nil then, but the transaction is
+	// still returned.
+	ReceiptErr error
+}
+
+// BundleFetcher is implemented by clients that can fetch a TransactionBundle
+// more efficiently than by issuing the individual Client calls.
+type BundleFetcher interface {
+	// GetTransactionBundle retrieves a transaction, its receipt and its
+	// containing block header. Returns nil, nil if the transaction is not found.
+	GetTransactionBundle(ctx context.Context, txHash common.Hash) (*TransactionBundle, error)
+}
+
+// GetTransactionBundle retrieves a transaction, its receipt and its containing
+// block header through c.
+//
+// If c implements BundleFetcher its batched path is used, otherwise the
+// objects are fetched with individual Client calls.
+// Returns nil, nil if the transaction is not found.
+func GetTransactionBundle(ctx context.Context, c Client, txHash common.Hash) (*TransactionBundle, error) {
+	if f, ok := c.(BundleFetcher); ok {
+		return f.GetTransactionBundle(ctx, txHash)
+	}
+	return getTransactionBundle(ctx, c, txHash)
+}
+
+// getTransactionBundle fetches a TransactionBundle with individual Client
+// calls. The transaction and receipt are requested concurrently, so that like
+// the batched path it takes two round trips.
+func getTransactionBundle(ctx context.Context, c Client, txHash common.Hash) (*TransactionBundle, error) {
+	var (
+		receipt    *types.Receipt
+		receiptErr error
+		done       = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		receipt, receiptErr = c.GetTransactionReceipt(ctx, txHash)
+	}()
+	tx, isPending, err := c.GetTransaction(ctx, txHash)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, nil
+	}
+	bundle := &TransactionBundle{Transaction: tx, IsPending: isPending}
+	if isPending {
+		return bundle, nil
+	}
+
+	if receiptErr != nil && !errors.Is(receiptErr, ErrNotFound) {
+		bundle.ReceiptErr = receiptErr
+		return bundle, nil
+	}
+	bundle.Receipt = receipt
+	if receipt != nil {
+		if bundle.Header, err = c.GetBlockHeader(ctx, receipt.BlockNumber); err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
+
+// rpcTransaction mirrors ethclient's view of eth_getTransactionByHash
+// results, keeping the inclusion fields that types.Transaction drops.
+type rpcTransaction struct {
+	tx *types.Transaction
+	txExtraInfo
+}
+
+type txExtraInfo struct {
+	BlockNumber *string      `json:"blockNumber,omitempty"`
+	BlockHash   *common.Hash `json:"blockHash,omitempty"`
+}
+
+func (tx *rpcTransaction) UnmarshalJSON(msg []byte) error {
+	if err := json.Unmarshal(msg, &tx.tx); err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, &tx.txExtraInfo)
+}
+
+// locateTransaction retrieves a transaction and the hash and number of the
+// block including it from a single eth_getTransactionByHash call.
+func (c *RPCClient) locateTransaction(ctx context.Context, txHash common.Hash) (txResult, error) {
+	var result *rpcTransaction
+	if err := c.call(ctx, &result, "eth_getTransactionByHash", txHash); err != nil {
+		return txResult{}, err
+	}
+	if result == nil || result.tx == nil {
+		return txResult{}, nil
+	}
+	res := txResult{tx: result.tx, isPending: result.BlockNumber == nil}
+	if !res.isPending {
+		number, err := hexutil.DecodeBig(*result.BlockNumber)
+		if err != nil {
+			return txResult{}, fmt.Errorf("invalid block number of transaction %s: %w", txHash.Hex(), err)
+		}
+		res.blockNumber = number
+		if result.BlockHash != nil {
+			res.blockHash = *result.BlockHash
+		}
+	}
+	return res, nil
+}
+
+// GetTransactionBundle retrieves a transaction, its receipt and its
+// containing block header using JSON-RPC batch requests.
+//
+// The transaction and receipt are fetched in a single batch. The header can
+// only be requested once the containing block hash is known, so it follows in
+// a second request; callers therefore pay two round trips instead of three.
+func (c *RPCClient) GetTransactionBundle(ctx context.Context, txHash common.Hash) (*TransactionBundle, error) {
+	var (
+		txResult *rpcTransaction
+		receipt  *types.Receipt
+	)
+	batch := []rpc.BatchElem{
+		{Method: "eth_getTransactionByHash", Args: []interface{}{txHash}, Result: &txResult},
+		{Method: "eth_getTransactionReceipt", Args: []interface{}{txHash}, Result: &receipt},
+	}
+	err := c.do(ctx, "eth_getTransactionByHash", func(ctx context.Context) error {
+		nameRequest(ctx, batchMethod([]string{batch[0].Method, batch[1].Method}))
+		if err := c.rpc.BatchCallContext(ctx, batch); err != nil {
+			return err
+		}
+		return batch[0].Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txResult == nil || txResult.tx == nil {
+		return nil, nil
+	}
+
+	bundle := &TransactionBundle{
+		Transaction: txResult.tx,
+		IsPending:   txResult.BlockNumber == nil,
+	}
+	if bundle.IsPending {
+		return bundle, nil
+	}
+
+	if err := classifyError("eth_getTransactionReceipt", batch[1].Error); err != nil && !errors.Is(err, ErrNotFound) {
+		bundle.ReceiptErr = err
+		return bundle, nil
+	}
+	bundle.Receipt = receipt
+	if txResult.BlockHash == nil {
+		return bundle, nil
+	}
+
+	var header *types.Header
+	if err := c.call(ctx, &header, "eth_getBlockByHash", *txResult.BlockHash, false); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("containing block not found")
+	}
+	bundle.Header = header
+	return bundle, nil
+}
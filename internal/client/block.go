@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// receiptFetchWorkers bounds the concurrent per-transaction receipt requests
+// issued when an endpoint lacks eth_getBlockReceipts.
+const receiptFetchWorkers = 16
+
+// BlockRef identifies a block by number, hash or tag. The zero value refers
+// to the latest block.
+type BlockRef struct {
+	// Number is a block number or one of the negative rpc.BlockNumber tags
+	// (rpc.LatestBlockNumber, rpc.SafeBlockNumber, ...). Nil means latest.
+	Number *big.Int
+
+	// Hash, if set, selects the block by hash; Number is then ignored.
+	Hash *common.Hash
+}
+
+// NumberRef refers to the canonical block with number n.
+func NumberRef(n uint64) BlockRef {
+	return BlockRef{Number: new(big.Int).SetUint64(n)}
+}
+
+// HashRef refers to the block with the given hash.
+func HashRef(hash common.Hash) BlockRef {
+	return BlockRef{Hash: &hash}
+}
+
+// TagRef refers to a block by tag, e.g. rpc.FinalizedBlockNumber.
+func TagRef(tag rpc.BlockNumber) BlockRef {
+	return BlockRef{Number: big.NewInt(int64(tag))}
+}
+
+// ParseBlockRef parses a block number (decimal or 0x-prefixed hex), a
+// 0x-prefixed block hash or one of the tags latest, safe, finalized,
+// pending and earliest. Earliest is block 0, as in go-ethereum, where
+// rpc.EarliestBlockNumber is 0 rather than a negative tag.
+func ParseBlockRef(s string) (BlockRef, error) {
+	switch strings.ToLower(s) {
+	case "latest":
+		return TagRef(rpc.LatestBlockNumber), nil
+	case "safe":
+		return TagRef(rpc.SafeBlockNumber), nil
+	case "finalized":
+		return TagRef(rpc.FinalizedBlockNumber), nil
+	case "pending":
+		return TagRef(rpc.PendingBlockNumber), nil
+	case "earliest":
+		return NumberRef(0), nil
+	}
+	if strings.HasPrefix(s, "0x") && len(s) == 2+2*common.HashLength {
+		hash := common.HexToHash(s)
+		if hash.Hex() != strings.ToLower(s) {
+			return BlockRef{}, fmt.Errorf("invalid block hash: %s", s)
+		}
+		return HashRef(hash), nil
+	}
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok || n.Sign() < 0 || !n.IsUint64() {
+		return BlockRef{}, fmt.Errorf("invalid block: %s (expected a number, hash or latest, safe, finalized, pending or earliest)", s)
+	}
+	return BlockRef{Number: n}, nil
+}
+
+// String formats the reference as accepted by ParseBlockRef.
+func (r BlockRef) String() string {
+	switch {
+	case r.Hash != nil:
+		return r.Hash.Hex()
+	case r.Number == nil:
+		return "latest"
+	case r.Number.Sign() < 0:
+		return rpc.BlockNumber(r.Number.Int64()).String()
+	}
+	return r.Number.String()
+}
+
+// tag returns the tag r refers to, if any. A nil number is the latest tag.
+func (r BlockRef) tag() (rpc.BlockNumber, bool) {
+	switch {
+	case r.Hash != nil:
+		return 0, false
+	case r.Number == nil:
+		return rpc.LatestBlockNumber, true
+	case r.Number.Sign() < 0:
+		return rpc.BlockNumber(r.Number.Int64()), true
+	}
+	return 0, false
+}
+
+// numberOrHash converts r into a JSON-RPC block parameter.
+func (r BlockRef) numberOrHash() rpc.BlockNumberOrHash {
+	if r.Hash != nil {
+		return rpc.BlockNumberOrHashWithHash(*r.Hash, false)
+	}
+	if tag, ok := r.tag(); ok {
+		return rpc.BlockNumberOrHashWithNumber(tag)
+	}
+	return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(r.Number.Int64()))
+}
+
+// arg converts r into the block parameter of state methods such as
+// eth_getBalance: a number or tag, or an EIP-1898 object for hashes.
+func (r BlockRef) arg() interface{} {
+	if r.Hash != nil {
+		return rpc.BlockNumberOrHashWithHash(*r.Hash, false)
+	}
+	if tag, ok := r.tag(); ok {
+		return tag
+	}
+	return rpc.BlockNumber(r.Number.Int64())
+}
+
+// errBlockNotFound is the error of block lookups that find nothing.
+func errBlockNotFound(method string, ref BlockRef) error {
+	return &RPCError{Method: method, Kind: ErrNotFound, Err: fmt.Errorf("block %s not found", ref)}
+}
+
+// fetchReceipts retrieves the receipts of txs one by one, with up to
+// receiptFetchWorkers requests in flight. The receipts are returned in the
+// order of txs.
+func fetchReceipts(ctx context.Context, c Client, txs types.Transactions) (types.Receipts, error) {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		receipts = make(types.Receipts, len(txs))
+		work     = make(chan int)
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for w := 0; w < receiptFetchWorkers && w < len(txs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				receipt, err := c.GetTransactionReceipt(ctx, txs[i].Hash())
+				if err == nil && receipt == nil {
+					err = fmt.Errorf("receipt of %s not found", txs[i].Hash().Hex())
+				}
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				receipts[i] = receipt
+			}
+		}()
+	}
+	for i := range txs {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return receipts, nil
+}
+
+// BlockAtTime returns the header of the last block produced at or before t,
+// by binary search over the headers of c. It fails if t predates genesis.
+func BlockAtTime(ctx context.Context, c Client, t time.Time) (*types.Header, error) {
+	ts := uint64(t.Unix())
+	if t.Unix() < 0 {
+		ts = 0
+	}
+	latest, err := c.GetBlockHeader(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Time <= ts {
+		return latest, nil
+	}
+	genesis, err := c.GetBlockHeader(ctx, new(big.Int))
+	if err != nil {
+		return nil, err
+	}
+	if genesis.Time > ts {
+		return nil, fmt.Errorf("%s predates the genesis block (%s)", t.UTC().Format(time.RFC3339), time.Unix(int64(genesis.Time), 0).UTC().Format(time.RFC3339))
+	}
+
+	lo, hi := genesis, latest // lo.Time <= ts < hi.Time
+	for hi.Number.Uint64()-lo.Number.Uint64() > 1 {
+		mid := lo.Number.Uint64() + (hi.Number.Uint64()-lo.Number.Uint64())/2
+		header, err := c.GetBlockHeader(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, err
+		}
+		if header.Time <= ts {
+			lo = header
+		} else {
+			hi = header
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,612 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// finalityDepth is the number of confirmations after which a block is
+// treated as final on chains that do not expose a "finalized" block tag.
+const finalityDepth = 64
+
+// DefaultCacheDir returns the default on-disk cache location, getho in the
+// user cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "getho"), nil
+}
+
+// ClearCache removes all cached data under dir.
+func ClearCache(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+// CacheOptions configures a CachingClient.
+type CacheOptions struct {
+	// Dir is the cache directory. Empty uses DefaultCacheDir.
+	Dir string
+
+	// MaxSize caps the total size of cached files in bytes. When exceeded,
+	// the least recently used entries are evicted as the client is closed.
+	// Zero means unlimited.
+	MaxSize int64
+}
+
+// CachingClient is a Client decorator that stores immutable chain data on
+// disk.
+//
+// Only transactions, receipts, headers and blocks of finalized blocks are
+// cached, keyed by chain ID and hash; pending and unfinalized data always
+// goes to the wrapped client. Cache failures are never fatal: a broken cache
+// entry is treated as a miss.
+//
+// The chain ID and the finalized block are only requested from the wrapped
+// client when they are needed: to store an entry, or to look up an entry
+// whose key does not determine the chain, such as a block number. Until
+// then, Chain and ChainID report the chain of the entries served from the
+// cache, so that a command answered from the cache makes no calls at all.
+type CachingClient struct {
+	inner   Client
+	dir     string
+	maxSize int64
+
+	mu        sync.Mutex
+	chainDir  string       // cache directory of the connected chain, resolved lazily
+	chain     *chain.Chain // connected chain, or chain of the entries served
+	finalized *big.Int
+	stored    bool // whether entries were written, so that eviction is due
+
+	// unstored holds fetched transactions whose receipt has not been seen
+	// yet, so that they are stored along with a final receipt.
+	unstored map[common.Hash]*types.Transaction
+}
+
+// NewCachingClient wraps inner with an on-disk cache.
+func NewCachingClient(inner Client, opts CacheOptions) (*CachingClient, error) {
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	return &CachingClient{
+		inner:    inner,
+		dir:      dir,
+		maxSize:  opts.MaxSize,
+		unstored: make(map[common.Hash]*types.Transaction),
+	}, nil
+}
+
+// Unwrap returns the wrapped client.
+func (c *CachingClient) Unwrap() Client {
+	return c.inner
+}
+
+// GetTransaction retrieves a transaction by hash.
+func (c *CachingClient) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	if tx := c.cachedTransaction(ctx, txHash); tx != nil {
+		return tx, false, nil
+	}
+
+	tx, isPending, err := c.inner.GetTransaction(ctx, txHash)
+	if err != nil || tx == nil || isPending {
+		return tx, isPending, err
+	}
+
+	// The transaction itself does not carry its block number; its receipt
+	// does. Rather than fetch the receipt here, the transaction is stored
+	// once its receipt is known to be final: right away if the receipt is
+	// cached, otherwise when the receipt is fetched through this client.
+	if c.cachedReceipt(ctx, txHash) != nil {
+		c.storeTransaction(ctx, tx)
+	} else {
+		c.mu.Lock()
+		c.unstored[txHash] = tx
+		c.mu.Unlock()
+	}
+	return tx, false, nil
+}
+
+// GetTransactionReceipt retrieves a transaction receipt by hash.
+func (c *CachingClient) GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if receipt := c.cachedReceipt(ctx, txHash); receipt != nil {
+		return receipt, nil
+	}
+
+	receipt, err := c.inner.GetTransactionReceipt(ctx, txHash)
+	if err != nil || receipt == nil {
+		return receipt, err
+	}
+	c.storeReceipt(ctx, receipt)
+	return receipt, nil
+}
+
+// Chain returns the chain the wrapped client identified when it was
+// created, else the chain resolved by the cache or, before that, the chain
+// of the entries served from the cache. It returns nil if neither is known.
+func (c *CachingClient) Chain() *chain.Chain {
+	if ch := knownChain(c.inner); ch != nil {
+		return ch
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.chain
+}
+
+// ChainID retrieves the chain ID of the connected network, without a call
+// if Chain knows it.
+func (c *CachingClient) ChainID(ctx context.Context) (*big.Int, error) {
+	if ch := c.Chain(); ch != nil {
+		return new(big.Int).Set(ch.Config.ChainID), nil
+	}
+	chainID, err := c.inner.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.chain = chainByID(chainID)
+	c.mu.Unlock()
+	return chainID, nil
+}
+
+// chainByID returns the registered chain of an ID, or an unknown chain.
+func chainByID(chainID *big.Int) *chain.Chain {
+	if ch, ok := chain.ByID(chainID); ok {
+		return ch
+	}
+	return chain.Unknown(chainID)
+}
+
+// GetBlockHeader retrieves a block header by number. Requests for the latest
+// block or other block tags bypass the cache.
+func (c *CachingClient) GetBlockHeader(ctx context.Context, blockNumber *big.Int) (*types.Header, error) {
+	if blockNumber == nil || blockNumber.Sign() < 0 {
+		return c.inner.GetBlockHeader(ctx, blockNumber)
+	}
+
+	if ref, ok := c.load(ctx, "number", blockNumber.String()); ok {
+		if header := c.cachedHeader(ctx, common.HexToHash(string(ref))); header != nil {
+			return header, nil
+		}
+	}
+
+	header, err := c.inner.GetBlockHeader(ctx, blockNumber)
+	if err != nil || header == nil {
+		return header, err
+	}
+	c.storeHeader(ctx, header)
+	return header, nil
+}
+
+// GetBlock retrieves a block with its full transactions by number, hash or
+// tag. Requests by tag bypass the cache.
+func (c *CachingClient) GetBlock(ctx context.Context, ref BlockRef) (*types.Block, error) {
+	if hash, ok := c.blockHash(ctx, ref); ok {
+		if data, _, ok := c.loadAny(ctx, "block", hash); ok {
+			block := new(types.Block)
+			if err := rlp.DecodeBytes(data, block); err == nil {
+				return block, nil
+			}
+		}
+	}
+
+	block, err := c.inner.GetBlock(ctx, ref)
+	if err != nil || block == nil || !c.isFinal(ctx, block.Number()) {
+		return block, err
+	}
+	if data, err := rlp.EncodeToBytes(block); err == nil {
+		c.store(ctx, "block", block.Hash().Hex(), data)
+	}
+	c.storeHeader(ctx, block.Header())
+	return block, nil
+}
+
+// GetBlockReceipts retrieves the receipts of all transactions in a block.
+// The receipts of a finalized block are cached together, keyed by block
+// hash; requests by tag bypass the cache.
+func (c *CachingClient) GetBlockReceipts(ctx context.Context, ref BlockRef) (types.Receipts, error) {
+	if hash, ok := c.blockHash(ctx, ref); ok {
+		if data, _, ok := c.loadAny(ctx, "receipts", hash); ok {
+			var receipts types.Receipts
+			if err := json.Unmarshal(data, &receipts); err == nil {
+				return receipts, nil
+			}
+		}
+	}
+
+	receipts, err := c.inner.GetBlockReceipts(ctx, ref)
+	if err != nil || len(receipts) == 0 || !c.isFinal(ctx, receipts[0].BlockNumber) {
+		return receipts, err
+	}
+	if data, err := json.Marshal(receipts); err == nil {
+		c.store(ctx, "receipts", receipts[0].BlockHash.Hex(), data)
+	}
+	return receipts, nil
+}
+
+// blockHash resolves ref to the hash of a cached block, without asking the
+// wrapped client. Tags are never resolved, since the block they refer to
+// changes.
+func (c *CachingClient) blockHash(ctx context.Context, ref BlockRef) (string, bool) {
+	if ref.Hash != nil {
+		return ref.Hash.Hex(), true
+	}
+	if _, ok := ref.tag(); ok {
+		return "", false
+	}
+	hash, ok := c.load(ctx, "number", ref.Number.String())
+	return string(hash), ok
+}
+
+// GetBalance retrieves the balance of an account at a block. Account state
+// is not cached.
+func (c *CachingClient) GetBalance(ctx context.Context, account common.Address, ref BlockRef) (*big.Int, error) {
+	return c.inner.GetBalance(ctx, account, ref)
+}
+
+// GetNonce retrieves the nonce of an account at a block.
+func (c *CachingClient) GetNonce(ctx context.Context, account common.Address, ref BlockRef) (uint64, error) {
+	return c.inner.GetNonce(ctx, account, ref)
+}
+
+// GetCode retrieves the code of an account at a block.
+func (c *CachingClient) GetCode(ctx context.Context, account common.Address, ref BlockRef) ([]byte, error) {
+	return c.inner.GetCode(ctx, account, ref)
+}
+
+// GetStorageAt retrieves the value of a storage slot of an account at a
+// block.
+func (c *CachingClient) GetStorageAt(ctx context.Context, account common.Address, slot common.Hash, ref BlockRef) (common.Hash, error) {
+	return c.inner.GetStorageAt(ctx, account, slot, ref)
+}
+
+// GetProof retrieves the state of an account and of the given storage
+// slots at a block, with their Merkle proofs.
+func (c *CachingClient) GetProof(ctx context.Context, account common.Address, slots []common.Hash, ref BlockRef) (*AccountProof, error) {
+	return c.inner.GetProof(ctx, account, slots, ref)
+}
+
+// TraceTransaction generates an execution trace for a transaction. Traces
+// depend on tracer options and are not cached.
+func (c *CachingClient) TraceTransaction(ctx context.Context, txHash common.Hash, opts *TraceOptions) (*TraceResult, error) {
+	return c.inner.TraceTransaction(ctx, txHash, opts)
+}
+
+// GetTransactionBundle retrieves a transaction, its receipt and its
+// containing block header, using the wrapped client's batched path unless
+// all three are cached.
+func (c *CachingClient) GetTransactionBundle(ctx context.Context, txHash common.Hash) (*TransactionBundle, error) {
+	if tx := c.cachedTransaction(ctx, txHash); tx != nil {
+		if receipt := c.cachedReceipt(ctx, txHash); receipt != nil {
+			if header := c.cachedHeader(ctx, receipt.BlockHash); header != nil {
+				return &TransactionBundle{Transaction: tx, Receipt: receipt, Header: header}, nil
+			}
+		}
+	}
+
+	bundle, err := GetTransactionBundle(ctx, c.inner, txHash)
+	if err != nil || bundle == nil || bundle.Receipt == nil {
+		return bundle, err
+	}
+	if c.storeReceipt(ctx, bundle.Receipt) {
+		c.storeTransaction(ctx, bundle.Transaction)
+	}
+	if bundle.Header != nil {
+		c.storeHeader(ctx, bundle.Header)
+	}
+	return bundle, nil
+}
+
+// Close evicts entries beyond the size limit if any were written, and
+// closes the wrapped client.
+func (c *CachingClient) Close() {
+	c.mu.Lock()
+	stored := c.stored
+	c.mu.Unlock()
+	if stored {
+		c.evict()
+	}
+	c.inner.Close()
+}
+
+// cachedTransaction returns a cached transaction, or nil.
+func (c *CachingClient) cachedTransaction(ctx context.Context, txHash common.Hash) *types.Transaction {
+	data, chainID, ok := c.loadAny(ctx, "tx", txHash.Hex())
+	if !ok {
+		return nil
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return nil
+	}
+	// A transaction without replay protection is valid on every chain, so
+	// only the connected chain's entry will do.
+	if chainID != nil && (!tx.Protected() || tx.ChainId().Cmp(chainID) != 0) {
+		if data, ok = c.load(ctx, "tx", txHash.Hex()); !ok || tx.UnmarshalBinary(data) != nil {
+			return nil
+		}
+	}
+	return tx
+}
+
+// cachedReceipt returns a cached receipt, or nil.
+func (c *CachingClient) cachedReceipt(ctx context.Context, txHash common.Hash) *types.Receipt {
+	data, chainID, ok := c.loadAny(ctx, "receipt", txHash.Hex())
+	if !ok {
+		return nil
+	}
+	receipt := new(types.Receipt)
+	if err := json.Unmarshal(data, receipt); err != nil {
+		return nil
+	}
+	// Typed transactions commit to their chain ID; legacy ones may be
+	// replayed on other chains, with receipts of their own.
+	if chainID != nil && receipt.Type == types.LegacyTxType {
+		if data, ok = c.load(ctx, "receipt", txHash.Hex()); !ok || json.Unmarshal(data, receipt) != nil {
+			return nil
+		}
+	}
+	return receipt
+}
+
+// cachedHeader returns a cached header, or nil.
+func (c *CachingClient) cachedHeader(ctx context.Context, blockHash common.Hash) *types.Header {
+	data, _, ok := c.loadAny(ctx, "header", blockHash.Hex())
+	if !ok {
+		return nil
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return nil
+	}
+	return header
+}
+
+// storeReceipt caches a receipt if its block is final, reporting whether it
+// did. A transaction fetched earlier by GetTransaction is cached with it.
+func (c *CachingClient) storeReceipt(ctx context.Context, receipt *types.Receipt) bool {
+	if !c.isFinal(ctx, receipt.BlockNumber) {
+		return false
+	}
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return false
+	}
+	c.store(ctx, "receipt", receipt.TxHash.Hex(), data)
+
+	c.mu.Lock()
+	tx := c.unstored[receipt.TxHash]
+	delete(c.unstored, receipt.TxHash)
+	c.mu.Unlock()
+	if tx != nil {
+		c.storeTransaction(ctx, tx)
+	}
+	return true
+}
+
+// storeTransaction caches a transaction. The caller checks that its block
+// is final.
+func (c *CachingClient) storeTransaction(ctx context.Context, tx *types.Transaction) {
+	if data, err := tx.MarshalBinary(); err == nil {
+		c.store(ctx, "tx", tx.Hash().Hex(), data)
+	}
+}
+
+// storeHeader caches a header and its number index if the block is final.
+func (c *CachingClient) storeHeader(ctx context.Context, header *types.Header) {
+	if !c.isFinal(ctx, header.Number) {
+		return
+	}
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return
+	}
+	hash := header.Hash().Hex()
+	c.store(ctx, "header", hash, data)
+	c.store(ctx, "number", header.Number.String(), []byte(hash))
+}
+
+// isFinal reports whether the block with the given number is finalized.
+//
+// The finalized block number is fetched once per client, from the
+// "finalized" tag where supported and from a fixed confirmation depth below
+// the latest block otherwise.
+func (c *CachingClient) isFinal(ctx context.Context, number *big.Int) bool {
+	if number == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	finalized := c.finalized
+	c.mu.Unlock()
+
+	if finalized == nil {
+		header, err := c.inner.GetBlockHeader(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+		if err == nil && header != nil {
+			finalized = header.Number
+		} else {
+			latest, err := c.inner.GetBlockHeader(ctx, nil)
+			if err != nil || latest == nil {
+				return false
+			}
+			finalized = new(big.Int).Sub(latest.Number, big.NewInt(finalityDepth))
+		}
+		c.mu.Lock()
+		c.finalized = finalized
+		c.mu.Unlock()
+	}
+	return number.Cmp(finalized) <= 0
+}
+
+// chainPath returns the cache directory of the connected chain.
+func (c *CachingClient) chainPath(ctx context.Context) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.chainDir == "" {
+		var chainID *big.Int
+		if ch := knownChain(c.inner); ch != nil {
+			chainID = ch.Config.ChainID
+		} else {
+			var err error
+			if chainID, err = c.inner.ChainID(ctx); err != nil {
+				return "", false
+			}
+		}
+		if chainID == nil {
+			return "", false
+		}
+		c.chainDir = filepath.Join(c.dir, chainID.String())
+		c.chain = chainByID(chainID)
+	}
+	return c.chainDir, true
+}
+
+// path returns the file holding a cache entry.
+func (c *CachingClient) path(ctx context.Context, kind, key string) (string, bool) {
+	dir, ok := c.chainPath(ctx)
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(dir, kind, strings.ToLower(key)), true
+}
+
+// load reads a cache entry of the connected chain, marking it as recently
+// used.
+func (c *CachingClient) load(ctx context.Context, kind, key string) ([]byte, bool) {
+	path, ok := c.path(ctx, kind, key)
+	if !ok {
+		return nil, false
+	}
+	return readEntry(path)
+}
+
+// loadAny reads a cache entry whose key is a hash, without resolving the
+// connected chain if it is not known yet: an entry cached for a single
+// chain is returned with that chain's ID, which the caller checks if the
+// key does not determine the chain. Entries of the connected chain are
+// returned with a nil chain ID.
+func (c *CachingClient) loadAny(ctx context.Context, kind, key string) ([]byte, *big.Int, bool) {
+	c.mu.Lock()
+	resolved := c.chainDir != ""
+	c.mu.Unlock()
+	if !resolved {
+		matches, _ := filepath.Glob(filepath.Join(c.dir, "*", kind, strings.ToLower(key)))
+		if len(matches) == 0 {
+			return nil, nil, false
+		}
+		if len(matches) == 1 {
+			chainID, ok := new(big.Int).SetString(filepath.Base(filepath.Dir(filepath.Dir(matches[0]))), 10)
+			if ok {
+				data, ok := readEntry(matches[0])
+				if ok {
+					c.mu.Lock()
+					if c.chain == nil {
+						c.chain = chainByID(chainID)
+					}
+					c.mu.Unlock()
+				}
+				return data, chainID, ok
+			}
+		}
+	}
+	data, ok := c.load(ctx, kind, key)
+	return data, nil, ok
+}
+
+// readEntry reads the cache file at path, marking it as recently used.
+func readEntry(path string) ([]byte, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return data, true
+}
+
+// store writes a cache entry atomically.
+func (c *CachingClient) store(ctx context.Context, kind, key string, data []byte) {
+	path, ok := c.path(ctx, kind, key)
+	if !ok {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	c.mu.Lock()
+	c.stored = true
+	c.mu.Unlock()
+}
+
+// evict removes least recently used entries until the cache fits MaxSize.
+// It walks the whole cache, so it runs once, as the client is closed.
+func (c *CachingClient) evict() {
+	if c.maxSize <= 0 {
+		return
+	}
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var (
+		entries []entry
+		total   int64
+	)
+	_ = filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{path, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if total <= c.maxSize {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.Remove(e.path); err == nil || errors.Is(err, fs.ErrNotExist) {
+			total -= e.size
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"testing"
+)
+
+// cacheCassette is transferCassette with the finalized block, which the
+// cache asks for before storing entries.
+const cacheCassette = "testdata/cache.jsonl"
+
+// newCacheReplayClient returns the client NewClient creates for an
+// endpoint answering from cacheCassette, with the cache in dir and the
+// calls to the endpoint recorded in the returned telemetry.
+func newCacheReplayClient(t *testing.T, dir string) (Client, *Telemetry) {
+	t.Helper()
+	replayer, err := NewReplayer(cacheCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcOpts := DefaultRPCOptions()
+	rpcOpts.Transport = replayer
+	telemetry := NewTelemetry(nil, DebugOff)
+	c, err := NewClient(context.Background(), Options{
+		RPCURLs:   []string{ReplayURL},
+		RPC:       &rpcOpts,
+		Cache:     &CacheOptions{Dir: dir},
+		Telemetry: telemetry,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, telemetry
+}
+
+// rpcCalls returns the number of calls recorded in telemetry by method.
+func rpcCalls(telemetry *Telemetry) map[string]int {
+	calls := make(map[string]int)
+	for _, s := range telemetry.Stats() {
+		calls[s.Method] += int(s.Calls)
+	}
+	return calls
+}
+
+func TestCachingClientGetTransaction(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// A cold cache costs the transaction fetch alone.
+	c, telemetry := newCacheReplayClient(t, dir)
+	tx, isPending, err := c.GetTransaction(ctx, transferTx)
+	if err != nil || tx == nil || isPending {
+		t.Fatalf("GetTransaction = %v, %v, %v", tx, isPending, err)
+	}
+	if calls := rpcCalls(telemetry); len(calls) != 1 || calls["eth_getTransactionByHash"] != 1 {
+		t.Errorf("cold GetTransaction calls = %v, want eth_getTransactionByHash only", calls)
+	}
+
+	// The transaction is stored along with its final receipt.
+	receipt, err := c.GetTransactionReceipt(ctx, transferTx)
+	if err != nil || receipt == nil {
+		t.Fatalf("GetTransactionReceipt = %v, %v", receipt, err)
+	}
+	c.Close()
+
+	c, telemetry = newCacheReplayClient(t, dir)
+	defer c.Close()
+	tx, _, err = c.GetTransaction(ctx, transferTx)
+	if err != nil || tx == nil || tx.Hash() != transferTx {
+		t.Fatalf("cached GetTransaction = %v, %v", tx, err)
+	}
+	if receipt, err = c.GetTransactionReceipt(ctx, transferTx); err != nil || receipt == nil || receipt.TxHash != transferTx {
+		t.Fatalf("cached GetTransactionReceipt = %v, %v", receipt, err)
+	}
+	if calls := rpcCalls(telemetry); len(calls) != 0 {
+		t.Errorf("warm cache calls = %v, want none", calls)
+	}
+}
+
+func TestCachingClientBundle(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// What `getho tx` asks for: a cold cache fetches the bundle, and the
+	// chain ID once, to store it.
+	c, telemetry := newCacheReplayClient(t, dir)
+	bundle, err := GetTransactionBundle(ctx, c, transferTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTransferBundle(t, bundle)
+	ch, err := DetectChain(ctx, c)
+	if err != nil || ch.Config.ChainID.Uint64() != 1337 {
+		t.Fatalf("DetectChain = %v, %v, want chain 1337", ch, err)
+	}
+	if calls := rpcCalls(telemetry); calls["eth_chainId"] != 1 {
+		t.Errorf("cold cache calls = %v, want eth_chainId once", calls)
+	}
+	c.Close()
+
+	// A warm cache answers everything, the chain included.
+	c, telemetry = newCacheReplayClient(t, dir)
+	defer c.Close()
+	bundle, err = GetTransactionBundle(ctx, c, transferTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTransferBundle(t, bundle)
+	ch, err = DetectChain(ctx, c)
+	if err != nil || ch.Config.ChainID.Uint64() != 1337 {
+		t.Fatalf("DetectChain = %v, %v, want chain 1337", ch, err)
+	}
+	if calls := rpcCalls(telemetry); len(calls) != 0 {
+		t.Errorf("warm cache calls = %v, want none", calls)
+	}
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// ReplayURL is the placeholder endpoint used when replaying a cassette.
+const ReplayURL = "http://replay.getho.invalid"
+
+// Interaction is a single recorded JSON-RPC call.
+type Interaction struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// key identifies an interaction by method and canonicalized params, so that
+// replay does not depend on request IDs or JSON formatting.
+func (i *Interaction) key() string {
+	var params interface{}
+	if len(i.Params) > 0 {
+		if err := json.Unmarshal(i.Params, &params); err != nil {
+			return i.Method + string(i.Params)
+		}
+	}
+	canonical, _ := json.Marshal(params)
+	return i.Method + string(canonical)
+}
+
+// jsonrpcMessage is the subset of a JSON-RPC 2.0 message we need to record
+// and replay calls.
+type jsonrpcMessage struct {
+	Version string          `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// parseMessages parses a single JSON-RPC message or a batch, reporting
+// whether it was a batch.
+func parseMessages(body []byte) ([]jsonrpcMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var msgs []jsonrpcMessage
+		err := json.Unmarshal(body, &msgs)
+		return msgs, true, err
+	}
+	var msg jsonrpcMessage
+	err := json.Unmarshal(body, &msg)
+	return []jsonrpcMessage{msg}, false, err
+}
+
+// Recorder is an http.RoundTripper that captures every JSON-RPC call passing
+// through it into a cassette file, one JSON-encoded Interaction per line.
+//
+// Interactions are written as they happen, so a session that ends in an
+// error is still fully recorded.
+type Recorder struct {
+	base http.RoundTripper
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecorder creates a cassette file at path and returns a Recorder that
+// sends requests through base (nil uses http.DefaultTransport).
+func NewRecorder(path string, base http.RoundTripper) (*Recorder, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{base: base, file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.record(reqBody, respBody)
+	return resp, nil
+}
+
+// record pairs request and response messages by ID and appends them to the
+// cassette. Messages that cannot be parsed are skipped.
+func (r *Recorder) record(reqBody, respBody []byte) {
+	reqs, _, err := parseMessages(reqBody)
+	if err != nil {
+		return
+	}
+	resps, _, err := parseMessages(respBody)
+	if err != nil {
+		return
+	}
+	byID := make(map[string]jsonrpcMessage, len(resps))
+	for _, resp := range resps {
+		byID[string(resp.ID)] = resp
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, req := range reqs {
+		resp, ok := byID[string(req.ID)]
+		if !ok || req.Method == "" {
+			continue
+		}
+		_ = r.enc.Encode(&Interaction{
+			Method: req.Method,
+			Params: req.Params,
+			Result: resp.Result,
+			Error:  resp.Error,
+		})
+	}
+}
+
+// Close closes the cassette file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Replayer is an http.RoundTripper that answers JSON-RPC calls from a
+// cassette file without any network access.
+//
+// Calls are matched by method and params. When the same call was recorded
+// several times the answers are replayed in order, repeating the last one.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]*Interaction
+}
+
+// NewReplayer loads a cassette file written by a Recorder.
+func NewReplayer(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := &Replayer{interactions: make(map[string][]*Interaction)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 256<<20) // traces can be very large
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		interaction := new(Interaction)
+		if err := json.Unmarshal(scanner.Bytes(), interaction); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s line %d: %w", path, line, err)
+		}
+		key := interaction.key()
+		r.interactions[key] = append(r.interactions[key], interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	reqs, batch, err := parseMessages(body)
+	if err != nil {
+		return nil, fmt.Errorf("replay: invalid request: %w", err)
+	}
+
+	resps := make([]jsonrpcMessage, 0, len(reqs))
+	for _, msg := range reqs {
+		resps = append(resps, r.answer(msg))
+	}
+
+	var out []byte
+	if batch {
+		out, err = json.Marshal(resps)
+	} else {
+		out, err = json.Marshal(resps[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(out)),
+		ContentLength: int64(len(out)),
+		Request:       req,
+	}, nil
+}
+
+// answer builds the replayed response to a single request.
+func (r *Replayer) answer(req jsonrpcMessage) jsonrpcMessage {
+	resp := jsonrpcMessage{Version: "2.0", ID: req.ID}
+	key := (&Interaction{Method: req.Method, Params: req.Params}).key()
+
+	r.mu.Lock()
+	recorded := r.interactions[key]
+	var interaction *Interaction
+	if len(recorded) > 0 {
+		interaction = recorded[0]
+		if len(recorded) > 1 {
+			r.interactions[key] = recorded[1:]
+		}
+	}
+	r.mu.Unlock()
+
+	if interaction == nil {
+		msg, _ := json.Marshal(fmt.Sprintf("replay: no recorded response for %s %s", req.Method, req.Params))
+		resp.Error = json.RawMessage(`{"code":-32000,"message":` + string(msg) + `}`)
+		return resp
+	}
+	resp.Result, resp.Error = interaction.Result, interaction.Error
+	if resp.Result == nil && resp.Error == nil {
+		resp.Result = json.RawMessage("null")
+	}
+	return resp
+}
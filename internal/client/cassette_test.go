@@ -0,0 +1,144 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// transferCassette is a `getho tx` session against a simulated node, holding
+// a mined ether transfer.
+const transferCassette = "testdata/transfer.jsonl"
+
+var (
+	transferTx    = common.HexToHash("0xc03720e78ae74df636863b7831a40e57053de0de12d15b6f6e4b057f8d45adca")
+	transferBlock = common.HexToHash("0xdb9119b40d38186b0739efd0f88f96f8d7a82d4c4c30f472bd4f14c76f49f9d2")
+)
+
+// newReplayClient returns a client answering from the cassette at path.
+func newReplayClient(t *testing.T, path string) *RPCClient {
+	t.Helper()
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultRPCOptions()
+	opts.Transport = replayer
+	c, err := NewRPCClientWithOptions(context.Background(), ReplayURL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func checkTransferBundle(t *testing.T, bundle *TransactionBundle) {
+	t.Helper()
+	if bundle == nil {
+		t.Fatal("transaction not found")
+	}
+	if bundle.Transaction.Hash() != transferTx {
+		t.Errorf("transaction hash = %s, want %s", bundle.Transaction.Hash().Hex(), transferTx.Hex())
+	}
+	if bundle.IsPending || bundle.ReceiptErr != nil {
+		t.Fatalf("pending = %v, receipt error = %v", bundle.IsPending, bundle.ReceiptErr)
+	}
+	if bundle.Receipt == nil || bundle.Receipt.Status != types.ReceiptStatusSuccessful || bundle.Receipt.GasUsed != 21000 {
+		t.Errorf("unexpected receipt %+v", bundle.Receipt)
+	}
+	if bundle.Header == nil || bundle.Header.Hash() != transferBlock || bundle.Header.Number.Uint64() != 1 {
+		t.Errorf("unexpected header %+v", bundle.Header)
+	}
+}
+
+func TestReplayTransactionBundle(t *testing.T) {
+	c := newReplayClient(t, transferCassette)
+	ctx := context.Background()
+
+	bundle, err := GetTransactionBundle(ctx, c, transferTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTransferBundle(t, bundle)
+
+	chainID, err := c.ChainID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainID.Uint64() != 1337 {
+		t.Errorf("chain ID = %v, want 1337", chainID)
+	}
+}
+
+func TestReplayUnrecordedCall(t *testing.T) {
+	c := newReplayClient(t, transferCassette)
+
+	_, err := c.GetTransactionReceipt(context.Background(), common.Hash{1})
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Fatalf("err = %v, want a missing recording", err)
+	}
+}
+
+func TestRecordReplayedSession(t *testing.T) {
+	// Serve the cassette over HTTP and record a session against it: the
+	// new cassette must hold the same calls.
+	replayer, err := NewReplayer(transferCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := replayer.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer resp.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := NewRecorder(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultRPCOptions()
+	opts.Transport = recorder
+	c, err := NewRPCClientWithOptions(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := c.GetTransactionBundle(context.Background(), transferTx)
+	c.Close()
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTransferBundle(t, bundle)
+
+	recorded, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(transferCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The recorded session stops short of the chain ID lookup of `getho tx`.
+	want = want[:bytes.LastIndex(bytes.TrimSpace(want), []byte("\n"))+1]
+	if !bytes.Equal(recorded, want) {
+		t.Errorf("recorded cassette differs from the replayed one:\n%s\nwant:\n%s", recorded, want)
+	}
+}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// ChaindataClient is a Client that reads a stopped geth node's database
+// directly, without any RPC.
+//
+// The database (pebble or leveldb, plus the ancients freezer) is opened
+// read-only through go-ethereum's rawdb accessors, so a snapshot datadir
+// copied off a production node can be inspected offline. Transaction lookups
+// depend on geth's transaction index, which geth prunes for old blocks
+// unless run with --history.transactions=0. Likewise, account state is only
+// available for the blocks whose state the node kept: all of them on an
+// archive node, the most recent 128 or so otherwise.
+type ChaindataClient struct {
+	db     ethdb.Database
+	config *params.ChainConfig
+
+	stateOnce sync.Once
+	state     state.Database // opened on first use, see stateAt
+	triedb    *triedb.Database
+}
+
+// NewChaindataClient opens the geth database under datadir read-only.
+//
+// datadir may be the geth datadir itself (containing geth/chaindata) or the
+// chaindata directory. The node must not be running.
+func NewChaindataClient(datadir string) (*ChaindataClient, error) {
+	datadir, err := expandHome(datadir)
+	if err != nil {
+		return nil, err
+	}
+	chaindata := datadir
+	if _, err := os.Stat(filepath.Join(datadir, "geth", "chaindata")); err == nil {
+		chaindata = filepath.Join(datadir, "geth", "chaindata")
+	}
+	if rawdb.PreexistingDatabase(chaindata) == "" {
+		return nil, fmt.Errorf("no geth database found in %s", datadir)
+	}
+
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         chaindata,
+		AncientsDirectory: filepath.Join(chaindata, "ancient"),
+		Namespace:         "getho/",
+		Cache:             16,
+		Handles:           16,
+		ReadOnly:          true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geth database (is the node still running?): %w", err)
+	}
+
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	config := rawdb.ReadChainConfig(db, genesis)
+	if config == nil {
+		db.Close()
+		return nil, errors.New("geth database has no chain config")
+	}
+	return &ChaindataClient{db: db, config: config}, nil
+}
+
+// ChainConfig returns the chain configuration stored in the database.
+func (c *ChaindataClient) ChainConfig() *params.ChainConfig {
+	return c.config
+}
+
+// GetTransaction retrieves a transaction by hash. Transactions read from the
+// database are never pending.
+func (c *ChaindataClient) GetTransaction(_ context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	tx, _, _, _ := rawdb.ReadTransaction(c.db, txHash)
+	return tx, false, nil
+}
+
+// GetTransactionReceipt retrieves a transaction receipt by hash.
+func (c *ChaindataClient) GetTransactionReceipt(_ context.Context, txHash common.Hash) (*types.Receipt, error) {
+	receipt, _, _, _ := rawdb.ReadReceipt(c.db, txHash, c.config)
+	return receipt, nil
+}
+
+// ChainID retrieves the chain ID from the stored chain config.
+func (c *ChaindataClient) ChainID(_ context.Context) (*big.Int, error) {
+	return c.config.ChainID, nil
+}
+
+// GetBlockHeader retrieves a canonical block header by number. nil and the
+// latest/pending tags select the head header, the finalized tag selects the
+// last finalized block recorded by the node.
+func (c *ChaindataClient) GetBlockHeader(_ context.Context, blockNumber *big.Int) (*types.Header, error) {
+	hash, number, err := c.resolve("GetBlockHeader", BlockRef{Number: blockNumber})
+	if err != nil {
+		return nil, err
+	}
+	header := rawdb.ReadHeader(c.db, hash, number)
+	if header == nil {
+		return nil, &RPCError{Method: "GetBlockHeader", Kind: ErrNotFound, Err: errors.New("header not found")}
+	}
+	return header, nil
+}
+
+// GetBlock retrieves a block with its full transactions by number, hash or
+// tag. Tags resolve as in GetBlockHeader.
+func (c *ChaindataClient) GetBlock(_ context.Context, ref BlockRef) (*types.Block, error) {
+	hash, number, err := c.resolve("GetBlock", ref)
+	if err != nil {
+		return nil, err
+	}
+	block := rawdb.ReadBlock(c.db, hash, number)
+	if block == nil {
+		return nil, errBlockNotFound("GetBlock", ref)
+	}
+	return block, nil
+}
+
+// GetBlockReceipts retrieves the receipts of all transactions in a block.
+func (c *ChaindataClient) GetBlockReceipts(_ context.Context, ref BlockRef) (types.Receipts, error) {
+	hash, number, err := c.resolve("GetBlockReceipts", ref)
+	if err != nil {
+		return nil, err
+	}
+	header := rawdb.ReadHeader(c.db, hash, number)
+	if header == nil {
+		return nil, errBlockNotFound("GetBlockReceipts", ref)
+	}
+	receipts := rawdb.ReadReceipts(c.db, hash, number, header.Time, c.config)
+	if receipts == nil {
+		return nil, errBlockNotFound("GetBlockReceipts", ref)
+	}
+	return receipts, nil
+}
+
+// resolve returns the hash and number of the block ref refers to, on behalf
+// of method.
+func (c *ChaindataClient) resolve(method string, ref BlockRef) (common.Hash, uint64, error) {
+	var hash common.Hash
+	tag, isTag := ref.tag()
+	switch {
+	case ref.Hash != nil:
+		hash = *ref.Hash
+	case isTag && (tag == rpc.LatestBlockNumber || tag == rpc.PendingBlockNumber):
+		hash = rawdb.ReadHeadHeaderHash(c.db)
+	case isTag && tag == rpc.FinalizedBlockNumber:
+		hash = rawdb.ReadFinalizedBlockHash(c.db)
+	case isTag:
+		return common.Hash{}, 0, fmt.Errorf("block tag %s is not available from a database", tag)
+	default:
+		hash = rawdb.ReadCanonicalHash(c.db, ref.Number.Uint64())
+	}
+	if hash == (common.Hash{}) {
+		return common.Hash{}, 0, &RPCError{Method: method, Kind: ErrNotFound, Err: errors.New("header not found")}
+	}
+	number := rawdb.ReadHeaderNumber(c.db, hash)
+	if number == nil {
+		return common.Hash{}, 0, &RPCError{Method: method, Kind: ErrNotFound, Err: errors.New("header not found")}
+	}
+	return hash, *number, nil
+}
+
+// GetBalance retrieves the balance of an account at a block.
+func (c *ChaindataClient) GetBalance(_ context.Context, account common.Address, ref BlockRef) (*big.Int, error) {
+	statedb, _, err := c.stateAt("GetBalance", ref)
+	if err != nil {
+		return nil, err
+	}
+	balance := statedb.GetBalance(account).ToBig()
+	return balance, statedb.Error()
+}
+
+// GetNonce retrieves the nonce of an account at a block.
+func (c *ChaindataClient) GetNonce(_ context.Context, account common.Address, ref BlockRef) (uint64, error) {
+	statedb, _, err := c.stateAt("GetNonce", ref)
+	if err != nil {
+		return 0, err
+	}
+	nonce := statedb.GetNonce(account)
+	return nonce, statedb.Error()
+}
+
+// GetCode retrieves the code of an account at a block.
+func (c *ChaindataClient) GetCode(_ context.Context, account common.Address, ref BlockRef) ([]byte, error) {
+	statedb, _, err := c.stateAt("GetCode", ref)
+	if err != nil {
+		return nil, err
+	}
+	code := statedb.GetCode(account)
+	return code, statedb.Error()
+}
+
+// GetStorageAt retrieves the value of a storage slot of an account at a
+// block.
+func (c *ChaindataClient) GetStorageAt(_ context.Context, account common.Address, slot common.Hash, ref BlockRef) (common.Hash, error) {
+	statedb, _, err := c.stateAt("GetStorageAt", ref)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	value := statedb.GetState(account, slot)
+	return value, statedb.Error()
+}
+
+// GetProof retrieves the state of an account and of the given storage
+// slots at a block, with their Merkle proofs.
+func (c *ChaindataClient) GetProof(_ context.Context, account common.Address, slots []common.Hash, ref BlockRef) (*AccountProof, error) {
+	statedb, root, err := c.stateAt("GetProof", ref)
+	if err != nil {
+		return nil, err
+	}
+	return proveAccount(statedb, root, account, slots)
+}
+
+// stateAt opens the state of the block ref refers to, on behalf of method.
+// The trie database is opened read-only on first use, in whichever scheme
+// (hash or path) the node stored its state.
+func (c *ChaindataClient) stateAt(method string, ref BlockRef) (*state.StateDB, common.Hash, error) {
+	hash, number, err := c.resolve(method, ref)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	header := rawdb.ReadHeader(c.db, hash, number)
+	if header == nil {
+		return nil, common.Hash{}, errBlockNotFound(method, ref)
+	}
+
+	c.stateOnce.Do(func() {
+		config := &triedb.Config{}
+		if rawdb.ReadStateScheme(c.db) == rawdb.PathScheme {
+			config.PathDB = pathdb.ReadOnly
+		}
+		c.triedb = triedb.NewDatabase(c.db, config)
+		c.state = state.NewDatabaseWithNodeDB(c.db, c.triedb)
+	})
+	statedb, err := state.New(header.Root, c.state, nil)
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("state of block %d is not available: %w", number, err)
+	}
+	return statedb, header.Root, nil
+}
+
+// TraceTransaction is not supported: tracing re-executes transactions, which
+// requires state and an EVM rather than stored chain data.
+func (c *ChaindataClient) TraceTransaction(_ context.Context, _ common.Hash, _ *TraceOptions) (*TraceResult, error) {
+	return nil, &RPCError{
+		Method: "debug_traceTransaction",
+		Kind:   ErrMethodUnsupported,
+		Err:    errors.New("tracing requires a live node"),
+	}
+}
+
+// Close closes the database.
+func (c *ChaindataClient) Close() {
+	if c.triedb != nil {
+		c.triedb.Close()
+	}
+	c.db.Close()
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// chaindataInitCode stores 42 in slot 0, logs a byte and deploys the
+// one-byte runtime code 0xfe.
+var chaindataInitCode = common.FromHex("602a600055" + "60fe600053" + "60016000a0" + "60016000f3")
+
+// chaindataChain is a chain written to a geth datadir: a transfer in block
+// 1, a contract creation in block 2 and an empty block 3, with block 2
+// finalized.
+type chaindataChain struct {
+	datadir  string
+	blocks   []*types.Block
+	receipts []types.Receipts
+
+	sender, recipient, contract common.Address
+	transfer, create            *types.Transaction
+}
+
+// newChaindataChain writes the chain to a new datadir the way geth would,
+// keeping the state of every block in the given state scheme.
+func newChaindataChain(t *testing.T, scheme string) *chaindataChain {
+	t.Helper()
+	key := simKey(0)
+	c := &chaindataChain{
+		datadir:   t.TempDir(),
+		sender:    crypto.PubkeyToAddress(key.PublicKey),
+		recipient: common.HexToAddress("0x00000000000000000000000000000000000000b2"),
+	}
+	c.contract = crypto.CreateAddress(c.sender, 1)
+	genesis := &core.Genesis{
+		Config:  params.AllEthashProtocolChanges,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+		Alloc:   types.GenesisAlloc{c.sender: {Balance: big.NewInt(params.Ether)}},
+	}
+	signer := types.LatestSigner(genesis.Config)
+	_, c.blocks, c.receipts = core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 3, func(i int, b *core.BlockGen) {
+		var inner types.TxData
+		switch i {
+		case 0:
+			inner = &types.DynamicFeeTx{ChainID: genesis.Config.ChainID, Nonce: 0, GasTipCap: simTip,
+				GasFeeCap: big.NewInt(params.GWei * 10), Gas: 21_000, To: &c.recipient, Value: big.NewInt(1000)}
+		case 1:
+			inner = &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(params.GWei * 10), Gas: 100_000, Data: chaindataInitCode}
+		default:
+			return
+		}
+		tx := types.MustSignNewTx(key, signer, inner)
+		b.AddTx(tx)
+		if i == 0 {
+			c.transfer = tx
+		} else {
+			c.create = tx
+		}
+	})
+
+	chaindata := filepath.Join(c.datadir, "geth", "chaindata")
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         chaindata,
+		AncientsDirectory: filepath.Join(chaindata, "ancient"),
+		Cache:             16,
+		Handles:           16,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheConfig := core.DefaultCacheConfigWithScheme(scheme)
+	cacheConfig.TrieDirtyDisabled = true // commit the state of every block, as an archive node does
+	bc, err := core.NewBlockChain(db, cacheConfig, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.InsertChain(c.blocks); err != nil {
+		t.Fatal(err)
+	}
+	// geth indexes transactions in the background; write the index here so
+	// that it is complete when the node stops.
+	for _, block := range c.blocks {
+		rawdb.WriteTxLookupEntriesByBlock(db, block)
+	}
+	rawdb.WriteFinalizedBlockHash(db, c.blocks[1].Hash())
+	bc.Stop()
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// newChaindataClient opens the chain's datadir.
+func newChaindataClient(t *testing.T, chain *chaindataChain) *ChaindataClient {
+	t.Helper()
+	c, err := NewChaindataClient(chain.datadir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestChaindataClientTransaction(t *testing.T) {
+	chain := newChaindataChain(t, rawdb.HashScheme)
+	c := newChaindataClient(t, chain)
+	ctx := context.Background()
+
+	if id, err := c.ChainID(ctx); err != nil || id.Cmp(params.AllEthashProtocolChanges.ChainID) != 0 {
+		t.Errorf("ChainID = %v, %v, want %v", id, err, params.AllEthashProtocolChanges.ChainID)
+	}
+
+	tx, isPending, err := c.GetTransaction(ctx, chain.create.Hash())
+	if err != nil || tx == nil || isPending || tx.Hash() != chain.create.Hash() {
+		t.Fatalf("GetTransaction = %v, %v, %v", tx, isPending, err)
+	}
+	receipt, err := c.GetTransactionReceipt(ctx, chain.create.Hash())
+	if err != nil || receipt == nil {
+		t.Fatalf("GetTransactionReceipt = %v, %v", receipt, err)
+	}
+	want := chain.receipts[1][0]
+	if receipt.BlockHash != chain.blocks[1].Hash() || receipt.BlockNumber.Uint64() != 2 || receipt.TxHash != chain.create.Hash() {
+		t.Errorf("receipt of block %v %s, tx %s", receipt.BlockNumber, receipt.BlockHash.Hex(), receipt.TxHash.Hex())
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful || receipt.GasUsed != want.GasUsed || receipt.ContractAddress != chain.contract {
+		t.Errorf("receipt status %d, gas used %d, contract %s, want 1, %d, %s", receipt.Status, receipt.GasUsed, receipt.ContractAddress.Hex(), want.GasUsed, chain.contract.Hex())
+	}
+	if len(receipt.Logs) != 1 || receipt.Logs[0].Address != chain.contract || receipt.Logs[0].TxHash != chain.create.Hash() {
+		t.Errorf("receipt logs = %+v, want one from the contract", receipt.Logs)
+	}
+
+	unknown := common.Hash{0xba, 0xd}
+	if tx, _, err := c.GetTransaction(ctx, unknown); tx != nil || err != nil {
+		t.Errorf("GetTransaction of an unknown transaction = %v, %v, want nil, nil", tx, err)
+	}
+	if receipt, err := c.GetTransactionReceipt(ctx, unknown); receipt != nil || err != nil {
+		t.Errorf("GetTransactionReceipt of an unknown transaction = %v, %v, want nil, nil", receipt, err)
+	}
+}
+
+func TestChaindataClientHeaders(t *testing.T) {
+	chain := newChaindataChain(t, rawdb.HashScheme)
+	c := newChaindataClient(t, chain)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		number *big.Int
+		want   *types.Block
+	}{
+		{"by number", big.NewInt(1), chain.blocks[0]},
+		{"latest", nil, chain.blocks[2]},
+		{"pending", big.NewInt(-1), chain.blocks[2]},
+		{"finalized", big.NewInt(-3), chain.blocks[1]},
+	}
+	for _, tt := range tests {
+		header, err := c.GetBlockHeader(ctx, tt.number)
+		if err != nil || header.Hash() != tt.want.Hash() {
+			t.Errorf("%s: GetBlockHeader = %v, %v, want block %d", tt.name, header, err, tt.want.NumberU64())
+		}
+	}
+	if _, err := c.GetBlockHeader(ctx, big.NewInt(4)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("future block: error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.GetBlockHeader(ctx, big.NewInt(-4)); err == nil { // safe
+		t.Error("safe block: no error")
+	}
+
+	hash := chain.blocks[0].Hash()
+	block, err := c.GetBlock(ctx, BlockRef{Hash: &hash})
+	if err != nil || block.Hash() != hash || len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != chain.transfer.Hash() {
+		t.Errorf("GetBlock by hash = %v, %v, want block 1 with the transfer", block, err)
+	}
+	receipts, err := c.GetBlockReceipts(ctx, BlockRef{Hash: &hash})
+	if err != nil || len(receipts) != 1 || receipts[0].TxHash != chain.transfer.Hash() || receipts[0].GasUsed != 21_000 {
+		t.Errorf("GetBlockReceipts = %v, %v, want the transfer's", receipts, err)
+	}
+}
+
+func TestChaindataClientState(t *testing.T) {
+	for _, scheme := range []string{rawdb.HashScheme, rawdb.PathScheme} {
+		chain := newChaindataChain(t, scheme)
+		c := newChaindataClient(t, chain)
+		ctx := context.Background()
+
+		latest := BlockRef{}
+		if balance, err := c.GetBalance(ctx, chain.recipient, latest); err != nil || balance.Int64() != 1000 {
+			t.Errorf("%s: recipient balance = %v, %v, want 1000", scheme, balance, err)
+		}
+		if nonce, err := c.GetNonce(ctx, chain.sender, latest); err != nil || nonce != 2 {
+			t.Errorf("%s: sender nonce = %d, %v, want 2", scheme, nonce, err)
+		}
+		if code, err := c.GetCode(ctx, chain.contract, latest); err != nil || len(code) != 1 || code[0] != 0xfe {
+			t.Errorf("%s: contract code = %x, %v, want fe", scheme, code, err)
+		}
+		if value, err := c.GetStorageAt(ctx, chain.contract, common.Hash{}, latest); err != nil || value != common.BigToHash(big.NewInt(42)) {
+			t.Errorf("%s: contract slot 0 = %s, %v, want 42", scheme, value.Hex(), err)
+		}
+		proof, err := c.GetProof(ctx, chain.contract, []common.Hash{{}}, latest)
+		if err != nil || proof.Nonce != 1 || len(proof.AccountProof) == 0 || len(proof.StorageProof) != 1 {
+			t.Errorf("%s: GetProof = %+v, %v", scheme, proof, err)
+		}
+
+		// Before the transfer.
+		if balance, err := c.GetBalance(ctx, chain.recipient, BlockRef{Number: big.NewInt(0)}); err != nil || balance.Sign() != 0 {
+			t.Errorf("%s: recipient balance at genesis = %v, %v, want 0", scheme, balance, err)
+		}
+	}
+}
+
+func TestNewChaindataClientMissingDatabase(t *testing.T) {
+	if _, err := NewChaindataClient(t.TempDir()); err == nil {
+		t.Error("empty datadir: no error")
+	}
+}
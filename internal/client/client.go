@@ -21,14 +21,43 @@ type Client interface {
 	// Returns nil, nil if the receipt is not found.
 	GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
 
+	// ChainID retrieves the chain ID of the connected network.
+	ChainID(ctx context.Context) (*big.Int, error)
+
 	// GetBlockHeader retrieves a block header by number.
 	// This is needed for base fee and other block-level context.
 	GetBlockHeader(ctx context.Context, blockNumber *big.Int) (*types.Header, error)
 
-	// TraceTransaction generates an execution trace for a transaction.
-	// The format of the returned data is implementation-specific (e.g., Geth's
-	// trace format), but should be parseable into the tracer.Trace model.
-	TraceTransaction(ctx context.Context, txHash common.Hash) (interface{}, error)
+	// GetBlock retrieves a block, including its full transactions, by
+	// number, hash or tag.
+	GetBlock(ctx context.Context, ref BlockRef) (*types.Block, error)
+
+	// GetBlockReceipts retrieves the receipts of all transactions in a
+	// block, in transaction order.
+	GetBlockReceipts(ctx context.Context, ref BlockRef) (types.Receipts, error)
+
+	// GetBalance retrieves the balance of an account in wei at a block.
+	GetBalance(ctx context.Context, account common.Address, ref BlockRef) (*big.Int, error)
+
+	// GetNonce retrieves the nonce of an account at a block.
+	GetNonce(ctx context.Context, account common.Address, ref BlockRef) (uint64, error)
+
+	// GetCode retrieves the code of an account at a block. Accounts without
+	// code return an empty slice.
+	GetCode(ctx context.Context, account common.Address, ref BlockRef) ([]byte, error)
+
+	// GetStorageAt retrieves the value of a storage slot of an account at a
+	// block.
+	GetStorageAt(ctx context.Context, account common.Address, slot common.Hash, ref BlockRef) (common.Hash, error)
+
+	// GetProof retrieves the state of an account and of the given storage
+	// slots at a block, with their Merkle proofs (eth_getProof).
+	GetProof(ctx context.Context, account common.Address, slots []common.Hash, ref BlockRef) (*AccountProof, error)
+
+	// TraceTransaction generates an execution trace for a transaction using
+	// the tracer selected in opts (nil selects the default struct logger).
+	// Returns nil, nil if the transaction is not found.
+	TraceTransaction(ctx context.Context, txHash common.Hash, opts *TraceOptions) (*TraceResult, error)
 
 	// Close closes the client connection and releases resources.
 	Close()
@@ -2,7 +2,14 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luckify/getho/internal/chain"
 )
 
 const (
@@ -12,18 +19,286 @@ const (
 )
 
 // GetRPCURL returns the RPC URL from environment variable or default.
+//
+// If GETHO_RPC_URL lists several endpoints, the first one is returned.
 func GetRPCURL() string {
-	if url := os.Getenv("GETHO_RPC_URL"); url != "" {
-		return url
+	return GetRPCURLs()[0]
+}
+
+// GetRPCURLs returns the RPC URLs from environment variable or default.
+//
+// GETHO_RPC_URL may hold a comma-separated list of endpoints.
+func GetRPCURLs() []string {
+	if urls := splitURLs(os.Getenv("GETHO_RPC_URL")); len(urls) > 0 {
+		return urls
 	}
-	return DefaultRPCURL
+	return []string{DefaultRPCURL}
 }
 
-// NewClient creates a new client instance using the configured RPC URL.
-func NewClient(ctx context.Context, rpcURL string) (Client, error) {
-	if rpcURL == "" {
-		rpcURL = GetRPCURL()
+// splitURLs splits a comma-separated endpoint list, dropping empty entries.
+func splitURLs(s string) []string {
+	var urls []string
+	for _, url := range strings.Split(s, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
 	}
-	return NewRPCClient(ctx, rpcURL)
+	return urls
+}
+
+// Config holds connection settings read from the getho config file.
+//
+// The file is JSON, for example:
+//
+//	{
+//	  "rpc": ["http://geth:8545", "http://nethermind:8545"], "mode": "quorum", "quorum": 2,
+//	  "profiles": {
+//	    "internal": {"rpc": ["https://node.internal"], "bearer": "..."},
+//	    "engine": {"rpc": ["http://localhost:8551"], "jwtSecret": "~/.ethereum/jwtsecret"}
+//	  }
+//	}
+//
+// Settings of a selected profile override the top-level ones.
+type Config struct {
+	RPC    []string `json:"rpc,omitempty"`    // endpoint URLs
+	Mode   Mode     `json:"mode,omitempty"`   // multi-endpoint mode
+	Quorum int      `json:"quorum,omitempty"` // endpoints asked in quorum mode
+
+	Retries   *int    `json:"retries,omitempty"`   // retries of temporary failures
+	RateLimit float64 `json:"rateLimit,omitempty"` // requests per second per endpoint
+
+	Headers   map[string]string `json:"headers,omitempty"`   // extra HTTP headers
+	BasicAuth string            `json:"basicAuth,omitempty"` // "user:password"
+	Bearer    string            `json:"bearer,omitempty"`    // bearer token
+	JWTSecret string            `json:"jwtSecret,omitempty"` // path of a geth JWT secret file
+
+	Profiles map[string]*Config `json:"profiles,omitempty"` // named overrides
 }
 
+// Profile returns the configuration with the named profile applied. An
+// empty name returns c itself.
+func (c *Config) Profile(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok || p == nil {
+		return nil, fmt.Errorf("unknown config profile %q", name)
+	}
+
+	merged := *c
+	merged.Profiles = nil
+	if len(p.RPC) > 0 {
+		merged.RPC = p.RPC
+	}
+	if p.Mode != "" {
+		merged.Mode = p.Mode
+	}
+	if p.Quorum != 0 {
+		merged.Quorum = p.Quorum
+	}
+	if p.Retries != nil {
+		merged.Retries = p.Retries
+	}
+	if p.RateLimit != 0 {
+		merged.RateLimit = p.RateLimit
+	}
+	if len(p.Headers) > 0 {
+		merged.Headers = make(map[string]string, len(c.Headers)+len(p.Headers))
+		for name, value := range c.Headers {
+			merged.Headers[name] = value
+		}
+		for name, value := range p.Headers {
+			merged.Headers[name] = value
+		}
+	}
+	// A profile's credentials replace the top-level ones entirely, since the
+	// authentication schemes are mutually exclusive.
+	if p.BasicAuth != "" || p.Bearer != "" || p.JWTSecret != "" {
+		merged.BasicAuth, merged.Bearer, merged.JWTSecret = p.BasicAuth, p.Bearer, p.JWTSecret
+	}
+	return &merged, nil
+}
+
+// ConfigPath returns the location of the getho config file: $GETHO_CONFIG if
+// set, otherwise getho/config.json in the user configuration directory.
+func ConfigPath() (string, error) {
+	if path := os.Getenv("GETHO_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "getho", "config.json"), nil
+}
+
+// LoadConfig reads the getho config file. A missing file yields an empty
+// Config rather than an error.
+func LoadConfig() (*Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return &Config{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Options controls how NewClient connects to execution clients.
+type Options struct {
+	// RPCURLs lists the endpoints to use. When empty, GetRPCURLs is used.
+	RPCURLs []string
+
+	// Mode selects how several endpoints are combined. Ignored when only
+	// one endpoint is configured.
+	Mode Mode
+
+	// Quorum is the number of endpoints asked in ModeQuorum. Zero asks all.
+	Quorum int
+
+	// OnDisagreement, if set, is called whenever endpoints disagree in
+	// ModeQuorum.
+	OnDisagreement func(Disagreement)
+
+	// RPC configures each endpoint's RPCClient. Nil uses DefaultRPCOptions.
+	RPC *RPCOptions
+
+	// Cache, if set, wraps the client in a CachingClient.
+	Cache *CacheOptions
+
+	// Datadir, if set, reads a stopped geth node's database instead of
+	// connecting to RPC endpoints; see ChaindataClient.
+	Datadir string
+
+	// Era1Dir, if set, reads a directory of era1 archives instead of
+	// connecting to RPC endpoints; see Era1Client.
+	Era1Dir string
+
+	// Telemetry, if set, records the JSON-RPC requests sent to each
+	// endpoint unless RPC sets a Telemetry of its own; see
+	// RPCOptions.Telemetry. Datadirs and era1 directories send none.
+	Telemetry *Telemetry
+}
+
+// NewClient creates a new client instance using the configured RPC URLs.
+//
+// A single endpoint yields an RPCClient; several endpoints are wrapped in a
+// MultiClient. Either is wrapped in a CachingClient if opts.Cache is set.
+// If opts.Datadir or opts.Era1Dir is set, a ChaindataClient or Era1Client
+// is returned instead.
+//
+// The chain of an RPC endpoint is detected here, once, and reported by the
+// client's Chain method; see ChainProvider. With opts.Cache set, the
+// CachingClient identifies the chain instead, only when the cache cannot
+// answer, so that cached data costs no calls.
+func NewClient(ctx context.Context, opts Options) (Client, error) {
+	c, err := newEndpointClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cache == nil {
+		if err := identifyChain(ctx, c); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+	cached, err := NewCachingClient(c, *opts.Cache)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return cached, nil
+}
+
+// identifyChain detects the chain of an endpoint client once, so that
+// commands and decorators can ask for it without further calls.
+func identifyChain(ctx context.Context, c Client) error {
+	var target **chain.Chain
+	switch inner := c.(type) {
+	case *RPCClient:
+		target = &inner.chain
+	case *MultiClient:
+		target = &inner.chain
+	default:
+		return nil // local clients read their chain configuration
+	}
+	ch, err := DetectChain(ctx, c)
+	if err != nil {
+		return err
+	}
+	*target = ch
+	return nil
+}
+
+// newEndpointClient connects to the endpoints configured in opts.
+func newEndpointClient(ctx context.Context, opts Options) (Client, error) {
+	if opts.Datadir != "" {
+		c, err := NewChaindataClient(opts.Datadir)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	if opts.Era1Dir != "" {
+		c, err := NewEra1Client(opts.Era1Dir)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	urls := opts.RPCURLs
+	if len(urls) == 0 {
+		urls = GetRPCURLs()
+	}
+	rpcOpts := DefaultRPCOptions()
+	if opts.RPC != nil {
+		rpcOpts = *opts.RPC
+	}
+	if rpcOpts.Telemetry == nil {
+		rpcOpts.Telemetry = opts.Telemetry
+	}
+
+	if len(urls) == 1 {
+		c, err := NewRPCClientWithOptions(ctx, urls[0], rpcOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to Ethereum node at %s: %w", RedactURL(urls[0]), err)
+		}
+		return c, nil
+	}
+
+	// Endpoints that cannot be dialed are skipped so that failover still
+	// works when one of them is down at startup.
+	var firstErr error
+	endpoints := make([]Endpoint, 0, len(urls))
+	for _, url := range urls {
+		c, err := NewRPCClientWithOptions(ctx, url, rpcOpts)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to connect to Ethereum node at %s: %w", RedactURL(url), err)
+			}
+			continue
+		}
+		endpoints = append(endpoints, Endpoint{Name: RedactURL(url), Client: c})
+	}
+	if len(endpoints) == 0 {
+		return nil, firstErr
+	}
+	return NewMultiClient(endpoints, MultiOptions{
+		Mode:           opts.Mode,
+		Quorum:         opts.Quorum,
+		OnDisagreement: opts.OnDisagreement,
+	})
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// ChainConfigurer is implemented by clients that read the chain
+// configuration from their data source, e.g. a geth database.
+type ChainConfigurer interface {
+	ChainConfig() *params.ChainConfig
+}
+
+// ChainProvider is implemented by clients that identified the chain they
+// are connected to when they were created, as NewClient does.
+type ChainProvider interface {
+	// Chain returns the connected chain, or nil if it was not identified.
+	Chain() *chain.Chain
+}
+
+// DetectChain identifies the chain c is connected to.
+//
+// A chain identified when c was created is returned as is. Clients that
+// know their chain configuration are trusted; otherwise the chain ID
+// reported by eth_chainId is looked up in the chain registry. Unregistered
+// chains are assumed to run with every known fork active.
+func DetectChain(ctx context.Context, c Client) (*chain.Chain, error) {
+	for inner := c; inner != nil; {
+		if p, ok := inner.(ChainProvider); ok && p.Chain() != nil {
+			return p.Chain(), nil
+		}
+		if cc, ok := inner.(ChainConfigurer); ok {
+			return chain.FromConfig(cc.ChainConfig()), nil
+		}
+		u, ok := inner.(interface{ Unwrap() Client })
+		if !ok {
+			break
+		}
+		inner = u.Unwrap()
+	}
+
+	id, err := c.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect chain: %w", err)
+	}
+	if ch, ok := chain.ByID(id); ok {
+		return ch, nil
+	}
+	return chain.Unknown(id), nil
+}
+
+// knownChain returns the chain c or a client it wraps identified when it was
+// created, without any call to the endpoint, or nil.
+func knownChain(c Client) *chain.Chain {
+	for c != nil {
+		if p, ok := c.(ChainProvider); ok && p.Chain() != nil {
+			return p.Chain()
+		}
+		u, ok := c.(interface{ Unwrap() Client })
+		if !ok {
+			return nil
+		}
+		c = u.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,588 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/snappy"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// e2store entry types used by era1 files.
+const (
+	era1TypeVersion            = 0x3265
+	era1TypeCompressedHeader   = 0x03
+	era1TypeCompressedBody     = 0x04
+	era1TypeCompressedReceipts = 0x05
+
+	e2storeHeaderSize = 8
+)
+
+// era1Networks maps the network part of era1 file names to chain configs.
+var era1Networks = map[string]*params.ChainConfig{
+	"mainnet": chain.Mainnet.Config,
+	"sepolia": chain.Sepolia.Config,
+	"goerli":  params.GoerliChainConfig,
+}
+
+// era1File is an open era1 archive holding the blocks [start, start+count).
+type era1File struct {
+	name   string
+	f      *os.File
+	start  uint64
+	count  uint64
+	length int64
+}
+
+// era1Block is a block read from an archive.
+type era1Block struct {
+	header   *types.Header
+	body     *types.Body
+	receipts types.Receipts
+}
+
+// Era1Client is a Client that reads pre-merge history from a directory of
+// era1 archives, as produced by geth export-history and distributed by
+// history mirrors.
+//
+// Files are matched to block ranges by their block index when the client is
+// opened. Era1 archives carry no transaction index, so the first lookup of a
+// transaction scans block bodies across all files; pointing the client at a
+// directory holding just the relevant epochs keeps lookups fast.
+type Era1Client struct {
+	files  []*era1File // sorted by start block
+	config *params.ChainConfig
+
+	mu        sync.Mutex
+	locations map[common.Hash]uint64 // tx hash to block number, filled by lookups
+	numbers   map[common.Hash]uint64 // block hash to block number, filled by lookups
+}
+
+// NewEra1Client opens the era1 files in dir. The network is taken from the
+// file names (<network>-<epoch>-<root>.era1) and must be the same for all
+// files.
+func NewEra1Client(dir string) (*Era1Client, error) {
+	dir, err := expandHome(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Era1Client{locations: make(map[common.Hash]uint64), numbers: make(map[common.Hash]uint64)}
+	network := ""
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".era1" {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(entry.Name(), ".era1"), "-")
+		if len(parts) != 3 {
+			c.Close()
+			return nil, fmt.Errorf("malformed era1 file name %s", entry.Name())
+		}
+		if _, err := strconv.ParseUint(parts[1], 10, 64); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("malformed era1 file name %s", entry.Name())
+		}
+		if network != "" && parts[0] != network {
+			c.Close()
+			return nil, fmt.Errorf("era1 files of different networks in %s: %s and %s", dir, network, parts[0])
+		}
+		network = parts[0]
+
+		file, err := openEra1File(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.files = append(c.files, file)
+	}
+	if len(c.files) == 0 {
+		return nil, fmt.Errorf("no era1 files found in %s", dir)
+	}
+	config, ok := era1Networks[network]
+	if !ok {
+		c.Close()
+		return nil, fmt.Errorf("unknown era1 network %q", network)
+	}
+	c.config = config
+
+	sort.Slice(c.files, func(i, j int) bool { return c.files[i].start < c.files[j].start })
+	for i := 1; i < len(c.files); i++ {
+		if prev := c.files[i-1]; prev.start+prev.count > c.files[i].start {
+			c.Close()
+			return nil, fmt.Errorf("era1 files %s and %s overlap", prev.name, c.files[i].name)
+		}
+	}
+	return c, nil
+}
+
+// openEra1File opens an era1 archive and reads its block index metadata.
+func openEra1File(path string) (*era1File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &era1File{name: filepath.Base(path), f: f}
+	if err := file.readMetadata(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("invalid era1 file %s: %w", file.name, err)
+	}
+	return file, nil
+}
+
+// readMetadata checks the version entry and reads the start block and block
+// count from the trailing block index.
+func (e *era1File) readMetadata() error {
+	info, err := e.f.Stat()
+	if err != nil {
+		return err
+	}
+	e.length = info.Size()
+
+	typ, length, err := e.readEntryHeader(0)
+	if err != nil {
+		return err
+	}
+	if typ != era1TypeVersion || length != 0 {
+		return errors.New("missing version entry")
+	}
+
+	var b [8]byte
+	if _, err := e.f.ReadAt(b[:], e.length-8); err != nil {
+		return err
+	}
+	e.count = binary.LittleEndian.Uint64(b[:])
+	if e.count == 0 || int64(e.count) > (e.length-24)/8 {
+		return errors.New("corrupt block index")
+	}
+	if _, err := e.f.ReadAt(b[:], e.length-16-int64(e.count)*8); err != nil {
+		return err
+	}
+	e.start = binary.LittleEndian.Uint64(b[:])
+	return nil
+}
+
+// readEntryHeader reads the type and value length of the e2store entry at off.
+func (e *era1File) readEntryHeader(off int64) (uint16, uint32, error) {
+	var b [e2storeHeaderSize]byte
+	if _, err := e.f.ReadAt(b[:], off); err != nil {
+		return 0, 0, err
+	}
+	if b[6] != 0 || b[7] != 0 {
+		return 0, 0, fmt.Errorf("reserved bytes of entry at %d are not zero", off)
+	}
+	return binary.LittleEndian.Uint16(b[:2]), binary.LittleEndian.Uint32(b[2:6]), nil
+}
+
+// readCompressed reads and decompresses the entry of type typ at off,
+// returning the decompressed value and the offset of the next entry.
+func (e *era1File) readCompressed(typ uint16, off int64) ([]byte, int64, error) {
+	gotType, length, err := e.readEntryHeader(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if gotType != typ {
+		return nil, 0, fmt.Errorf("unexpected entry type %#x at %d, want %#x", gotType, off, typ)
+	}
+	if off+e2storeHeaderSize+int64(length) > e.length {
+		return nil, 0, fmt.Errorf("entry at %d exceeds file size", off)
+	}
+	value := make([]byte, length)
+	if _, err := e.f.ReadAt(value, off+e2storeHeaderSize); err != nil {
+		return nil, 0, err
+	}
+	data, err := io.ReadAll(snappy.NewReader(bytes.NewReader(value)))
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, off + e2storeHeaderSize + int64(length), nil
+}
+
+// blockOffset returns the offset of block n's header entry.
+func (e *era1File) blockOffset(n uint64) (int64, error) {
+	indexOffset := e.length - 24 - int64(e.count)*8 // start of the block index entry
+	var b [8]byte
+	if _, err := e.f.ReadAt(b[:], indexOffset+16+int64(n-e.start)*8); err != nil {
+		return 0, err
+	}
+	// Offsets are relative to the block index entry.
+	return indexOffset + int64(binary.LittleEndian.Uint64(b[:])), nil
+}
+
+// readHeader reads the header of block n.
+func (e *era1File) readHeader(n uint64) (*types.Header, int64, error) {
+	off, err := e.blockOffset(n)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, next, err := e.readCompressed(era1TypeCompressedHeader, off)
+	if err != nil {
+		return nil, 0, err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return nil, 0, fmt.Errorf("invalid header of block %d: %w", n, err)
+	}
+	return header, next, nil
+}
+
+// readBlock reads the header, body and receipts of block n. Receipts are
+// stored in consensus encoding; their derived fields are filled in from
+// the block.
+func (e *era1File) readBlock(n uint64, config *params.ChainConfig) (*era1Block, error) {
+	header, off, err := e.readHeader(n)
+	if err != nil {
+		return nil, err
+	}
+	data, off, err := e.readCompressed(era1TypeCompressedBody, off)
+	if err != nil {
+		return nil, err
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(data, body); err != nil {
+		return nil, fmt.Errorf("invalid body of block %d: %w", n, err)
+	}
+	if data, _, err = e.readCompressed(era1TypeCompressedReceipts, off); err != nil {
+		return nil, err
+	}
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(data, &receipts); err != nil {
+		return nil, fmt.Errorf("invalid receipts of block %d: %w", n, err)
+	}
+	if err := receipts.DeriveFields(config, header.Hash(), n, header.Time, header.BaseFee, nil, body.Transactions); err != nil {
+		return nil, fmt.Errorf("invalid receipts of block %d: %w", n, err)
+	}
+	return &era1Block{header: header, body: body, receipts: receipts}, nil
+}
+
+// containsTx reports whether block n includes the transaction txHash. Only
+// the body is read.
+func (e *era1File) containsTx(n uint64, txHash common.Hash) (bool, error) {
+	_, off, err := e.readHeader(n)
+	if err != nil {
+		return false, err
+	}
+	data, _, err := e.readCompressed(era1TypeCompressedBody, off)
+	if err != nil {
+		return false, err
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(data, body); err != nil {
+		return false, fmt.Errorf("invalid body of block %d: %w", n, err)
+	}
+	for _, tx := range body.Transactions {
+		if tx.Hash() == txHash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ChainConfig returns the chain configuration of the archives' network.
+func (c *Era1Client) ChainConfig() *params.ChainConfig {
+	return c.config
+}
+
+// file returns the archive holding block n, or nil.
+func (c *Era1Client) file(n uint64) *era1File {
+	i := sort.Search(len(c.files), func(i int) bool { return c.files[i].start+c.files[i].count > n })
+	if i == len(c.files) || c.files[i].start > n {
+		return nil
+	}
+	return c.files[i]
+}
+
+// head returns the number of the last archived block.
+func (c *Era1Client) head() uint64 {
+	last := c.files[len(c.files)-1]
+	return last.start + last.count - 1
+}
+
+// locate returns the number of the block including txHash, scanning the
+// archives on the first lookup.
+func (c *Era1Client) locate(ctx context.Context, txHash common.Hash) (uint64, bool, error) {
+	return c.lookup(ctx, c.locations, txHash, func(file *era1File, n uint64) (bool, error) {
+		return file.containsTx(n, txHash)
+	})
+}
+
+// blockNumber returns the number of the block with the given hash, scanning
+// the archived headers on the first lookup.
+func (c *Era1Client) blockNumber(ctx context.Context, hash common.Hash) (uint64, bool, error) {
+	return c.lookup(ctx, c.numbers, hash, func(file *era1File, n uint64) (bool, error) {
+		header, _, err := file.readHeader(n)
+		if err != nil {
+			return false, err
+		}
+		return header.Hash() == hash, nil
+	})
+}
+
+// lookup returns the block number cached under key in index, or else scans
+// the archives concurrently for the first block satisfying match and caches
+// it.
+func (c *Era1Client) lookup(ctx context.Context, index map[common.Hash]uint64, key common.Hash, match func(*era1File, uint64) (bool, error)) (uint64, bool, error) {
+	c.mu.Lock()
+	n, ok := index[key]
+	c.mu.Unlock()
+	if ok {
+		return n, true, nil
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		found    bool
+		firstErr error
+		work     = make(chan *era1File)
+	)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range work {
+				for b := file.start; b < file.start+file.count && ctx.Err() == nil; b++ {
+					ok, err := match(file, b)
+					if err != nil || ok {
+						once.Do(func() {
+							n, found, firstErr = b, ok, err
+							if err != nil {
+								firstErr = fmt.Errorf("%s: %w", file.name, err)
+							}
+							cancel()
+						})
+						break
+					}
+				}
+			}
+		}()
+	}
+	for _, file := range c.files {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- file
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, false, firstErr
+	}
+	if !found {
+		// Cancellation by the caller may have cut the scan short.
+		return 0, false, parent.Err()
+	}
+	c.mu.Lock()
+	index[key] = n
+	c.mu.Unlock()
+	return n, true, nil
+}
+
+// transactionBlock reads the block including txHash and the transaction's
+// index in it. Returns a nil block if the transaction is not archived.
+func (c *Era1Client) transactionBlock(ctx context.Context, txHash common.Hash) (*era1Block, int, error) {
+	n, ok, err := c.locate(ctx, txHash)
+	if err != nil || !ok {
+		return nil, 0, err
+	}
+	block, err := c.file(n).readBlock(n, c.config)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, tx := range block.body.Transactions {
+		if tx.Hash() == txHash {
+			return block, i, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("transaction %s missing from block %d", txHash.Hex(), n)
+}
+
+// GetTransaction retrieves a transaction by hash. Archived transactions are
+// never pending.
+func (c *Era1Client) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	block, i, err := c.transactionBlock(ctx, txHash)
+	if err != nil || block == nil {
+		return nil, false, err
+	}
+	return block.body.Transactions[i], false, nil
+}
+
+// GetTransactionReceipt retrieves a transaction receipt by hash.
+func (c *Era1Client) GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	block, i, err := c.transactionBlock(ctx, txHash)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return block.receipts[i], nil
+}
+
+// GetTransactionBundle retrieves a transaction, its receipt and its block
+// header with a single read of the containing block.
+func (c *Era1Client) GetTransactionBundle(ctx context.Context, txHash common.Hash) (*TransactionBundle, error) {
+	block, i, err := c.transactionBlock(ctx, txHash)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return &TransactionBundle{
+		Transaction: block.body.Transactions[i],
+		Receipt:     block.receipts[i],
+		Header:      block.header,
+	}, nil
+}
+
+// ChainID retrieves the chain ID of the archives' network.
+func (c *Era1Client) ChainID(_ context.Context) (*big.Int, error) {
+	return c.config.ChainID, nil
+}
+
+// GetBlockHeader retrieves a block header by number. nil and the latest,
+// safe and finalized tags select the last archived block, since archived
+// history is final.
+func (c *Era1Client) GetBlockHeader(ctx context.Context, blockNumber *big.Int) (*types.Header, error) {
+	file, n, err := c.resolve(ctx, "GetBlockHeader", BlockRef{Number: blockNumber})
+	if err != nil {
+		return nil, err
+	}
+	header, _, err := file.readHeader(n)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.name, err)
+	}
+	return header, nil
+}
+
+// GetBlock retrieves a block with its full transactions by number, hash or
+// tag. Tags resolve as in GetBlockHeader; the first lookup by hash scans the
+// archived headers.
+func (c *Era1Client) GetBlock(ctx context.Context, ref BlockRef) (*types.Block, error) {
+	file, n, err := c.resolve(ctx, "GetBlock", ref)
+	if err != nil {
+		return nil, err
+	}
+	block, err := file.readBlock(n, c.config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.name, err)
+	}
+	return types.NewBlockWithHeader(block.header).WithBody(types.Body{Transactions: block.body.Transactions, Uncles: block.body.Uncles}), nil
+}
+
+// GetBlockReceipts retrieves the receipts of all transactions in a block.
+func (c *Era1Client) GetBlockReceipts(ctx context.Context, ref BlockRef) (types.Receipts, error) {
+	file, n, err := c.resolve(ctx, "GetBlockReceipts", ref)
+	if err != nil {
+		return nil, err
+	}
+	block, err := file.readBlock(n, c.config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.name, err)
+	}
+	return block.receipts, nil
+}
+
+// resolve returns the archive and number of the block ref refers to, on
+// behalf of method.
+func (c *Era1Client) resolve(ctx context.Context, method string, ref BlockRef) (*era1File, uint64, error) {
+	var n uint64
+	tag, isTag := ref.tag()
+	switch {
+	case ref.Hash != nil:
+		number, ok, err := c.blockNumber(ctx, *ref.Hash)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			return nil, 0, &RPCError{Method: method, Kind: ErrNotFound, Err: fmt.Errorf("block %s is not archived", ref)}
+		}
+		n = number
+	case isTag && (tag == rpc.LatestBlockNumber || tag == rpc.SafeBlockNumber || tag == rpc.FinalizedBlockNumber):
+		n = c.head()
+	case isTag:
+		return nil, 0, fmt.Errorf("block tag %s is not available from era1 archives", tag)
+	case !ref.Number.IsUint64():
+		return nil, 0, &RPCError{Method: method, Kind: ErrNotFound, Err: errors.New("header not found")}
+	default:
+		n = ref.Number.Uint64()
+	}
+
+	file := c.file(n)
+	if file == nil {
+		return nil, 0, &RPCError{Method: method, Kind: ErrNotFound, Err: fmt.Errorf("block %d is not archived", n)}
+	}
+	return file, n, nil
+}
+
+// GetBalance is not supported: era1 archives hold no state.
+func (c *Era1Client) GetBalance(_ context.Context, _ common.Address, _ BlockRef) (*big.Int, error) {
+	return nil, errEra1NoState("eth_getBalance")
+}
+
+// GetNonce is not supported: era1 archives hold no state.
+func (c *Era1Client) GetNonce(_ context.Context, _ common.Address, _ BlockRef) (uint64, error) {
+	return 0, errEra1NoState("eth_getTransactionCount")
+}
+
+// GetCode is not supported: era1 archives hold no state.
+func (c *Era1Client) GetCode(_ context.Context, _ common.Address, _ BlockRef) ([]byte, error) {
+	return nil, errEra1NoState("eth_getCode")
+}
+
+// GetStorageAt is not supported: era1 archives hold no state.
+func (c *Era1Client) GetStorageAt(_ context.Context, _ common.Address, _ common.Hash, _ BlockRef) (common.Hash, error) {
+	return common.Hash{}, errEra1NoState("eth_getStorageAt")
+}
+
+// GetProof is not supported: era1 archives hold no state.
+func (c *Era1Client) GetProof(_ context.Context, _ common.Address, _ []common.Hash, _ BlockRef) (*AccountProof, error) {
+	return nil, errEra1NoState("eth_getProof")
+}
+
+// errEra1NoState is the error of state methods, which era1 archives cannot
+// serve.
+func errEra1NoState(method string) error {
+	return &RPCError{
+		Method: method,
+		Kind:   ErrMethodUnsupported,
+		Err:    errors.New("era1 archives hold no state; account state requires a live node or a datadir"),
+	}
+}
+
+// TraceTransaction is not supported: tracing re-executes transactions, which
+// requires state and an EVM rather than archived chain data.
+func (c *Era1Client) TraceTransaction(_ context.Context, _ common.Hash, _ *TraceOptions) (*TraceResult, error) {
+	return nil, &RPCError{
+		Method: "debug_traceTransaction",
+		Kind:   ErrMethodUnsupported,
+		Err:    errors.New("tracing requires a live node"),
+	}
+}
+
+// Close closes the archive files.
+func (c *Era1Client) Close() {
+	for _, file := range c.files {
+		file.f.Close()
+	}
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/golang/snappy"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// era1 entry types the client skips.
+const (
+	era1TypeTotalDifficulty = 0x06
+	era1TypeAccumulator     = 0x07
+	era1TypeBlockIndex      = 0x3266
+)
+
+// era1Fixture is a chain of four London blocks, starting at the London fork
+// block, with transactions in the middle two. The blocks are made up; only
+// their layout follows mainnet's.
+type era1Fixture struct {
+	blocks   []*types.Block
+	receipts []types.Receipts
+
+	transfer, create, call *types.Transaction
+	sender                 common.Address
+}
+
+const era1FixtureStart = 12_965_000
+
+var (
+	era1Recipient = common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	era1Token     = common.HexToAddress("0x00000000000000000000000000000000000000d4")
+)
+
+func newEra1Fixture(t *testing.T) *era1Fixture {
+	t.Helper()
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.NewLondonSigner(chain.Mainnet.Config.ChainID)
+	sign := func(inner types.TxData) *types.Transaction {
+		tx, err := types.SignNewTx(key, signer, inner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	baseFee := big.NewInt(1_000_000_000)
+	f := &era1Fixture{
+		sender: crypto.PubkeyToAddress(key.PublicKey),
+		transfer: sign(&types.DynamicFeeTx{ChainID: chain.Mainnet.Config.ChainID, Nonce: 0, GasTipCap: big.NewInt(2_000_000_000),
+			GasFeeCap: big.NewInt(50_000_000_000), Gas: 21_000, To: &era1Recipient, Value: big.NewInt(1e18)}),
+		create: sign(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(20_000_000_000), Gas: 100_000, Data: []byte{0x60, 0x00}}),
+		call: sign(&types.DynamicFeeTx{ChainID: chain.Mainnet.Config.ChainID, Nonce: 2, GasTipCap: big.NewInt(1_000_000_000),
+			GasFeeCap: big.NewInt(1_500_000_000), Gas: 60_000, To: &era1Token, Data: []byte{0xa9, 0x05, 0x9c, 0xbb}}),
+	}
+
+	log := func(data byte) *types.Log {
+		return &types.Log{Address: era1Token, Topics: []common.Hash{{data}}, Data: []byte{data}}
+	}
+	bodies := [][]*types.Transaction{nil, {f.transfer, f.create}, {f.call}, nil}
+	logs := [][][]*types.Log{nil, {nil, {log(1)}}, {{log(2), log(3)}}, nil}
+	gasUsed := [][]uint64{nil, {21_000, 53_000}, {35_000}, nil}
+
+	parent := common.Hash{0xee}
+	for i, txs := range bodies {
+		var (
+			receipts   types.Receipts
+			cumulative uint64
+		)
+		for j, tx := range txs {
+			cumulative += gasUsed[i][j]
+			receipt := &types.Receipt{Type: tx.Type(), Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: cumulative, Logs: logs[i][j]}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipts = append(receipts, receipt)
+		}
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(era1FixtureStart + int64(i)),
+			Time:       1_628_166_822 + uint64(i)*13,
+			Difficulty: big.NewInt(7_742_493_487_903_256),
+			GasLimit:   30_000_000,
+			GasUsed:    cumulative,
+			BaseFee:    baseFee,
+			TxHash:     types.DeriveSha(types.Transactions(txs), trie.NewStackTrie(nil)),
+			Bloom:      types.CreateBloom(receipts),
+		}
+		block := types.NewBlockWithHeader(header).WithBody(types.Body{Transactions: txs})
+		f.blocks = append(f.blocks, block)
+		f.receipts = append(f.receipts, receipts)
+		parent = block.Hash()
+	}
+	return f
+}
+
+// writeEra1 writes the blocks [from, to) of the fixture to an era1 file
+// named name in dir.
+func (f *era1Fixture) writeEra1(t *testing.T, dir, name string, from, to int) {
+	t.Helper()
+	var buf bytes.Buffer
+	entry := func(typ uint16, value []byte) {
+		var header [e2storeHeaderSize]byte
+		binary.LittleEndian.PutUint16(header[:2], typ)
+		binary.LittleEndian.PutUint32(header[2:6], uint32(len(value)))
+		buf.Write(header[:])
+		buf.Write(value)
+	}
+	compressed := func(typ uint16, v interface{}) {
+		data, err := rlp.EncodeToBytes(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var value bytes.Buffer
+		w := snappy.NewBufferedWriter(&value)
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		entry(typ, value.Bytes())
+	}
+
+	entry(era1TypeVersion, nil)
+	var offsets []int64
+	for i := from; i < to; i++ {
+		offsets = append(offsets, int64(buf.Len()))
+		block := f.blocks[i]
+		compressed(era1TypeCompressedHeader, block.Header())
+		compressed(era1TypeCompressedBody, block.Body())
+		compressed(era1TypeCompressedReceipts, f.receipts[i])
+		entry(era1TypeTotalDifficulty, make([]byte, 32))
+	}
+	entry(era1TypeAccumulator, make([]byte, 32))
+
+	// The block index holds the start block, the offsets of the blocks
+	// relative to the index entry, and the block count.
+	indexOffset := int64(buf.Len())
+	index := binary.LittleEndian.AppendUint64(nil, f.blocks[from].NumberU64())
+	for _, off := range offsets {
+		index = binary.LittleEndian.AppendUint64(index, uint64(off-indexOffset))
+	}
+	index = binary.LittleEndian.AppendUint64(index, uint64(to-from))
+	entry(era1TypeBlockIndex, index)
+
+	if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newEra1Client returns a client for the fixture split across two files.
+func newEra1Client(t *testing.T, f *era1Fixture) *Era1Client {
+	t.Helper()
+	dir := t.TempDir()
+	f.writeEra1(t, dir, "mainnet-01582-0a0a0a0a.era1", 0, 2)
+	f.writeEra1(t, dir, "mainnet-01583-0b0b0b0b.era1", 2, 4)
+	c, err := NewEra1Client(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestEra1BlockIndex(t *testing.T) {
+	f := newEra1Fixture(t)
+	c := newEra1Client(t, f)
+	ctx := context.Background()
+
+	for _, want := range f.blocks {
+		header, err := c.GetBlockHeader(ctx, want.Number())
+		if err != nil {
+			t.Fatalf("block %d: %v", want.NumberU64(), err)
+		}
+		if header.Hash() != want.Hash() {
+			t.Errorf("block %d: hash %s, want %s", want.NumberU64(), header.Hash().Hex(), want.Hash().Hex())
+		}
+	}
+
+	last := f.blocks[len(f.blocks)-1]
+	for _, number := range []*big.Int{nil, big.NewInt(-4), big.NewInt(-3)} { // latest, finalized, safe
+		header, err := c.GetBlockHeader(ctx, number)
+		if err != nil || header.Hash() != last.Hash() {
+			t.Errorf("block %v = %v, %v, want the last archived block", number, header, err)
+		}
+	}
+	for _, n := range []int64{era1FixtureStart - 1, era1FixtureStart + 4} {
+		if _, err := c.GetBlockHeader(ctx, big.NewInt(n)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("block %d: error %v, want %v", n, err, ErrNotFound)
+		}
+	}
+
+	hash := f.blocks[2].Hash()
+	block, err := c.GetBlock(ctx, BlockRef{Hash: &hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Hash() != hash || len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != f.call.Hash() {
+		t.Errorf("block by hash = %d with %d transactions, want %d with the call", block.NumberU64(), len(block.Transactions()), f.blocks[2].NumberU64())
+	}
+}
+
+func TestEra1Transaction(t *testing.T) {
+	f := newEra1Fixture(t)
+	c := newEra1Client(t, f)
+	ctx := context.Background()
+
+	tests := []struct {
+		tx    *types.Transaction
+		block *types.Block
+		index uint
+	}{
+		{f.transfer, f.blocks[1], 0},
+		{f.create, f.blocks[1], 1},
+		{f.call, f.blocks[2], 0},
+	}
+	for _, tt := range tests {
+		tx, isPending, err := c.GetTransaction(ctx, tt.tx.Hash())
+		if err != nil || tx == nil || isPending || tx.Hash() != tt.tx.Hash() {
+			t.Fatalf("GetTransaction(%s) = %v, %v, %v", tt.tx.Hash().Hex(), tx, isPending, err)
+		}
+		bundle, err := c.GetTransactionBundle(ctx, tt.tx.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bundle.Header.Hash() != tt.block.Hash() || bundle.Receipt.TransactionIndex != tt.index {
+			t.Errorf("%s: in block %d at %d, want %d at %d", tt.tx.Hash().Hex(), bundle.Header.Number, bundle.Receipt.TransactionIndex, tt.block.NumberU64(), tt.index)
+		}
+	}
+
+	tx, _, err := c.GetTransaction(ctx, common.Hash{0xba, 0xd})
+	if err != nil || tx != nil {
+		t.Errorf("GetTransaction of an unarchived transaction = %v, %v, want nil, nil", tx, err)
+	}
+}
+
+func TestEra1ReceiptFields(t *testing.T) {
+	f := newEra1Fixture(t)
+	c := newEra1Client(t, f)
+	ctx := context.Background()
+
+	// The archives store consensus receipts; everything else is derived
+	// from the block.
+	receipts, err := c.GetBlockReceipts(ctx, BlockRef{Number: f.blocks[1].Number()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 2 {
+		t.Fatalf("%d receipts, want 2", len(receipts))
+	}
+	block := f.blocks[1]
+	transfer, create := receipts[0], receipts[1]
+	for i, r := range receipts {
+		if r.BlockHash != block.Hash() || r.BlockNumber.Cmp(block.Number()) != 0 || r.TransactionIndex != uint(i) || r.TxHash != block.Transactions()[i].Hash() {
+			t.Errorf("receipt %d: block %s %v, index %d, tx %s", i, r.BlockHash.Hex(), r.BlockNumber, r.TransactionIndex, r.TxHash.Hex())
+		}
+	}
+	if transfer.GasUsed != 21_000 || create.GasUsed != 53_000 {
+		t.Errorf("gas used = %d, %d, want 21000, 53000", transfer.GasUsed, create.GasUsed)
+	}
+	// The transfer pays the base fee and its full tip; the legacy creation
+	// its gas price.
+	if transfer.EffectiveGasPrice.Int64() != 3_000_000_000 || create.EffectiveGasPrice.Int64() != 20_000_000_000 {
+		t.Errorf("effective gas prices = %v, %v, want 3 and 20 gwei", transfer.EffectiveGasPrice, create.EffectiveGasPrice)
+	}
+	if want := crypto.CreateAddress(f.sender, 1); create.ContractAddress != want {
+		t.Errorf("contract address = %s, want %s", create.ContractAddress.Hex(), want.Hex())
+	}
+	if transfer.ContractAddress != (common.Address{}) {
+		t.Errorf("transfer contract address = %s, want none", transfer.ContractAddress.Hex())
+	}
+	if l := create.Logs[0]; l.TxHash != f.create.Hash() || l.TxIndex != 1 || l.Index != 0 || l.BlockNumber != block.NumberU64() || l.BlockHash != block.Hash() {
+		t.Errorf("creation log = %+v", l)
+	}
+
+	// Log indices are counted per block.
+	receipt, err := c.GetTransactionReceipt(ctx, f.call.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipt.Logs) != 2 || receipt.Logs[0].Index != 0 || receipt.Logs[1].Index != 1 {
+		t.Errorf("call logs = %+v, want indices 0 and 1", receipt.Logs)
+	}
+	// The call's fee cap leaves less than the full tip.
+	if receipt.EffectiveGasPrice.Int64() != 1_500_000_000 {
+		t.Errorf("effective gas price = %v, want 1.5 gwei", receipt.EffectiveGasPrice)
+	}
+}
+
+func TestNewEra1ClientErrors(t *testing.T) {
+	f := newEra1Fixture(t)
+	tests := []struct {
+		name  string
+		files map[string][2]int // file name to block range
+		err   string
+	}{
+		{"overlap", map[string][2]int{"mainnet-01582-0a0a0a0a.era1": {0, 3}, "mainnet-01583-0b0b0b0b.era1": {2, 4}}, "overlap"},
+		{"missing root", map[string][2]int{"mainnet-01582.era1": {0, 2}}, "malformed era1 file name mainnet-01582.era1"},
+		{"non-numeric epoch", map[string][2]int{"mainnet-first-0a0a0a0a.era1": {0, 2}}, "malformed era1 file name mainnet-first-0a0a0a0a.era1"},
+		{"mixed networks", map[string][2]int{"mainnet-01582-0a0a0a0a.era1": {0, 2}, "sepolia-01583-0b0b0b0b.era1": {2, 4}}, "different networks"},
+		{"unknown network", map[string][2]int{"holesky-01582-0a0a0a0a.era1": {0, 2}}, `unknown era1 network "holesky"`},
+		{"no files", nil, "no era1 files"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for name, r := range tt.files {
+			f.writeEra1(t, dir, name, r[0], r[1])
+		}
+		c, err := NewEra1Client(dir)
+		if err == nil {
+			c.Close()
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %q, want %q", tt.name, err, tt.err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mainnet-01582-0a0a0a0a.era1"), make([]byte, 64), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEra1Client(dir); err == nil || !strings.Contains(err.Error(), "missing version entry") {
+		t.Errorf("file without a version entry: error %v", err)
+	}
+}
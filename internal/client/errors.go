@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Error categories. RPCClient wraps the errors it returns in an *RPCError
+// whose Kind is one of these, so callers can use errors.Is to check the
+// category and errors.As to get at the details.
+var (
+	// ErrNotFound indicates the requested object does not exist on the node,
+	// or not yet (e.g. "header not found" while the node is catching up).
+	ErrNotFound = errors.New("not found")
+
+	// ErrRateLimited indicates the endpoint rejected the request because
+	// of rate limiting (HTTP 429 or a provider-specific JSON-RPC error).
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrMethodUnsupported indicates the endpoint does not serve the
+	// requested method, e.g. a missing debug namespace.
+	ErrMethodUnsupported = errors.New("method not supported")
+
+	// ErrTransport indicates the endpoint could not be reached or did not
+	// produce a usable response.
+	ErrTransport = errors.New("transport error")
+)
+
+// RPCError is a classified error from a JSON-RPC call.
+type RPCError struct {
+	Method string // JSON-RPC method, e.g. "eth_getTransactionReceipt"
+	Kind   error  // error category, or nil if unclassified
+	Err    error  // underlying error
+
+	// Temporary reports whether retrying the call may succeed.
+	Temporary bool
+}
+
+func (e *RPCError) Error() string {
+	return e.Method + ": " + e.Err.Error()
+}
+
+// Unwrap exposes both the category and the underlying error to errors.Is
+// and errors.As.
+func (e *RPCError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// classifyError wraps err from a call to method in an *RPCError. nil and
+// context errors are returned unchanged, as are already classified errors.
+func classifyError(method string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var classified *RPCError
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	e := &RPCError{Method: method, Err: err}
+	msg := strings.ToLower(err.Error())
+
+	var (
+		rpcErr  rpc.Error
+		httpErr rpc.HTTPError
+	)
+	switch {
+	case errors.Is(err, ethereum.NotFound):
+		e.Kind = ErrNotFound
+	case errors.As(err, &httpErr) && httpErr.StatusCode == 429,
+		strings.Contains(msg, "rate limit"), strings.Contains(msg, "too many requests"),
+		errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32005:
+		e.Kind, e.Temporary = ErrRateLimited, true
+	case errors.Is(err, types.ErrTxTypeNotSupported):
+		// A transaction type too new for go-ethereum's decoder; the method
+		// itself is served.
+	case errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601, isMethodNotFound(msg):
+		e.Kind = ErrMethodUnsupported
+	case strings.Contains(msg, "header not found"):
+		// Typically a load-balanced node that has not caught up yet.
+		e.Kind, e.Temporary = ErrNotFound, true
+	case strings.Contains(msg, "block not found"), strings.Contains(msg, "unknown block"):
+		// A block that does not exist, such as a mistyped number or the
+		// finalized block of a pre-merge chain; waiting won't help.
+		e.Kind = ErrNotFound
+	case isTransportError(err):
+		e.Kind, e.Temporary = ErrTransport, true
+	}
+	return e
+}
+
+// methodNotFoundMessages are the messages with which nodes and providers
+// that do not use error code -32601 reject a method they do not serve.
+// They name the method rather than a parameter, so that e.g. "transaction
+// type not supported" is not taken for a missing method.
+var methodNotFoundMessages = []string{
+	"method not found",                // Besu, Nethermind, most proxies
+	"does not exist/is not available", // geth, Erigon, Infura
+	"method not supported",
+	"method is not supported",
+	"unsupported method", // Alchemy
+	"method not enabled", // Besu, with the namespace disabled
+	"method not available",
+	"method not allowed",
+}
+
+// isMethodNotFound reports whether the lower-cased error message msg says
+// that the method is not served.
+func isMethodNotFound(msg string) bool {
+	for _, m := range methodNotFoundMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransportError reports whether err indicates that an endpoint could not
+// be reached or did not produce a usable answer, as opposed to the node
+// answering with a JSON-RPC error.
+func isTransportError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrTransport) || errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == 429
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
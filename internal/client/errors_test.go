@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// jsonrpcError is a JSON-RPC error answer with a code, as rpc.Error.
+type jsonrpcError struct {
+	code int
+	msg  string
+}
+
+func (e jsonrpcError) Error() string  { return e.msg }
+func (e jsonrpcError) ErrorCode() int { return e.code }
+
+func TestClassifyError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		name      string
+		err       error
+		kind      error // nil for unclassified
+		temporary bool
+	}{
+		{"not found", ethereum.NotFound, ErrNotFound, false},
+		{"http 429", rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, ErrRateLimited, true},
+		{"rate limit message", errors.New("daily request count exceeded, request rate limited"), ErrRateLimited, true},
+		{"too many requests message", errors.New("Too Many Requests"), ErrRateLimited, true},
+		{"limit exceeded code", jsonrpcError{-32005, "limit exceeded"}, ErrRateLimited, true},
+		{"method not found code", jsonrpcError{-32601, "the method debug_traceTransaction does not exist/is not available"}, ErrMethodUnsupported, false},
+		{"method not found message", jsonrpcError{-32000, "Method not found"}, ErrMethodUnsupported, false},
+		{"unsupported method message", errors.New("Unsupported method: trace_transaction"), ErrMethodUnsupported, false},
+		{"tx type not supported", fmt.Errorf("decoding: %w", types.ErrTxTypeNotSupported), nil, false},
+		{"header not found", jsonrpcError{-32000, "header not found"}, ErrNotFound, true},
+		{"block not found", jsonrpcError{-32000, "block not found"}, ErrNotFound, false},
+		{"unknown block", jsonrpcError{-32000, "unknown block"}, ErrNotFound, false},
+		{"finalized block not found", jsonrpcError{-32000, "finalized block not found"}, ErrNotFound, false},
+		{"http 503", rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}, ErrTransport, true},
+		{"http 400", rpc.HTTPError{StatusCode: 400, Status: "400 Bad Request"}, nil, false},
+		{"connection refused", dialErr, ErrTransport, true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), ErrTransport, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, ErrTransport, true},
+		{"execution reverted", jsonrpcError{3, "execution reverted"}, nil, false},
+	}
+	for _, tt := range tests {
+		err := classifyError("eth_call", tt.err)
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			t.Errorf("%s: %v is not an *RPCError", tt.name, err)
+			continue
+		}
+		if rpcErr.Kind != tt.kind || rpcErr.Temporary != tt.temporary {
+			t.Errorf("%s: kind %v, temporary %v, want %v, %v", tt.name, rpcErr.Kind, rpcErr.Temporary, tt.kind, tt.temporary)
+		}
+		if tt.kind != nil && !errors.Is(err, tt.kind) {
+			t.Errorf("%s: %v is not %v", tt.name, err, tt.kind)
+		}
+		if shouldRetry(err) != tt.temporary {
+			t.Errorf("%s: shouldRetry = %v, want %v", tt.name, shouldRetry(err), tt.temporary)
+		}
+		if rpcErr.Method != "eth_call" || rpcErr.Err.Error() != tt.err.Error() {
+			t.Errorf("%s: %s: %v, want eth_call: %v", tt.name, rpcErr.Method, rpcErr.Err, tt.err)
+		}
+	}
+
+	// Errors that are not classified again.
+	for _, err := range []error{nil, context.Canceled, context.DeadlineExceeded} {
+		if got := classifyError("eth_call", err); got != err {
+			t.Errorf("classifyError(%v) = %v, want it unchanged", err, got)
+		}
+	}
+	classified := classifyError("eth_call", ethereum.NotFound)
+	if got := classifyError("eth_getBlockByNumber", fmt.Errorf("wrapped: %w", classified)); !errors.Is(got, classified) || errors.Is(got, ErrTransport) {
+		t.Errorf("classifying a classified error gave %v", got)
+	}
+}
+
+func TestIsTransportError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{io.EOF, true},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{rpc.HTTPError{StatusCode: 502}, true},
+		{rpc.HTTPError{StatusCode: 404}, false},
+		{jsonrpcError{-32000, "connection reset by peer"}, false}, // the node answered
+		{&RPCError{Kind: ErrRateLimited, Err: errors.New("slow down")}, true},
+		{&RPCError{Kind: ErrNotFound, Err: errors.New("header not found")}, false},
+	}
+	for _, tt := range tests {
+		if got := isTransportError(tt.err); got != tt.want {
+			t.Errorf("isTransportError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 250 * time.Millisecond, MaxBackoff: 5 * time.Second, Multiplier: 2}
+	want := []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	// A multiplier below 1 keeps the delay constant.
+	p = RetryPolicy{InitialBackoff: time.Second, Multiplier: 0.5}
+	if got := p.backoff(4); got != time.Second {
+		t.Errorf("backoff with multiplier 0.5 = %v, want 1s", got)
+	}
+
+	// Jitter stays within its fraction of the delay.
+	p = RetryPolicy{InitialBackoff: time.Second, Multiplier: 2, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(2); got < 1600*time.Millisecond || got > 2400*time.Millisecond {
+			t.Fatalf("backoff(2) with 20%% jitter = %v, want 1.6s to 2.4s", got)
+		}
+	}
+}
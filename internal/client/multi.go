@@ -0,0 +1,497 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// Mode selects how a MultiClient combines its endpoints.
+type Mode string
+
+const (
+	// ModeFailover sends each request to one endpoint, moving on to the next
+	// one when the current endpoint fails with a transport error or is rate
+	// limited.
+	ModeFailover Mode = "failover"
+	// ModeQuorum sends each request to several endpoints concurrently and
+	// returns the answer agreed on by a majority of them.
+	ModeQuorum Mode = "quorum"
+)
+
+// ParseMode parses a mode name, defaulting to ModeFailover when empty.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeFailover:
+		return ModeFailover, nil
+	case ModeQuorum:
+		return ModeQuorum, nil
+	default:
+		return "", fmt.Errorf("unknown RPC mode %q (expected failover or quorum)", s)
+	}
+}
+
+// ErrNoQuorum is returned in ModeQuorum when too few endpoints agree.
+var ErrNoQuorum = errors.New("endpoints did not reach quorum")
+
+// Endpoint is a named Client wrapped by a MultiClient.
+type Endpoint struct {
+	Name   string // display name, usually the endpoint URL
+	Client Client
+}
+
+// MultiOptions configures a MultiClient.
+type MultiOptions struct {
+	Mode Mode
+
+	// Quorum is the number of endpoints asked in ModeQuorum; a majority of
+	// them must agree. Zero asks all endpoints.
+	Quorum int
+
+	// OnDisagreement, if set, is called whenever endpoints disagree in
+	// ModeQuorum.
+	OnDisagreement func(Disagreement)
+}
+
+// Disagreement records endpoints returning different answers to the same
+// request in ModeQuorum.
+type Disagreement struct {
+	Method string // e.g. "GetTransactionReceipt"
+	Key    string // requested hash or block number
+
+	// Answers maps endpoint names to a short description of their answer.
+	Answers map[string]string
+}
+
+func (d Disagreement) String() string {
+	names := make([]string, 0, len(d.Answers))
+	for name := range d.Answers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+d.Answers[name])
+	}
+	return fmt.Sprintf("%s(%s): %s", d.Method, d.Key, strings.Join(parts, ", "))
+}
+
+// MultiClient is a Client that wraps several endpoints, either failing over
+// between them or requiring a quorum of matching answers.
+type MultiClient struct {
+	endpoints []Endpoint
+	opts      MultiOptions
+	chain     *chain.Chain
+
+	mu        sync.Mutex
+	preferred int // index of the endpoint tried first in ModeFailover
+}
+
+// NewMultiClient creates a client over the given endpoints. The MultiClient
+// takes ownership of the endpoint clients and closes them on Close.
+func NewMultiClient(endpoints []Endpoint, opts MultiOptions) (*MultiClient, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one endpoint is required")
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeFailover
+	}
+	if opts.Quorum <= 0 || opts.Quorum > len(endpoints) {
+		opts.Quorum = len(endpoints)
+	}
+	return &MultiClient{endpoints: endpoints, opts: opts}, nil
+}
+
+// Endpoints returns the wrapped endpoints.
+func (m *MultiClient) Endpoints() []Endpoint {
+	return m.endpoints
+}
+
+// txResult is a transaction and, once mined, the block including it.
+type txResult struct {
+	tx          *types.Transaction
+	isPending   bool
+	blockHash   common.Hash
+	blockNumber *big.Int
+}
+
+// transactionLocator is implemented by clients that report the block
+// including a transaction along with the transaction itself.
+type transactionLocator interface {
+	locateTransaction(ctx context.Context, txHash common.Hash) (txResult, error)
+}
+
+// Chain returns the chain identified when the client was created by
+// NewClient, or nil.
+func (m *MultiClient) Chain() *chain.Chain {
+	return m.chain
+}
+
+// GetTransaction retrieves a transaction by hash.
+//
+// In ModeQuorum, endpoints agree when they include the transaction in the
+// same block: endpoints on different forks return the same transaction.
+func (m *MultiClient) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	if m.opts.Mode != ModeQuorum {
+		res, err := failover(ctx, m, func(c Client) (txResult, error) {
+			tx, isPending, err := c.GetTransaction(ctx, txHash)
+			return txResult{tx: tx, isPending: isPending}, err
+		})
+		return res.tx, res.isPending, err
+	}
+
+	res, err := quorum(ctx, m, "GetTransaction", txHash.Hex(), func(c Client) (txResult, error) {
+		return locateTransaction(ctx, c, txHash)
+	}, func(r txResult) string {
+		switch {
+		case r.tx == nil:
+			return "not found"
+		case r.isPending:
+			return r.tx.Hash().Hex() + " (pending)"
+		case r.blockNumber == nil:
+			return r.tx.Hash().Hex() + " (no receipt)"
+		}
+		return fmt.Sprintf("%s in block %s (%s)", r.tx.Hash().Hex(), r.blockNumber, r.blockHash.TerminalString())
+	})
+	return res.tx, res.isPending, err
+}
+
+// locateTransaction retrieves a transaction and the block including it
+// through c, which without a transactionLocator takes a receipt lookup.
+func locateTransaction(ctx context.Context, c Client, txHash common.Hash) (txResult, error) {
+	if l, ok := c.(transactionLocator); ok {
+		return l.locateTransaction(ctx, txHash)
+	}
+	tx, isPending, err := c.GetTransaction(ctx, txHash)
+	res := txResult{tx: tx, isPending: isPending}
+	if err != nil || tx == nil || isPending {
+		return res, err
+	}
+	receipt, err := c.GetTransactionReceipt(ctx, txHash)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return res, err
+	}
+	if receipt != nil {
+		res.blockHash, res.blockNumber = receipt.BlockHash, receipt.BlockNumber
+	}
+	return res, nil
+}
+
+// GetTransactionReceipt retrieves a transaction receipt by hash.
+func (m *MultiClient) GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	call := func(c Client) (*types.Receipt, error) {
+		return c.GetTransactionReceipt(ctx, txHash)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetTransactionReceipt", txHash.Hex(), call, receiptFingerprint)
+}
+
+// ChainID retrieves the chain ID of the connected network.
+func (m *MultiClient) ChainID(ctx context.Context) (*big.Int, error) {
+	call := func(c Client) (*big.Int, error) {
+		return c.ChainID(ctx)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "ChainID", "-", call, func(id *big.Int) string {
+		return id.String()
+	})
+}
+
+// GetBlockHeader retrieves a block header by number.
+func (m *MultiClient) GetBlockHeader(ctx context.Context, blockNumber *big.Int) (*types.Header, error) {
+	call := func(c Client) (*types.Header, error) {
+		return c.GetBlockHeader(ctx, blockNumber)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+
+	key := "latest"
+	if blockNumber != nil {
+		key = blockNumber.String()
+	}
+	return quorum(ctx, m, "GetBlockHeader", key, call, func(h *types.Header) string {
+		if h == nil {
+			return "not found"
+		}
+		return h.Hash().Hex()
+	})
+}
+
+// GetBlock retrieves a block with its full transactions by number, hash or
+// tag.
+func (m *MultiClient) GetBlock(ctx context.Context, ref BlockRef) (*types.Block, error) {
+	call := func(c Client) (*types.Block, error) {
+		return c.GetBlock(ctx, ref)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetBlock", ref.String(), call, func(b *types.Block) string {
+		if b == nil {
+			return "not found"
+		}
+		return b.Hash().Hex()
+	})
+}
+
+// GetBlockReceipts retrieves the receipts of all transactions in a block.
+// In ModeQuorum, endpoints agree when their receipts belong to the same
+// block and hash to the same receipt root.
+func (m *MultiClient) GetBlockReceipts(ctx context.Context, ref BlockRef) (types.Receipts, error) {
+	call := func(c Client) (types.Receipts, error) {
+		return c.GetBlockReceipts(ctx, ref)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetBlockReceipts", ref.String(), call, func(r types.Receipts) string {
+		if len(r) == 0 {
+			return "no receipts"
+		}
+		root := types.DeriveSha(r, trie.NewStackTrie(nil))
+		return fmt.Sprintf("block %s: %d receipts (root %s)", r[0].BlockHash.TerminalString(), len(r), root.TerminalString())
+	})
+}
+
+// GetBalance retrieves the balance of an account at a block.
+func (m *MultiClient) GetBalance(ctx context.Context, account common.Address, ref BlockRef) (*big.Int, error) {
+	call := func(c Client) (*big.Int, error) {
+		return c.GetBalance(ctx, account, ref)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetBalance", stateKey(account, ref), call, func(balance *big.Int) string {
+		return balance.String()
+	})
+}
+
+// GetNonce retrieves the nonce of an account at a block.
+func (m *MultiClient) GetNonce(ctx context.Context, account common.Address, ref BlockRef) (uint64, error) {
+	call := func(c Client) (uint64, error) {
+		return c.GetNonce(ctx, account, ref)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetNonce", stateKey(account, ref), call, func(nonce uint64) string {
+		return fmt.Sprint(nonce)
+	})
+}
+
+// GetCode retrieves the code of an account at a block. In ModeQuorum,
+// endpoints agree when their code hashes match.
+func (m *MultiClient) GetCode(ctx context.Context, account common.Address, ref BlockRef) ([]byte, error) {
+	call := func(c Client) ([]byte, error) {
+		return c.GetCode(ctx, account, ref)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetCode", stateKey(account, ref), call, func(code []byte) string {
+		return fmt.Sprintf("%d bytes (hash %s)", len(code), crypto.Keccak256Hash(code).TerminalString())
+	})
+}
+
+// GetStorageAt retrieves the value of a storage slot of an account at a
+// block.
+func (m *MultiClient) GetStorageAt(ctx context.Context, account common.Address, slot common.Hash, ref BlockRef) (common.Hash, error) {
+	call := func(c Client) (common.Hash, error) {
+		return c.GetStorageAt(ctx, account, slot, ref)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetStorageAt", stateKey(account, ref)+" slot "+slot.Hex(), call, func(value common.Hash) string {
+		return value.Hex()
+	})
+}
+
+// GetProof retrieves the state of an account and of the given storage
+// slots at a block, with their Merkle proofs. In ModeQuorum, endpoints agree
+// when the proven account and slot values match; proofs of the same state
+// may still differ in encoding details between clients.
+func (m *MultiClient) GetProof(ctx context.Context, account common.Address, slots []common.Hash, ref BlockRef) (*AccountProof, error) {
+	call := func(c Client) (*AccountProof, error) {
+		return c.GetProof(ctx, account, slots, ref)
+	}
+	if m.opts.Mode != ModeQuorum {
+		return failover(ctx, m, call)
+	}
+	return quorum(ctx, m, "GetProof", stateKey(account, ref), call, func(p *AccountProof) string {
+		values := make([]string, len(p.StorageProof))
+		for i, slot := range p.StorageProof {
+			values[i] = slot.Value.String()
+		}
+		return fmt.Sprintf("balance %s, nonce %d, code %s, storage %s [%s]", p.Balance, p.Nonce,
+			p.CodeHash.TerminalString(), p.StorageHash.TerminalString(), strings.Join(values, " "))
+	})
+}
+
+// TraceTransaction generates an execution trace for a transaction.
+//
+// Traces are large and client-specific, so they are never compared across
+// endpoints; both modes fail over between endpoints.
+func (m *MultiClient) TraceTransaction(ctx context.Context, txHash common.Hash, opts *TraceOptions) (*TraceResult, error) {
+	return failover(ctx, m, func(c Client) (*TraceResult, error) {
+		return c.TraceTransaction(ctx, txHash, opts)
+	})
+}
+
+// GetTransactionBundle retrieves a transaction, its receipt and its
+// containing block header.
+//
+// In ModeFailover the bundle comes from a single endpoint, using its batched
+// path when available. In ModeQuorum each object is agreed on separately.
+func (m *MultiClient) GetTransactionBundle(ctx context.Context, txHash common.Hash) (*TransactionBundle, error) {
+	if m.opts.Mode == ModeQuorum {
+		return getTransactionBundle(ctx, m, txHash)
+	}
+	return failover(ctx, m, func(c Client) (*TransactionBundle, error) {
+		return GetTransactionBundle(ctx, c, txHash)
+	})
+}
+
+// Close closes all endpoint clients.
+func (m *MultiClient) Close() {
+	for _, e := range m.endpoints {
+		e.Client.Close()
+	}
+}
+
+// stateKey describes a state request in a Disagreement.
+func stateKey(account common.Address, ref BlockRef) string {
+	return account.Hex() + " at " + ref.String()
+}
+
+// failover calls fn on each endpoint in turn, starting with the preferred
+// one, until it succeeds or fails with a non-transport error. An endpoint
+// that succeeds after others failed becomes the preferred endpoint.
+func failover[T any](ctx context.Context, m *MultiClient, fn func(Client) (T, error)) (T, error) {
+	m.mu.Lock()
+	start := m.preferred
+	m.mu.Unlock()
+
+	var (
+		zero T
+		errs []error
+	)
+	for i := range m.endpoints {
+		idx := (start + i) % len(m.endpoints)
+		e := m.endpoints[idx]
+
+		res, err := fn(e.Client)
+		if ctx.Err() != nil {
+			return res, err
+		}
+		if err == nil || !isTransportError(err) {
+			if i > 0 {
+				m.mu.Lock()
+				m.preferred = idx
+				m.mu.Unlock()
+			}
+			return res, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+	}
+	return zero, fmt.Errorf("all endpoints failed: %w", errors.Join(errs...))
+}
+
+// quorum calls fn on the first Quorum endpoints concurrently and returns the
+// answer shared by a majority of them, as identified by fingerprint. Any
+// disagreement is reported through OnDisagreement.
+func quorum[T any](ctx context.Context, m *MultiClient, method, key string, fn func(Client) (T, error), fingerprint func(T) string) (T, error) {
+	type answer struct {
+		res T
+		err error
+	}
+	endpoints := m.endpoints[:m.opts.Quorum]
+	answers := make([]answer, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func(i int, c Client) {
+			defer wg.Done()
+			res, err := fn(c)
+			answers[i] = answer{res, err}
+		}(i, e.Client)
+	}
+	wg.Wait()
+
+	// Group successful answers by fingerprint.
+	var (
+		zero   T
+		errs   []error
+		votes  = make(map[string]int)
+		first  = make(map[string]int)
+		byName = make(map[string]string)
+	)
+	for i, a := range answers {
+		name := endpoints[i].Name
+		if a.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, a.err))
+			byName[name] = "error: " + a.err.Error()
+			continue
+		}
+		fp := fingerprint(a.res)
+		if _, ok := first[fp]; !ok {
+			first[fp] = i
+		}
+		votes[fp]++
+		byName[name] = fp
+	}
+
+	if len(votes) > 1 || (len(votes) == 1 && len(errs) > 0) {
+		if m.opts.OnDisagreement != nil {
+			m.opts.OnDisagreement(Disagreement{Method: method, Key: key, Answers: byName})
+		}
+	}
+
+	best, bestVotes := "", 0
+	for fp, n := range votes {
+		if n > bestVotes || (n == bestVotes && first[fp] < first[best]) {
+			best, bestVotes = fp, n
+		}
+	}
+	if bestVotes == 0 {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, fmt.Errorf("%w: %w", ErrNoQuorum, errors.Join(errs...))
+	}
+	if bestVotes < len(endpoints)/2+1 {
+		return zero, fmt.Errorf("%w: %s got %d of %d votes", ErrNoQuorum, method, bestVotes, len(endpoints))
+	}
+	return answers[first[best]].res, nil
+}
+
+// receiptFingerprint identifies a receipt by its consensus encoding and
+// inclusion data, ignoring fields that clients render differently.
+func receiptFingerprint(r *types.Receipt) string {
+	if r == nil {
+		return "not found"
+	}
+	enc, err := r.MarshalBinary()
+	if err != nil {
+		return "unencodable receipt"
+	}
+	h := crypto.Keccak256Hash(enc, r.BlockHash.Bytes(), new(big.Int).SetUint64(r.GasUsed).Bytes())
+	return fmt.Sprintf("block %s status %d gas %d (%s)", r.BlockHash.TerminalString(), r.Status, r.GasUsed, h.TerminalString())
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeClient answers ChainID with a fixed result and counts the calls. Its
+// other methods are not implemented.
+type fakeClient struct {
+	Client
+	chainID int64
+	err     error
+	calls   atomic.Int32
+}
+
+func (f *fakeClient) ChainID(ctx context.Context) (*big.Int, error) {
+	f.calls.Add(1)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return big.NewInt(f.chainID), nil
+}
+
+func (f *fakeClient) Close() {}
+
+var (
+	errFakeTransport   = &RPCError{Method: "eth_chainId", Kind: ErrTransport, Err: errors.New("connection refused"), Temporary: true}
+	errFakeRateLimited = &RPCError{Method: "eth_chainId", Kind: ErrRateLimited, Err: errors.New("429 Too Many Requests"), Temporary: true}
+	errFakeUnsupported = &RPCError{Method: "eth_chainId", Kind: ErrMethodUnsupported, Err: errors.New("method not found")}
+	errFakeReverted    = &RPCError{Method: "eth_chainId", Err: errors.New("execution reverted")}
+)
+
+// newFakeMultiClient returns a MultiClient over fakes, named "0", "1", ...
+func newFakeMultiClient(t *testing.T, fakes []*fakeClient, opts MultiOptions) *MultiClient {
+	t.Helper()
+	endpoints := make([]Endpoint, len(fakes))
+	for i, f := range fakes {
+		endpoints[i] = Endpoint{Name: string(rune('0' + i)), Client: f}
+	}
+	m, err := NewMultiClient(endpoints, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// checkCalls compares the number of calls each fake received with want.
+func checkCalls(t *testing.T, name string, fakes []*fakeClient, want []int32) {
+	t.Helper()
+	for i, f := range fakes {
+		if got := f.calls.Load(); got != want[i] {
+			t.Errorf("%s: endpoint %d called %d times, want %d", name, i, got, want[i])
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	tests := []struct {
+		name    string
+		errs    []error // of each endpoint, nil answers its index + 1
+		want    int64
+		wantErr error
+		calls   []int32
+	}{
+		{"first answers", []error{nil, nil}, 1, nil, []int32{1, 0}},
+		{"transport error", []error{errFakeTransport, nil}, 2, nil, []int32{1, 1}},
+		{"rate limited", []error{errFakeRateLimited, nil}, 2, nil, []int32{1, 1}},
+		{"two down", []error{errFakeTransport, errFakeRateLimited, nil}, 3, nil, []int32{1, 1, 1}},
+		{"method unsupported", []error{errFakeUnsupported, nil}, 0, ErrMethodUnsupported, []int32{1, 0}},
+		{"node error", []error{errFakeReverted, nil}, 0, errFakeReverted, []int32{1, 0}},
+		{"all down", []error{errFakeTransport, errFakeRateLimited}, 0, ErrTransport, []int32{1, 1}},
+	}
+	for _, tt := range tests {
+		fakes := make([]*fakeClient, len(tt.errs))
+		for i, err := range tt.errs {
+			fakes[i] = &fakeClient{chainID: int64(i + 1), err: err}
+		}
+		m := newFakeMultiClient(t, fakes, MultiOptions{Mode: ModeFailover})
+
+		id, err := m.ChainID(context.Background())
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		} else if err != nil || id.Int64() != tt.want {
+			t.Errorf("%s: ChainID = %v, %v, want %d", tt.name, id, err, tt.want)
+		}
+		checkCalls(t, tt.name, fakes, tt.calls)
+	}
+}
+
+func TestFailoverPrefersLastWorkingEndpoint(t *testing.T) {
+	fakes := []*fakeClient{{chainID: 1, err: errFakeTransport}, {chainID: 2}}
+	m := newFakeMultiClient(t, fakes, MultiOptions{Mode: ModeFailover})
+	for i := 0; i < 2; i++ {
+		if id, err := m.ChainID(context.Background()); err != nil || id.Int64() != 2 {
+			t.Fatalf("call %d: ChainID = %v, %v, want 2", i, id, err)
+		}
+	}
+	// The failed endpoint is not asked again.
+	checkCalls(t, "second call", fakes, []int32{1, 2})
+}
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		name     string
+		answers  []int64 // chain ID of each endpoint, 0 for a transport error
+		quorum   int
+		want     int64 // 0 for ErrNoQuorum
+		disagree bool
+		calls    []int32
+	}{
+		{"unanimous", []int64{1, 1, 1}, 0, 1, false, []int32{1, 1, 1}},
+		{"majority", []int64{1, 2, 2}, 0, 2, true, []int32{1, 1, 1}},
+		{"majority despite an error", []int64{0, 3, 3}, 0, 3, true, []int32{1, 1, 1}},
+		{"all different", []int64{1, 2, 3}, 0, 0, true, []int32{1, 1, 1}},
+		{"tie of two", []int64{1, 2}, 0, 0, true, []int32{1, 1}},
+		{"tie of four", []int64{1, 1, 2, 2}, 0, 0, true, []int32{1, 1, 1, 1}},
+		{"one vote of two", []int64{0, 1}, 0, 0, true, []int32{1, 1}},
+		{"all errors", []int64{0, 0}, 0, 0, false, []int32{1, 1}},
+		{"quorum of two", []int64{5, 5, 6}, 2, 5, false, []int32{1, 1, 0}},
+		{"quorum of two split", []int64{5, 6, 6}, 2, 0, true, []int32{1, 1, 0}},
+		{"quorum above endpoints", []int64{7, 7, 8}, 5, 7, true, []int32{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		fakes := make([]*fakeClient, len(tt.answers))
+		for i, id := range tt.answers {
+			fakes[i] = &fakeClient{chainID: id}
+			if id == 0 {
+				fakes[i].err = errFakeTransport
+			}
+		}
+		var disagreements []Disagreement
+		m := newFakeMultiClient(t, fakes, MultiOptions{
+			Mode:           ModeQuorum,
+			Quorum:         tt.quorum,
+			OnDisagreement: func(d Disagreement) { disagreements = append(disagreements, d) },
+		})
+
+		id, err := m.ChainID(context.Background())
+		if tt.want == 0 {
+			if !errors.Is(err, ErrNoQuorum) {
+				t.Errorf("%s: ChainID = %v, %v, want no quorum", tt.name, id, err)
+			}
+		} else if err != nil || id.Int64() != tt.want {
+			t.Errorf("%s: ChainID = %v, %v, want %d", tt.name, id, err, tt.want)
+		}
+		checkCalls(t, tt.name, fakes, tt.calls)
+
+		if got := len(disagreements) > 0; got != tt.disagree {
+			t.Errorf("%s: disagreement reported = %v, want %v", tt.name, got, tt.disagree)
+			continue
+		}
+		if !tt.disagree {
+			continue
+		}
+		d := disagreements[0]
+		if d.Method != "ChainID" || len(d.Answers) != m.opts.Quorum {
+			t.Errorf("%s: disagreement %v, want the answers of %d endpoints to ChainID", tt.name, d, m.opts.Quorum)
+		}
+		for i, answer := range tt.answers[:m.opts.Quorum] {
+			got := d.Answers[string(rune('0'+i))]
+			if answer == 0 && !strings.HasPrefix(got, "error: ") || answer != 0 && got != big.NewInt(answer).String() {
+				t.Errorf("%s: endpoint %d answer %q, want %d", tt.name, i, got, answer)
+			}
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ParityTraceType selects an output of trace_replayTransaction.
+type ParityTraceType string
+
+const (
+	// ParityTraceCalls is the flat list of call frames ("trace").
+	ParityTraceCalls ParityTraceType = "trace"
+	// ParityTraceVM is the opcode-level virtual machine trace ("vmTrace").
+	ParityTraceVM ParityTraceType = "vmTrace"
+	// ParityTraceStateDiff is the state changed by the transaction
+	// ("stateDiff").
+	ParityTraceStateDiff ParityTraceType = "stateDiff"
+)
+
+// ParityTracer is implemented by clients that can trace transactions with
+// the Parity-style trace namespace served by Erigon, Nethermind and
+// OpenEthereum.
+type ParityTracer interface {
+	// TraceParityTransaction returns the call frames of a mined
+	// transaction (trace_transaction), in depth-first order.
+	// Returns nil, nil if the transaction is not found.
+	TraceParityTransaction(ctx context.Context, txHash common.Hash) ([]ParityTrace, error)
+
+	// ReplayParityTransaction re-executes a mined transaction and returns
+	// the requested outputs (trace_replayTransaction).
+	// Returns nil, nil if the transaction is not found.
+	ReplayParityTransaction(ctx context.Context, txHash common.Hash, types ...ParityTraceType) (*ParityReplay, error)
+}
+
+// errNoParityTraces is returned by decorators whose wrapped client does not
+// implement ParityTracer.
+var errNoParityTraces = &RPCError{
+	Method: "trace_transaction",
+	Kind:   ErrMethodUnsupported,
+	Err:    errors.New("client does not serve the trace namespace"),
+}
+
+// ParityTrace is a single call frame of Parity-style trace output. Frames
+// are identified by their TraceAddress, the path of child indices from the
+// root frame. Failed frames have Error set; their Result is meaningless.
+type ParityTrace struct {
+	Type         string             `json:"type"` // call, create, suicide or reward
+	Action       ParityAction       `json:"action"`
+	Result       *ParityTraceResult `json:"result"`
+	Error        string             `json:"error,omitempty"`
+	Subtraces    int                `json:"subtraces"`
+	TraceAddress []int              `json:"traceAddress"`
+}
+
+// ParityAction is what a frame set out to do. Which fields are set depends
+// on the frame type.
+type ParityAction struct {
+	// call and create
+	CallType       string          `json:"callType,omitempty"` // call, callcode, delegatecall or staticcall
+	From           common.Address  `json:"from"`
+	To             *common.Address `json:"to,omitempty"`
+	Value          *hexutil.Big    `json:"value,omitempty"`
+	Gas            hexutil.Uint64  `json:"gas"`
+	Input          hexutil.Bytes   `json:"input,omitempty"`
+	Init           hexutil.Bytes   `json:"init,omitempty"`
+	CreationMethod string          `json:"creationMethod,omitempty"` // create or create2, where reported
+
+	// suicide
+	Address       *common.Address `json:"address,omitempty"`
+	RefundAddress *common.Address `json:"refundAddress,omitempty"`
+	Balance       *hexutil.Big    `json:"balance,omitempty"`
+}
+
+// ParityTraceResult is the outcome of a frame that did not fail.
+type ParityTraceResult struct {
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Output  hexutil.Bytes   `json:"output,omitempty"`
+	Address *common.Address `json:"address,omitempty"` // created contract
+	Code    hexutil.Bytes   `json:"code,omitempty"`    // created contract's code
+}
+
+// ParityReplay is the result of trace_replayTransaction. Only the outputs
+// that were requested are set.
+type ParityReplay struct {
+	Output    hexutil.Bytes                         `json:"output"`
+	Trace     []ParityTrace                         `json:"trace"`
+	VMTrace   *ParityVMTrace                        `json:"vmTrace"`
+	StateDiff map[common.Address]*ParityAccountDiff `json:"stateDiff"`
+}
+
+// ParityVMTrace is the execution of the code of a single frame.
+type ParityVMTrace struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []ParityVMOp  `json:"ops"`
+}
+
+// ParityVMOp is a single executed opcode. Sub holds the trace of the frame
+// entered by call and create opcodes.
+type ParityVMOp struct {
+	PC   uint64         `json:"pc"`
+	Op   string         `json:"op,omitempty"` // reported by Erigon only
+	Cost uint64         `json:"cost"`
+	Ex   *ParityVMExec  `json:"ex"` // nil if the opcode failed
+	Sub  *ParityVMTrace `json:"sub"`
+}
+
+// ParityVMExec is the effect of an executed opcode.
+type ParityVMExec struct {
+	Used uint64   `json:"used"` // gas remaining after the opcode
+	Push []string `json:"push"`
+	Mem  *struct {
+		Off  uint64        `json:"off"`
+		Data hexutil.Bytes `json:"data"`
+	} `json:"mem"`
+	Store *struct {
+		Key string `json:"key"`
+		Val string `json:"val"`
+	} `json:"store"`
+}
+
+// ParityAccountDiff is the change of an account's state in a stateDiff.
+type ParityAccountDiff struct {
+	Balance ParityDiff                 `json:"balance"`
+	Nonce   ParityDiff                 `json:"nonce"`
+	Code    ParityDiff                 `json:"code"`
+	Storage map[common.Hash]ParityDiff `json:"storage"`
+}
+
+// ParityDiff is the change of a single value in a stateDiff: unchanged
+// ("="), born ("+"), died ("-") or changed ("*"). From and To are the
+// hex-encoded values before and after, empty where the value did not exist.
+type ParityDiff struct {
+	Kind     string
+	From, To string
+}
+
+func (d *ParityDiff) UnmarshalJSON(data []byte) error {
+	var unchanged string
+	if err := json.Unmarshal(data, &unchanged); err == nil {
+		if unchanged != "=" {
+			return fmt.Errorf("invalid state diff %q", unchanged)
+		}
+		*d = ParityDiff{Kind: "="}
+		return nil
+	}
+
+	var dec struct {
+		Born    *string `json:"+"`
+		Died    *string `json:"-"`
+		Changed *struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"*"`
+	}
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	switch {
+	case dec.Born != nil:
+		*d = ParityDiff{Kind: "+", To: *dec.Born}
+	case dec.Died != nil:
+		*d = ParityDiff{Kind: "-", From: *dec.Died}
+	case dec.Changed != nil:
+		*d = ParityDiff{Kind: "*", From: dec.Changed.From, To: dec.Changed.To}
+	default:
+		return fmt.Errorf("invalid state diff %s", data)
+	}
+	return nil
+}
+
+func (d ParityDiff) MarshalJSON() ([]byte, error) {
+	switch d.Kind {
+	case "+":
+		return json.Marshal(map[string]string{"+": d.To})
+	case "-":
+		return json.Marshal(map[string]string{"-": d.From})
+	case "*":
+		return json.Marshal(map[string]map[string]string{"*": {"from": d.From, "to": d.To}})
+	}
+	return json.Marshal("=")
+}
+
+// PrestateFromStateDiff converts a stateDiff into the shape of the prestate tracer's
+// output. In diff mode Pre holds the changed values before and Post after the
+// transaction, as with geth; otherwise only Pre is set. Unlike geth's
+// tracer, a stateDiff omits accounts that were read but not changed.
+func PrestateFromStateDiff(diff map[common.Address]*ParityAccountDiff, diffMode bool) (*PrestateTrace, error) {
+	trace := &PrestateTrace{Pre: make(map[common.Address]*PrestateAccount)}
+	if diffMode {
+		trace.Post = make(map[common.Address]*PrestateAccount)
+	}
+	for addr, account := range diff {
+		var pre, post PrestateAccount
+		if err := account.apply(&pre, &post); err != nil {
+			return nil, fmt.Errorf("invalid state diff of %s: %w", addr.Hex(), err)
+		}
+		if account.Balance.Kind != "+" {
+			trace.Pre[addr] = &pre
+		}
+		if diffMode && account.Balance.Kind != "-" {
+			trace.Post[addr] = &post
+		}
+	}
+	return trace, nil
+}
+
+// apply sets the values of an account before and after the transaction.
+func (a *ParityAccountDiff) apply(pre, post *PrestateAccount) error {
+	var err error
+	if pre.Balance, post.Balance, err = diffBigs(a.Balance); err != nil {
+		return err
+	}
+	nonceFrom, nonceTo, err := diffBigs(a.Nonce)
+	if err != nil {
+		return err
+	}
+	if nonceFrom != nil {
+		pre.Nonce = nonceFrom.ToInt().Uint64()
+	}
+	if nonceTo != nil {
+		post.Nonce = nonceTo.ToInt().Uint64()
+	}
+	if a.Code.From != "" {
+		if pre.Code, err = hexutil.Decode(a.Code.From); err != nil {
+			return err
+		}
+	}
+	if a.Code.To != "" {
+		if post.Code, err = hexutil.Decode(a.Code.To); err != nil {
+			return err
+		}
+	}
+	for slot, value := range a.Storage {
+		if value.Kind == "=" {
+			continue
+		}
+		if value.From != "" {
+			if pre.Storage == nil {
+				pre.Storage = make(map[common.Hash]common.Hash)
+			}
+			pre.Storage[slot] = common.HexToHash(value.From)
+		}
+		if value.To != "" {
+			if post.Storage == nil {
+				post.Storage = make(map[common.Hash]common.Hash)
+			}
+			post.Storage[slot] = common.HexToHash(value.To)
+		}
+	}
+	return nil
+}
+
+// diffBigs decodes the quantities of a numeric diff, nil where absent.
+func diffBigs(d ParityDiff) (from, to *hexutil.Big, err error) {
+	decode := func(s string) (*hexutil.Big, error) {
+		if s == "" {
+			return nil, nil
+		}
+		n, err := hexutil.DecodeBig(s)
+		return (*hexutil.Big)(n), err
+	}
+	if from, err = decode(d.From); err != nil {
+		return nil, nil, err
+	}
+	if to, err = decode(d.To); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// TraceParityTransaction returns the call frames of a mined transaction
+// using trace_transaction.
+//
+// Note: This requires a node serving the trace namespace (e.g., Erigon,
+// Nethermind).
+func (c *RPCClient) TraceParityTransaction(ctx context.Context, txHash common.Hash) ([]ParityTrace, error) {
+	var traces []ParityTrace
+	if err := c.call(ctx, &traces, "trace_transaction", txHash); err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, nil
+	}
+	return traces, nil
+}
+
+// ReplayParityTransaction re-executes a mined transaction using
+// trace_replayTransaction and returns the requested outputs.
+func (c *RPCClient) ReplayParityTransaction(ctx context.Context, txHash common.Hash, types ...ParityTraceType) (*ParityReplay, error) {
+	if len(types) == 0 {
+		types = []ParityTraceType{ParityTraceCalls}
+	}
+	var replay *ParityReplay
+	if err := c.call(ctx, &replay, "trace_replayTransaction", txHash, types); err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// TraceParityTransaction returns the call frames of a mined transaction.
+// Like TraceTransaction, both modes fail over between endpoints.
+func (m *MultiClient) TraceParityTransaction(ctx context.Context, txHash common.Hash) ([]ParityTrace, error) {
+	return failover(ctx, m, func(c Client) ([]ParityTrace, error) {
+		p, ok := c.(ParityTracer)
+		if !ok {
+			return nil, errNoParityTraces
+		}
+		return p.TraceParityTransaction(ctx, txHash)
+	})
+}
+
+// ReplayParityTransaction re-executes a mined transaction and returns the
+// requested outputs. Both modes fail over between endpoints.
+func (m *MultiClient) ReplayParityTransaction(ctx context.Context, txHash common.Hash, types ...ParityTraceType) (*ParityReplay, error) {
+	return failover(ctx, m, func(c Client) (*ParityReplay, error) {
+		p, ok := c.(ParityTracer)
+		if !ok {
+			return nil, errNoParityTraces
+		}
+		return p.ReplayParityTransaction(ctx, txHash, types...)
+	})
+}
+
+// TraceParityTransaction returns the call frames of a mined transaction.
+// Traces are not cached.
+func (c *CachingClient) TraceParityTransaction(ctx context.Context, txHash common.Hash) ([]ParityTrace, error) {
+	p, ok := c.inner.(ParityTracer)
+	if !ok {
+		return nil, errNoParityTraces
+	}
+	return p.TraceParityTransaction(ctx, txHash)
+}
+
+// ReplayParityTransaction re-executes a mined transaction and returns the
+// requested outputs.
+func (c *CachingClient) ReplayParityTransaction(ctx context.Context, txHash common.Hash, types ...ParityTraceType) (*ParityReplay, error) {
+	p, ok := c.inner.(ParityTracer)
+	if !ok {
+		return nil, errNoParityTraces
+	}
+	return p.ReplayParityTransaction(ctx, txHash, types...)
+}
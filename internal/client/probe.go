@@ -0,0 +1,357 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// NodeInfoTTL is how long a probe result is trusted before commands stop
+// relying on it.
+const NodeInfoTTL = 24 * time.Hour
+
+// ErrNotProbeable is returned when probing a client that is not backed by a
+// JSON-RPC endpoint, e.g. a ChaindataClient.
+var ErrNotProbeable = errors.New("not a JSON-RPC endpoint")
+
+// Prober is implemented by clients that can report what their endpoint
+// supports.
+type Prober interface {
+	// ClientVersion returns the node's web3_clientVersion.
+	ClientVersion(ctx context.Context) (string, error)
+
+	// SyncProgress returns the sync status, or nil if the node is in sync.
+	SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error)
+
+	// HasMethod reports whether the endpoint serves method, by calling it
+	// with args. Any answer other than "method not found" counts.
+	HasMethod(ctx context.Context, method string, args ...interface{}) (bool, error)
+
+	// HasState reports whether the state of block number is available.
+	HasState(ctx context.Context, number uint64) (bool, error)
+}
+
+// NodeInfo describes the capabilities of an endpoint.
+//
+// Endpoint URLs may carry API keys, in their path as well as their
+// credentials, so saved results identify the endpoint by a hash of its URL
+// only; Endpoint is the display name and is not saved.
+type NodeInfo struct {
+	Endpoint      string                 `json:"-"`
+	EndpointHash  string                 `json:"endpointHash"`
+	ProbedAt      time.Time              `json:"probedAt"`
+	ClientVersion string                 `json:"clientVersion"`
+	ChainID       *big.Int               `json:"chainId"`
+	LatestBlock   uint64                 `json:"latestBlock"`
+	Sync          *ethereum.SyncProgress `json:"sync,omitempty"` // nil when in sync
+	Namespaces    map[string]bool        `json:"namespaces"`
+	OldestState   *uint64                `json:"oldestState,omitempty"` // nil if no state is served
+	BlockReceipts bool                   `json:"blockReceipts"`         // eth_getBlockReceipts
+	Errors        map[string]string      `json:"errors,omitempty"`      // probes that failed
+}
+
+// Archive reports whether the endpoint serves state back to genesis.
+func (n *NodeInfo) Archive() bool {
+	return n.OldestState != nil && *n.OldestState == 0
+}
+
+// Fresh reports whether the probe result is recent enough to rely on.
+func (n *NodeInfo) Fresh() bool {
+	return time.Since(n.ProbedAt) < NodeInfoTTL
+}
+
+// namespaceProbes lists a cheap call per JSON-RPC namespace. Calls about a
+// zero hash fail fast with "not found" where the namespace exists.
+var namespaceProbes = []struct {
+	namespace string
+	method    string
+	args      []interface{}
+}{
+	{"eth", "eth_blockNumber", nil},
+	{"net", "net_version", nil},
+	{"web3", "web3_clientVersion", nil},
+	{"debug", "debug_traceTransaction", []interface{}{common.Hash{}}},
+	{"trace", "trace_transaction", []interface{}{common.Hash{}}},
+	{"txpool", "txpool_status", nil},
+}
+
+// ProbedNamespaces returns the JSON-RPC namespaces checked by Probe.
+func ProbedNamespaces() []string {
+	namespaces := make([]string, 0, len(namespaceProbes))
+	for _, probe := range namespaceProbes {
+		namespaces = append(namespaces, probe.namespace)
+	}
+	return namespaces
+}
+
+// ProbeTargets returns the endpoints behind c that can be probed: each
+// endpoint of a MultiClient, or c itself. Decorators such as CachingClient
+// are looked through.
+func ProbeTargets(c Client) []Endpoint {
+	for {
+		u, ok := c.(interface{ Unwrap() Client })
+		if !ok {
+			break
+		}
+		c = u.Unwrap()
+	}
+	switch c := c.(type) {
+	case *MultiClient:
+		return c.Endpoints()
+	case *RPCClient:
+		return []Endpoint{{Name: RedactURL(c.rpcURL), Client: c}}
+	}
+	return []Endpoint{{Name: fmt.Sprintf("%T", c), Client: c}}
+}
+
+// Probe reports the capabilities of an endpoint. Individual probes that
+// fail are recorded in NodeInfo.Errors rather than failing the whole probe;
+// only an unreachable endpoint is an error.
+func Probe(ctx context.Context, e Endpoint) (*NodeInfo, error) {
+	p, ok := e.Client.(Prober)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", e.Name, ErrNotProbeable)
+	}
+
+	info := &NodeInfo{
+		Endpoint:     e.Name,
+		EndpointHash: endpointHash(e),
+		ProbedAt:     time.Now().UTC(),
+		Namespaces:   make(map[string]bool),
+		Errors:       make(map[string]string),
+	}
+	fail := func(probe string, err error) {
+		info.Errors[probe] = err.Error()
+	}
+
+	latest, err := e.Client.GetBlockHeader(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	info.LatestBlock = latest.Number.Uint64()
+
+	if info.ClientVersion, err = p.ClientVersion(ctx); err != nil {
+		fail("clientVersion", err)
+	}
+	if info.ChainID, err = e.Client.ChainID(ctx); err != nil {
+		fail("chainId", err)
+	}
+	if info.Sync, err = p.SyncProgress(ctx); err != nil {
+		fail("syncing", err)
+	}
+	for _, probe := range namespaceProbes {
+		ok, err := p.HasMethod(ctx, probe.method, probe.args...)
+		if err != nil {
+			fail(probe.namespace, err)
+			continue
+		}
+		info.Namespaces[probe.namespace] = ok
+	}
+	if info.BlockReceipts, err = p.HasMethod(ctx, "eth_getBlockReceipts", "earliest"); err != nil {
+		fail("blockReceipts", err)
+	}
+	if info.OldestState, err = oldestState(ctx, p, info.LatestBlock); err != nil {
+		fail("oldestState", err)
+	}
+	return info, ctx.Err()
+}
+
+// oldestState finds the oldest block of the contiguous range of blocks up to
+// the head whose state is served.
+//
+// The search starts at the head and steps back in doubling strides before
+// bisecting, since state outside that range does not make a node an
+// archive: hash-scheme full nodes keep the genesis state, and the state of
+// blocks flushed at shutdowns. Genesis only counts if the whole range is
+// served. A flushed state that happens to fall on a stride is still taken
+// for part of the range, making the result too old.
+func oldestState(ctx context.Context, p Prober, latest uint64) (*uint64, error) {
+	if ok, err := p.HasState(ctx, latest); err != nil || !ok {
+		return nil, err
+	}
+	lo, hi := uint64(0), latest // no state at lo unless the search reaches it, state at hi
+	for stride := uint64(1); hi > 1; stride *= 2 {
+		next := uint64(1)
+		if hi > stride+1 {
+			next = hi - stride
+		}
+		ok, err := p.HasState(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			lo = next
+			break
+		}
+		hi = next
+	}
+	if hi <= 1 {
+		ok, err := p.HasState(ctx, 0)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			hi = 0
+		}
+		return &hi, nil
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		ok, err := p.HasState(ctx, mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return &hi, nil
+}
+
+// ClientVersion returns the node's web3_clientVersion.
+func (c *RPCClient) ClientVersion(ctx context.Context) (string, error) {
+	var version string
+	err := c.call(ctx, &version, "web3_clientVersion")
+	return version, err
+}
+
+// SyncProgress returns the sync status, or nil if the node is in sync.
+func (c *RPCClient) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
+	var progress *ethereum.SyncProgress
+	err := c.do(ctx, "eth_syncing", func(ctx context.Context) (err error) {
+		progress, err = c.client.SyncProgress(ctx)
+		return err
+	})
+	return progress, err
+}
+
+// HasMethod reports whether the endpoint serves method, by calling it with
+// args. Any answer other than "method not found" counts, including errors
+// about the arguments.
+func (c *RPCClient) HasMethod(ctx context.Context, method string, args ...interface{}) (bool, error) {
+	var raw json.RawMessage
+	err := c.call(ctx, &raw, method, args...)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrMethodUnsupported):
+		return false, nil
+	case errors.Is(err, ErrTransport), errors.Is(err, ErrRateLimited),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false, err
+	}
+	return true, nil
+}
+
+// HasState reports whether the state of block number is available, by
+// reading the zero address's balance at that block.
+func (c *RPCClient) HasState(ctx context.Context, number uint64) (bool, error) {
+	var balance hexutil.Big
+	err := c.call(ctx, &balance, "eth_getBalance", common.Address{}, hexutil.Uint64(number))
+	if err == nil {
+		return true, nil
+	}
+	if isMissingState(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// isMissingState reports whether err says the requested state has been
+// pruned or was never stored. Clients word this differently.
+func isMissingState(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"missing trie node",
+		"state not available",
+		"state is not available",
+		"historical state",
+		"pruned",
+		"state histories",
+		"no state",
+		"required historical state unavailable",
+		"not available in this node",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultNodeInfoDir returns the default location of saved probe results,
+// nodes in the getho user config directory. It is kept apart from the cache
+// directory so that clearing the cache or evicting from it keeps them.
+func DefaultNodeInfoDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "getho", "nodes"), nil
+}
+
+// endpointHash identifies an endpoint by a hash of its full URL, which
+// tells apart endpoints differing only in an API key or path.
+func endpointHash(e Endpoint) string {
+	id := e.Name
+	for c := e.Client; c != nil; {
+		if rc, ok := c.(*RPCClient); ok {
+			id = rc.rpcURL
+			break
+		}
+		u, ok := c.(interface{ Unwrap() Client })
+		if !ok {
+			break
+		}
+		c = u.Unwrap()
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// nodeInfoPath returns the file of a probe result under dir.
+func nodeInfoPath(dir, hash string) string {
+	return filepath.Join(dir, hash[:16]+".json")
+}
+
+// LoadNodeInfo returns the saved probe result of an endpoint, if any.
+func LoadNodeInfo(dir string, e Endpoint) (*NodeInfo, bool) {
+	hash := endpointHash(e)
+	data, err := os.ReadFile(nodeInfoPath(dir, hash))
+	if err != nil {
+		return nil, false
+	}
+	info := new(NodeInfo)
+	if err := json.Unmarshal(data, info); err != nil || info.EndpointHash != hash {
+		return nil, false
+	}
+	info.Endpoint = e.Name
+	return info, true
+}
+
+// SaveNodeInfo saves a probe result under dir.
+func SaveNodeInfo(dir string, info *NodeInfo) error {
+	path := nodeInfoPath(dir, info.EndpointHash)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
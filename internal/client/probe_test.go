@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// fakeProber serves the state of the blocks for which state returns true.
+type fakeProber struct {
+	state func(number uint64) bool
+	err   error // returned by HasState, if set
+	calls int
+}
+
+func (p *fakeProber) ClientVersion(ctx context.Context) (string, error) { return "fake", nil }
+
+func (p *fakeProber) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
+	return nil, nil
+}
+
+func (p *fakeProber) HasMethod(ctx context.Context, method string, args ...interface{}) (bool, error) {
+	return true, nil
+}
+
+func (p *fakeProber) HasState(ctx context.Context, number uint64) (bool, error) {
+	p.calls++
+	if p.err != nil {
+		return false, p.err
+	}
+	return p.state(number), nil
+}
+
+// formatOldest formats the result of oldestState.
+func formatOldest(n *uint64) string {
+	if n == nil {
+		return "none"
+	}
+	return strconv.FormatUint(*n, 10)
+}
+
+func TestOldestState(t *testing.T) {
+	var (
+		archive = func(uint64) bool { return true }
+		none    = func(uint64) bool { return false }
+		// above returns state from block n on.
+		above = func(n uint64) func(uint64) bool {
+			return func(number uint64) bool { return number >= n }
+		}
+		// genesisAnd returns state from block n on, and that of genesis,
+		// which a hash-scheme node never prunes.
+		genesisAnd = func(n uint64) func(uint64) bool {
+			return func(number uint64) bool { return number == 0 || number >= n }
+		}
+	)
+	tests := []struct {
+		name   string
+		latest uint64
+		state  func(uint64) bool
+		want   int64 // -1 for no state
+	}{
+		{"archive", 21_000_000, archive, 0},
+		{"archive at genesis", 0, archive, 0},
+		{"archive of two blocks", 1, archive, 0},
+		{"pruned below N", 21_000_000, above(20_999_872), 20_999_872},
+		{"pruned far back", 21_000_000, above(15_537_394), 15_537_394},
+		{"state of the head only", 21_000_000, above(21_000_000), 21_000_000},
+		{"genesis and recent state", 21_000_000, genesisAnd(20_999_873), 20_999_873},
+		{"genesis and the head", 21_000_000, genesisAnd(21_000_000), 21_000_000},
+		{"genesis and everything but block 1", 1_000, genesisAnd(2), 2},
+		{"no state", 21_000_000, none, -1},
+	}
+	for _, tt := range tests {
+		p := &fakeProber{state: tt.state}
+		got, err := oldestState(context.Background(), p, tt.latest)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		switch {
+		case tt.want < 0 && got != nil:
+			t.Errorf("%s: oldest state %d, want none", tt.name, *got)
+		case tt.want >= 0 && (got == nil || *got != uint64(tt.want)):
+			t.Errorf("%s: oldest state %s, want %d", tt.name, formatOldest(got), tt.want)
+		}
+		// Strides and bisection take two probes per bit of the head number.
+		if p.calls > 2*64 {
+			t.Errorf("%s: %d probes", tt.name, p.calls)
+		}
+	}
+
+	errDown := errors.New("endpoint down")
+	if _, err := oldestState(context.Background(), &fakeProber{state: archive, err: errDown}, 100); !errors.Is(err, errDown) {
+		t.Errorf("err = %v, want %v", err, errDown)
+	}
+}
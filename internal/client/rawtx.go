@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// RawTransactionFetcher is implemented by clients that can return the
+// binary encoding of a transaction, for transaction types that go-ethereum's
+// types.Transaction cannot represent (e.g. EIP-7702 set-code transactions).
+type RawTransactionFetcher interface {
+	// GetRawTransaction returns the binary encoding of a transaction
+	// (eth_getRawTransactionByHash). Returns nil, nil if the transaction is
+	// not found.
+	GetRawTransaction(ctx context.Context, txHash common.Hash) ([]byte, error)
+}
+
+// errNoRawTransactions is returned for clients that do not implement
+// RawTransactionFetcher.
+var errNoRawTransactions = &RPCError{
+	Method: "eth_getRawTransactionByHash",
+	Kind:   ErrMethodUnsupported,
+	Err:    errors.New("client does not serve raw transactions"),
+}
+
+// GetRawTransaction returns the binary encoding of a transaction through c.
+// Returns nil, nil if the transaction is not found.
+func GetRawTransaction(ctx context.Context, c Client, txHash common.Hash) ([]byte, error) {
+	f, ok := c.(RawTransactionFetcher)
+	if !ok {
+		return nil, errNoRawTransactions
+	}
+	return f.GetRawTransaction(ctx, txHash)
+}
+
+// GetRawTransaction returns the binary encoding of a transaction using
+// eth_getRawTransactionByHash.
+func (c *RPCClient) GetRawTransaction(ctx context.Context, txHash common.Hash) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := c.call(ctx, &raw, "eth_getRawTransactionByHash", txHash); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return raw, nil
+}
+
+// GetRawTransaction returns the binary encoding of a transaction. Both
+// modes fail over between endpoints.
+func (m *MultiClient) GetRawTransaction(ctx context.Context, txHash common.Hash) ([]byte, error) {
+	return failover(ctx, m, func(c Client) ([]byte, error) {
+		return GetRawTransaction(ctx, c, txHash)
+	})
+}
+
+// GetRawTransaction returns the binary encoding of a transaction. Raw
+// transactions are not cached.
+func (c *CachingClient) GetRawTransaction(ctx context.Context, txHash common.Hash) ([]byte, error) {
+	return GetRawTransaction(ctx, c.inner, txHash)
+}
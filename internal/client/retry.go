@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how RPCClient retries temporary failures (rate
+// limiting, transport errors and nodes that are still catching up).
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero
+	// disables retrying.
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+
+	// Multiplier grows the delay after each retry.
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to this fraction (0-1) in either
+	// direction, so that concurrent clients do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the retry policy used when none is configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// backoff returns the delay before the given retry (starting at 1).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1) //nolint:gosec // jitter needs no crypto randomness
+	}
+	return time.Duration(delay)
+}
+
+// shouldRetry reports whether a classified error is worth retrying.
+func shouldRetry(err error) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Temporary
+}
+
+// rateLimiter spaces requests so that at most a fixed number are sent per
+// second.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter creates a limiter allowing perSecond requests per second.
+// A non-positive rate disables limiting and returns nil.
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next request may be sent or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, slot.Sub(now))
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
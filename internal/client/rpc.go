@@ -2,50 +2,250 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/luckify/getho/internal/chain"
 )
 
 // RPCClient is a JSON-RPC based implementation of the Client interface.
 //
 // It connects to an Ethereum node via standard JSON-RPC endpoints and
 // provides execution-layer transaction inspection capabilities.
+//
+// Errors returned by its methods are classified as *RPCError values (see
+// ErrNotFound, ErrRateLimited, ErrMethodUnsupported and ErrTransport), and
+// temporary failures are retried according to its RetryPolicy.
 type RPCClient struct {
-	client *ethclient.Client
-	rpcURL string
+	client    *ethclient.Client
+	rpc       *rpc.Client
+	rpcURL    string
+	transport Transport
+	retry     RetryPolicy
+	limiter   *rateLimiter
+	secrets   []string // credentials hidden in error messages
+	chain     *chain.Chain
+
+	telemetry *Telemetry // nil if requests are not recorded
+	endpoint  string     // name of the endpoint in telemetry
+
+	noBlockReceipts atomic.Bool // set once eth_getBlockReceipts proved unsupported
+}
+
+// RPCOptions configures an RPCClient.
+type RPCOptions struct {
+	// Retry controls retrying of temporary failures.
+	Retry RetryPolicy
+
+	// RateLimit caps the number of requests sent per second. Zero disables
+	// client-side rate limiting.
+	RateLimit float64
+
+	// Transport, if set, carries HTTP requests instead of
+	// http.DefaultTransport (e.g. a Recorder or Replayer). It is only
+	// supported for HTTP endpoints.
+	Transport http.RoundTripper
+
+	// Auth, if set, holds credentials sent with every request.
+	Auth *Auth
+
+	// Telemetry, if set, records every attempt of a JSON-RPC request under
+	// the endpoint's redacted URL.
+	Telemetry *Telemetry
+}
+
+// Transport identifies how an RPCClient reaches its endpoint.
+type Transport string
+
+const (
+	TransportHTTP      Transport = "http"   // http:// and https:// URLs
+	TransportWebSocket Transport = "ws"     // ws:// and wss:// URLs
+	TransportIPC       Transport = "ipc"    // geth IPC socket paths
+	TransportInProc    Transport = "inproc" // in-process servers, e.g. of a SimClient
+)
+
+// ParseTransport returns the transport used for an endpoint, which is either
+// a URL or the filesystem path of a geth IPC socket.
+func ParseTransport(rpcURL string) (Transport, error) {
+	u, err := url.Parse(rpcURL)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return TransportHTTP, nil
+	case "ws", "wss":
+		return TransportWebSocket, nil
+	case "":
+		return TransportIPC, nil
+	default:
+		return "", fmt.Errorf("unsupported endpoint scheme %q (expected http, https, ws, wss or an IPC socket path)", u.Scheme)
+	}
+}
+
+// DefaultRPCOptions returns the options used by NewRPCClient.
+func DefaultRPCOptions() RPCOptions {
+	return RPCOptions{Retry: DefaultRetryPolicy}
 }
 
 // NewRPCClient creates a new RPC client connected to the specified RPC endpoint.
 //
-// The endpoint should be a full URL (e.g., "http://localhost:8545" or
-// "https://eth-mainnet.g.alchemy.com/v2/YOUR_KEY").
+// The endpoint should be a full URL (e.g., "http://localhost:8545",
+// "wss://eth-mainnet.g.alchemy.com/v2/YOUR_KEY") or the path of a geth IPC
+// socket (e.g., "~/.ethereum/geth.ipc"). WebSocket and IPC endpoints are
+// connected eagerly and support subscriptions.
 func NewRPCClient(ctx context.Context, rpcURL string) (*RPCClient, error) {
+	return NewRPCClientWithOptions(ctx, rpcURL, DefaultRPCOptions())
+}
+
+// NewRPCClientWithOptions creates a new RPC client connected to the specified
+// RPC endpoint, configured by opts.
+func NewRPCClientWithOptions(ctx context.Context, rpcURL string, opts RPCOptions) (*RPCClient, error) {
 	if rpcURL == "" {
 		return nil, errors.New("RPC URL cannot be empty")
 	}
 
-	client, err := ethclient.DialContext(ctx, rpcURL)
+	transport, err := ParseTransport(rpcURL)
 	if err != nil {
 		return nil, err
 	}
+	if transport == TransportIPC {
+		if rpcURL, err = expandHome(rpcURL); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(rpcURL); err != nil {
+			return nil, fmt.Errorf("IPC endpoint unavailable: %w", err)
+		}
+	}
+
+	if opts.Transport != nil && transport != TransportHTTP {
+		return nil, fmt.Errorf("custom HTTP transport requires an HTTP endpoint, got %s", transport)
+	}
+	if !opts.Auth.empty() && transport == TransportIPC {
+		return nil, errors.New("authentication requires an HTTP or WebSocket endpoint")
+	}
+	authOpts, secrets, err := opts.Auth.options()
+	if err != nil {
+		return nil, err
+	}
+	secrets = append(secrets, urlSecrets(rpcURL)...)
+
+	var dialOpts []rpc.ClientOption
+	httpTransport := opts.Transport
+	if opts.Telemetry != nil && transport == TransportHTTP {
+		httpTransport = opts.Telemetry.Transport(RedactURL(rpcURL), httpTransport, secrets)
+	}
+	if httpTransport != nil {
+		dialOpts = append(dialOpts, rpc.WithHTTPClient(&http.Client{Transport: httpTransport}))
+	}
+	dialOpts = append(dialOpts, authOpts...)
+	rpcClient, err := rpc.DialOptions(ctx, rpcURL, dialOpts...)
+	if err != nil {
+		return nil, redact(err, secrets)
+	}
 
 	return &RPCClient{
-		client: client,
-		rpcURL: rpcURL,
+		client:    ethclient.NewClient(rpcClient),
+		rpc:       rpcClient,
+		rpcURL:    rpcURL,
+		transport: transport,
+		retry:     opts.Retry,
+		limiter:   newRateLimiter(opts.RateLimit),
+		secrets:   secrets,
+		telemetry: opts.Telemetry,
+		endpoint:  RedactURL(rpcURL),
 	}, nil
 }
 
+// expandHome expands a leading "~/" in path to the user's home directory.
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[2:]), nil
+}
+
+// Transport returns the transport used to reach the endpoint.
+func (c *RPCClient) Transport() Transport {
+	return c.transport
+}
+
+// Instrument records the requests sent from now on in t under the given
+// endpoint name. It must not be called while requests are in flight.
+func (c *RPCClient) Instrument(t *Telemetry, endpoint string) {
+	c.telemetry, c.endpoint = t, endpoint
+}
+
+// do runs a single JSON-RPC interaction, applying rate limiting, error
+// classification and the retry policy. Credentials are redacted from the
+// returned error.
+func (c *RPCClient) do(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	for retry := 0; ; retry++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		err := classifyError(method, c.attempt(ctx, method, retry, fn))
+		if err == nil || !shouldRetry(err) || retry >= c.retry.MaxRetries {
+			return redact(err, c.secrets)
+		}
+		if err := sleep(ctx, c.retry.backoff(retry+1)); err != nil {
+			return err
+		}
+	}
+}
+
+// attempt runs fn once, recording it in the client's Telemetry as the
+// given retry of method. The HTTP transport of the Telemetry fills in the
+// method and sizes of the bodies actually exchanged.
+func (c *RPCClient) attempt(ctx context.Context, method string, retry int, fn func(ctx context.Context) error) error {
+	if c.telemetry == nil {
+		return fn(ctx)
+	}
+	ex := &exchange{method: method}
+	start := time.Now()
+	err := fn(withExchange(ctx, ex))
+	c.telemetry.record(c.endpoint, ex, retry, redact(err, c.secrets), time.Since(start))
+	return err
+}
+
+// call is a retried, classified rpc.Client.CallContext.
+func (c *RPCClient) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return c.do(ctx, method, func(ctx context.Context) error {
+		return c.rpc.CallContext(ctx, result, method, args...)
+	})
+}
+
 // GetTransaction retrieves a transaction by hash.
 func (c *RPCClient) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
-	tx, isPending, err := c.client.TransactionByHash(ctx, txHash)
+	var (
+		tx        *types.Transaction
+		isPending bool
+	)
+	err := c.do(ctx, "eth_getTransactionByHash", func(ctx context.Context) (err error) {
+		tx, isPending, err = c.client.TransactionByHash(ctx, txHash)
+		return err
+	})
 	if err != nil {
 		// Check if it's a "not found" error
-		if errors.Is(err, ethereum.NotFound) {
+		if errors.Is(err, ErrNotFound) {
 			return nil, false, nil
 		}
 		return nil, false, err
@@ -55,10 +255,14 @@ func (c *RPCClient) GetTransaction(ctx context.Context, txHash common.Hash) (*ty
 
 // GetTransactionReceipt retrieves a transaction receipt by hash.
 func (c *RPCClient) GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	receipt, err := c.client.TransactionReceipt(ctx, txHash)
+	var receipt *types.Receipt
+	err := c.do(ctx, "eth_getTransactionReceipt", func(ctx context.Context) (err error) {
+		receipt, err = c.client.TransactionReceipt(ctx, txHash)
+		return err
+	})
 	if err != nil {
 		// Check if it's a "not found" error
-		if errors.Is(err, ethereum.NotFound) {
+		if errors.Is(err, ErrNotFound) {
 			return nil, nil
 		}
 		return nil, err
@@ -66,24 +270,151 @@ func (c *RPCClient) GetTransactionReceipt(ctx context.Context, txHash common.Has
 	return receipt, nil
 }
 
+// Chain returns the chain identified when the client was created by
+// NewClient, or nil.
+func (c *RPCClient) Chain() *chain.Chain {
+	return c.chain
+}
+
+// ChainID retrieves the chain ID of the connected network.
+func (c *RPCClient) ChainID(ctx context.Context) (*big.Int, error) {
+	var chainID *big.Int
+	err := c.do(ctx, "eth_chainId", func(ctx context.Context) (err error) {
+		chainID, err = c.client.ChainID(ctx)
+		return err
+	})
+	return chainID, err
+}
+
 // GetBlockHeader retrieves a block header by number.
 func (c *RPCClient) GetBlockHeader(ctx context.Context, blockNumber *big.Int) (*types.Header, error) {
-	header, err := c.client.HeaderByNumber(ctx, blockNumber)
+	var header *types.Header
+	err := c.do(ctx, "eth_getBlockByNumber", func(ctx context.Context) (err error) {
+		header, err = c.client.HeaderByNumber(ctx, blockNumber)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 	return header, nil
 }
 
-// TraceTransaction generates an execution trace for a transaction.
+// GetBlock retrieves a block with its full transactions by number, hash or
+// tag.
+func (c *RPCClient) GetBlock(ctx context.Context, ref BlockRef) (*types.Block, error) {
+	var block *types.Block
+	if ref.Hash != nil {
+		err := c.do(ctx, "eth_getBlockByHash", func(ctx context.Context) (err error) {
+			block, err = c.client.BlockByHash(ctx, *ref.Hash)
+			return err
+		})
+		return block, err
+	}
+	err := c.do(ctx, "eth_getBlockByNumber", func(ctx context.Context) (err error) {
+		block, err = c.client.BlockByNumber(ctx, ref.Number)
+		return err
+	})
+	return block, err
+}
+
+// GetBlockReceipts retrieves the receipts of a block with
+// eth_getBlockReceipts. Endpoints without that method are remembered and
+// served by fetching the receipt of each transaction concurrently instead.
+func (c *RPCClient) GetBlockReceipts(ctx context.Context, ref BlockRef) (types.Receipts, error) {
+	if !c.noBlockReceipts.Load() {
+		var receipts types.Receipts
+		err := c.do(ctx, "eth_getBlockReceipts", func(ctx context.Context) (err error) {
+			receipts, err = c.client.BlockReceipts(ctx, ref.numberOrHash())
+			return err
+		})
+		if !errors.Is(err, ErrMethodUnsupported) {
+			return receipts, err
+		}
+		c.noBlockReceipts.Store(true)
+	}
+
+	block, err := c.GetBlock(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return fetchReceipts(ctx, c, block.Transactions())
+}
+
+// GetBalance retrieves the balance of an account at a block.
+func (c *RPCClient) GetBalance(ctx context.Context, account common.Address, ref BlockRef) (*big.Int, error) {
+	var balance hexutil.Big
+	if err := c.call(ctx, &balance, "eth_getBalance", account, ref.arg()); err != nil {
+		return nil, err
+	}
+	return balance.ToInt(), nil
+}
+
+// GetNonce retrieves the nonce of an account at a block.
+func (c *RPCClient) GetNonce(ctx context.Context, account common.Address, ref BlockRef) (uint64, error) {
+	var nonce hexutil.Uint64
+	err := c.call(ctx, &nonce, "eth_getTransactionCount", account, ref.arg())
+	return uint64(nonce), err
+}
+
+// GetCode retrieves the code of an account at a block.
+func (c *RPCClient) GetCode(ctx context.Context, account common.Address, ref BlockRef) ([]byte, error) {
+	var code hexutil.Bytes
+	err := c.call(ctx, &code, "eth_getCode", account, ref.arg())
+	return code, err
+}
+
+// GetStorageAt retrieves the value of a storage slot of an account at a
+// block.
+func (c *RPCClient) GetStorageAt(ctx context.Context, account common.Address, slot common.Hash, ref BlockRef) (common.Hash, error) {
+	var value hexutil.Bytes
+	if err := c.call(ctx, &value, "eth_getStorageAt", account, slot, ref.arg()); err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(value), nil
+}
+
+// GetProof retrieves the state of an account and of the given storage
+// slots at a block, with their Merkle proofs.
+func (c *RPCClient) GetProof(ctx context.Context, account common.Address, slots []common.Hash, ref BlockRef) (*AccountProof, error) {
+	if slots == nil {
+		slots = []common.Hash{}
+	}
+	var proof *AccountProof
+	if err := c.call(ctx, &proof, "eth_getProof", account, slots, ref.arg()); err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, errBlockNotFound("eth_getProof", ref)
+	}
+	return proof, nil
+}
+
+// TraceTransaction generates an execution trace for a transaction using
+// debug_traceTransaction and the tracer selected in opts (nil selects the
+// default struct logger).
 //
 // Note: This requires a debug-enabled node (e.g., Geth with --http.api eth,debug).
-// The returned value is a raw trace result that should be parsed by the tracer package.
-func (c *RPCClient) TraceTransaction(ctx context.Context, txHash common.Hash) (interface{}, error) {
-	// For now, return an error indicating this needs to be implemented via
-	// direct RPC calls to debug_traceTransaction.
-	// We'll implement this properly when we add the tracer implementation.
-	return nil, errors.New("trace transaction not yet implemented via RPC client")
+func (c *RPCClient) TraceTransaction(ctx context.Context, txHash common.Hash, opts *TraceOptions) (*TraceResult, error) {
+	if opts == nil {
+		opts = &TraceOptions{}
+	}
+	cfg, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	if err := c.call(ctx, &raw, "debug_traceTransaction", txHash, cfg); err != nil {
+		// geth fails with an error rather than returning null
+		if strings.Contains(strings.ToLower(err.Error()), "transaction not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	return decodeTraceResult(opts.Tracer, opts.DiffMode, raw)
 }
 
 // Close closes the client connection.
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/catalyst"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+	"github.com/luckify/getho/internal/chain"
+
+	// Register the call and prestate tracers.
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+// SimURL is the endpoint name of a SimClient.
+const SimURL = "simulated"
+
+// simGenesisTime is the timestamp of the simulated genesis block,
+// 2024-01-01 00:00:00 UTC.
+const simGenesisTime = 1_704_067_200
+
+// simTip is the priority fee paid by transactions sent through the SimClient
+// helpers.
+var simTip = big.NewInt(params.GWei)
+
+// SimOptions configures a SimClient.
+type SimOptions struct {
+	// Accounts is the number of funded accounts. Zero means 4.
+	Accounts int
+
+	// Balance is the initial balance of each account. Nil means 1000 ether.
+	Balance *big.Int
+}
+
+// SimClient is a Client backed by an in-process simulated chain, for tests
+// and demos that must run without a node or network access.
+//
+// The chain is go-ethereum's own dev chain (chain ID 1337), run as
+// ethclient/simulated runs it: a node with the eth service, driven by the
+// simulated beacon client. ethclient/simulated's Backend does not expose the
+// eth service, which the debug tracing API is built on, so the node is
+// assembled here with that API registered. Reads and traces go through the
+// node's in-process JSON-RPC server, so a SimClient answers as an RPCClient
+// connected to a dev node does. The node keeps the state of every block, so
+// every transaction can be traced.
+//
+// The accounts' keys and the genesis block are the same on every run. Later
+// blocks are not: the beacon client stamps them with the wall clock time and
+// a random prevRandao.
+//
+// Sent transactions wait in the transaction pool until Mine seals a block.
+type SimClient struct {
+	*RPCClient
+
+	stack  *node.Node
+	beacon *catalyst.SimulatedBeacon
+	config *params.ChainConfig
+	keys   []*ecdsa.PrivateKey
+}
+
+// simKey returns the key of simulated account i, derived from its index.
+func simKey(i int) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("getho simulated account %d", i))))
+	if err != nil {
+		panic(err) // a keccak hash is a valid key but for a negligible chance
+	}
+	return key
+}
+
+// NewSimClient starts a simulated chain with funded accounts.
+func NewSimClient(opts SimOptions) (*SimClient, error) {
+	if opts.Accounts == 0 {
+		opts.Accounts = 4
+	}
+	if opts.Balance == nil {
+		opts.Balance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	}
+
+	keys := make([]*ecdsa.PrivateKey, opts.Accounts)
+	alloc := make(types.GenesisAlloc, opts.Accounts)
+	for i := range keys {
+		keys[i] = simKey(i)
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: opts.Balance}
+	}
+
+	nodeConf := node.DefaultConfig
+	nodeConf.DataDir = ""
+	nodeConf.P2P = p2p.Config{NoDiscovery: true}
+	stack, err := node.New(&nodeConf)
+	if err != nil {
+		return nil, err
+	}
+
+	ethConf := ethconfig.Defaults
+	ethConf.Genesis = &core.Genesis{
+		Config:    params.AllDevChainProtocolChanges,
+		Timestamp: simGenesisTime,
+		GasLimit:  ethconfig.Defaults.Miner.GasCeil,
+		Alloc:     alloc,
+	}
+	ethConf.SyncMode = downloader.FullSync
+	ethConf.TxPool.NoLocals = true
+	// Keep the state of every block, as an archive node does.
+	ethConf.NoPruning = true
+	ethConf.StateScheme = rawdb.HashScheme
+	backend, err := eth.New(stack, &ethConf)
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+	filterSystem := filters.NewFilterSystem(backend.APIBackend, filters.Config{})
+	stack.RegisterAPIs(append(tracers.APIs(backend.APIBackend), rpc.API{
+		Namespace: "eth",
+		Service:   filters.NewFilterAPI(filterSystem),
+	}))
+	if err := stack.Start(); err != nil {
+		stack.Close()
+		return nil, err
+	}
+	beacon, err := catalyst.NewSimulatedBeacon(0, backend)
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+
+	rpcClient := stack.Attach()
+	return &SimClient{
+		RPCClient: &RPCClient{
+			client:    ethclient.NewClient(rpcClient),
+			rpc:       rpcClient,
+			rpcURL:    SimURL,
+			transport: TransportInProc,
+			chain:     chain.FromConfig(backend.BlockChain().Config()),
+		},
+		stack:  stack,
+		beacon: beacon,
+		config: backend.BlockChain().Config(),
+		keys:   keys,
+	}, nil
+}
+
+// ChainConfig returns the chain configuration of the simulated chain.
+func (s *SimClient) ChainConfig() *params.ChainConfig {
+	return s.config
+}
+
+// Handler returns the JSON-RPC server of the simulated node, for serving it
+// over HTTP, e.g. with httptest, to code that dials an endpoint.
+func (s *SimClient) Handler() (http.Handler, error) {
+	return s.stack.RPCHandler()
+}
+
+// Accounts returns the funded accounts. The helpers refer to them by index.
+func (s *SimClient) Accounts() []common.Address {
+	accounts := make([]common.Address, len(s.keys))
+	for i, key := range s.keys {
+		accounts[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	return accounts
+}
+
+// Mine seals a block with the pooled transactions and returns its header.
+func (s *SimClient) Mine(ctx context.Context) (*types.Header, error) {
+	parent, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	head := s.beacon.Commit()
+	if head == parent.Hash() {
+		return nil, errors.New("failed to seal simulated block")
+	}
+	return s.client.HeaderByHash(ctx, head)
+}
+
+// SendTransaction submits a signed transaction to the transaction pool.
+// Blob transactions must carry their sidecar, which the pool verifies.
+func (s *SimClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("transaction rejected: %w", err)
+	}
+	return nil
+}
+
+// Deploy sends a contract creation transaction from account from and
+// returns it along with the address of the contract.
+func (s *SimClient) Deploy(ctx context.Context, from int, code []byte) (*types.Transaction, common.Address, error) {
+	tx, err := s.SendDynamicFee(ctx, from, nil, nil, code)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return tx, crypto.CreateAddress(crypto.PubkeyToAddress(s.keys[from].PublicKey), tx.Nonce()), nil
+}
+
+// SendLegacy sends a legacy (type 0) transaction from account from. A nil to
+// creates a contract.
+func (s *SimClient) SendLegacy(ctx context.Context, from int, to *common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	return s.send(ctx, from, to, value, data, nil, func(nonce, gas uint64, feeCap, _ *big.Int) types.TxData {
+		return &types.LegacyTx{Nonce: nonce, GasPrice: feeCap, Gas: gas, To: to, Value: value, Data: data}
+	})
+}
+
+// SendAccessList sends an EIP-2930 (type 1) transaction from account from.
+func (s *SimClient) SendAccessList(ctx context.Context, from int, to *common.Address, value *big.Int, data []byte, accessList types.AccessList) (*types.Transaction, error) {
+	return s.send(ctx, from, to, value, data, accessList, func(nonce, gas uint64, feeCap, _ *big.Int) types.TxData {
+		return &types.AccessListTx{
+			ChainID: s.config.ChainID, Nonce: nonce, GasPrice: feeCap, Gas: gas,
+			To: to, Value: value, Data: data, AccessList: accessList,
+		}
+	})
+}
+
+// SendDynamicFee sends an EIP-1559 (type 2) transaction from account from.
+func (s *SimClient) SendDynamicFee(ctx context.Context, from int, to *common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	return s.send(ctx, from, to, value, data, nil, func(nonce, gas uint64, feeCap, _ *big.Int) types.TxData {
+		return &types.DynamicFeeTx{
+			ChainID: s.config.ChainID, Nonce: nonce, GasTipCap: simTip, GasFeeCap: feeCap, Gas: gas,
+			To: to, Value: value, Data: data,
+		}
+	})
+}
+
+// SendBlob sends an EIP-4844 (type 3) transaction from account from to to,
+// carrying the given number of blobs of generated data.
+//
+// The transaction pool holds the blob transactions of an account apart from
+// its other transactions, and rejects a blob transaction from an account
+// that has others pending, and vice versa.
+func (s *SimClient) SendBlob(ctx context.Context, from int, to common.Address, blobs int) (*types.Transaction, error) {
+	if blobs < 1 || blobs > params.MaxBlobGasPerBlock/params.BlobTxBlobGasPerBlob {
+		return nil, fmt.Errorf("invalid blob count %d", blobs)
+	}
+	sidecar := new(types.BlobTxSidecar)
+	for i := 0; i < blobs; i++ {
+		var blob kzg4844.Blob
+		// Only the low byte of each 32-byte field element is set, keeping
+		// it below the BLS12-381 modulus.
+		for j := 31; j < len(blob); j += 32 {
+			blob[j] = byte(i + 1)
+		}
+		commitment, err := kzg4844.BlobToCommitment(&blob)
+		if err != nil {
+			return nil, err
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blob, commitment)
+		if err != nil {
+			return nil, err
+		}
+		sidecar.Blobs = append(sidecar.Blobs, blob)
+		sidecar.Commitments = append(sidecar.Commitments, commitment)
+		sidecar.Proofs = append(sidecar.Proofs, proof)
+	}
+
+	return s.send(ctx, from, &to, nil, nil, nil, func(nonce, gas uint64, feeCap, blobFeeCap *big.Int) types.TxData {
+		return &types.BlobTx{
+			ChainID:    uint256.MustFromBig(s.config.ChainID),
+			Nonce:      nonce,
+			GasTipCap:  uint256.MustFromBig(simTip),
+			GasFeeCap:  uint256.MustFromBig(feeCap),
+			Gas:        gas,
+			To:         to,
+			BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+			BlobHashes: sidecar.BlobHashes(),
+			Sidecar:    sidecar,
+		}
+	})
+}
+
+// send signs the transaction built by build with account from's key and
+// submits it to the transaction pool. build is passed the account's next
+// nonce, a gas limit estimated against the latest block, a fee cap of twice
+// the latest base fee plus the tip and a blob fee cap of twice the latest
+// blob base fee.
+//
+// Since gas is estimated against the latest block, a transaction calling a
+// contract deployed by a pooled transaction must wait for it to be mined.
+func (s *SimClient) send(ctx context.Context, from int, to *common.Address, value *big.Int, data []byte, accessList types.AccessList,
+	build func(nonce, gas uint64, feeCap, blobFeeCap *big.Int) types.TxData) (*types.Transaction, error) {
+	if from < 0 || from >= len(s.keys) {
+		return nil, fmt.Errorf("no simulated account %d", from)
+	}
+	key := s.keys[from]
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	nonce, err := s.client.PendingNonceAt(ctx, sender)
+	if err != nil {
+		return nil, err
+	}
+	gas, err := s.client.EstimateGas(ctx, ethereum.CallMsg{
+		From:       sender,
+		To:         to,
+		Value:      value,
+		Data:       data,
+		AccessList: accessList,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gas estimation failed: %w", err)
+	}
+	head, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), simTip)
+	blobFeeCap := new(big.Int)
+	if head.ExcessBlobGas != nil {
+		blobFeeCap = eip4844.CalcBlobFee(*head.ExcessBlobGas)
+		blobFeeCap.Mul(blobFeeCap, big.NewInt(2))
+	}
+
+	tx, err := types.SignNewTx(key, types.LatestSigner(s.config), build(nonce, gas, feeCap, blobFeeCap))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Close stops the simulated chain and discards it.
+func (s *SimClient) Close() {
+	s.RPCClient.Close()
+	_ = s.beacon.Stop()
+	_ = s.stack.Close()
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// AccountProof is the result of eth_getProof: an account's state and the
+// Merkle proofs of it and of the requested storage slots against the block's
+// state root.
+type AccountProof struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageProof  `json:"storageProof"`
+}
+
+// StorageProof is the value of a storage slot and its Merkle proof against
+// the account's storage root. Key is the slot as echoed by the node, which
+// some clients shorten to a quantity such as "0x0".
+type StorageProof struct {
+	Key   string          `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// proofList collects trie proof nodes, in order from the root.
+type proofList []hexutil.Bytes
+
+func (l *proofList) Put(_ []byte, value []byte) error {
+	*l = append(*l, common.CopyBytes(value))
+	return nil
+}
+
+func (l *proofList) Delete([]byte) error {
+	panic("not supported")
+}
+
+// proveAccount builds the eth_getProof result of account and slots from the
+// state with the given root, as geth does.
+func proveAccount(statedb *state.StateDB, root common.Hash, account common.Address, slots []common.Hash) (*AccountProof, error) {
+	triedb := statedb.Database().TrieDB()
+	result := &AccountProof{
+		Address:      account,
+		Balance:      (*hexutil.Big)(statedb.GetBalance(account).ToBig()),
+		CodeHash:     statedb.GetCodeHash(account),
+		Nonce:        hexutil.Uint64(statedb.GetNonce(account)),
+		StorageHash:  statedb.GetStorageRoot(account),
+		StorageProof: make([]StorageProof, len(slots)),
+	}
+
+	var storageTrie *trie.StateTrie
+	if result.StorageHash != types.EmptyRootHash && result.StorageHash != (common.Hash{}) {
+		id := trie.StorageTrieID(root, crypto.Keccak256Hash(account.Bytes()), result.StorageHash)
+		st, err := trie.NewStateTrie(id, triedb)
+		if err != nil {
+			return nil, err
+		}
+		storageTrie = st
+	}
+	for i, slot := range slots {
+		proof := proofList{}
+		if storageTrie != nil {
+			if err := storageTrie.Prove(crypto.Keccak256(slot.Bytes()), &proof); err != nil {
+				return nil, err
+			}
+		}
+		value := statedb.GetState(account, slot)
+		result.StorageProof[i] = StorageProof{Key: slot.Hex(), Value: (*hexutil.Big)(value.Big()), Proof: proof}
+	}
+
+	accountTrie, err := trie.NewStateTrie(trie.StateTrieID(root), triedb)
+	if err != nil {
+		return nil, err
+	}
+	proof := proofList{}
+	if err := accountTrie.Prove(crypto.Keccak256(account.Bytes()), &proof); err != nil {
+		return nil, err
+	}
+	result.AccountProof = proof
+	return result, statedb.Error()
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrSubscriptionsUnsupported is returned when subscribing through a client
+// whose endpoint cannot push notifications, e.g. plain HTTP.
+var ErrSubscriptionsUnsupported = errors.New("subscriptions require a WebSocket or IPC endpoint")
+
+// Subscriber is implemented by clients that can stream chain events.
+//
+// Events are delivered on the provided channel until the returned
+// subscription is unsubscribed or fails; its Err channel reports failures,
+// e.g. a dropped connection.
+type Subscriber interface {
+	// SubscribeNewHeads streams the header of each new chain head.
+	SubscribeNewHeads(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+
+	// SubscribeLogs streams logs matching q as they are included in blocks.
+	SubscribeLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+
+	// SubscribeNewPendingTransactions streams the hashes of transactions
+	// entering the node's transaction pool.
+	SubscribeNewPendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error)
+}
+
+// subscribable reports an error if the endpoint cannot push notifications.
+func (c *RPCClient) subscribable(method string) error {
+	if c.transport == TransportHTTP {
+		return &RPCError{Method: method, Kind: ErrMethodUnsupported, Err: ErrSubscriptionsUnsupported}
+	}
+	return nil
+}
+
+// SubscribeNewHeads streams the header of each new chain head.
+func (c *RPCClient) SubscribeNewHeads(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	if err := c.subscribable("eth_subscribe newHeads"); err != nil {
+		return nil, err
+	}
+	sub, err := c.client.SubscribeNewHead(ctx, ch)
+	return sub, redact(classifyError("eth_subscribe newHeads", err), c.secrets)
+}
+
+// SubscribeLogs streams logs matching q as they are included in blocks.
+func (c *RPCClient) SubscribeLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if err := c.subscribable("eth_subscribe logs"); err != nil {
+		return nil, err
+	}
+	sub, err := c.client.SubscribeFilterLogs(ctx, q, ch)
+	return sub, redact(classifyError("eth_subscribe logs", err), c.secrets)
+}
+
+// SubscribeNewPendingTransactions streams the hashes of transactions
+// entering the node's transaction pool.
+func (c *RPCClient) SubscribeNewPendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	if err := c.subscribable("eth_subscribe newPendingTransactions"); err != nil {
+		return nil, err
+	}
+	sub, err := c.rpc.EthSubscribe(ctx, ch, "newPendingTransactions")
+	return sub, redact(classifyError("eth_subscribe newPendingTransactions", err), c.secrets)
+}
+
+// SubscribeNewHeads subscribes through the first endpoint that supports
+// subscriptions. Subscriptions are not compared across endpoints, and a
+// failed subscription is not moved to another endpoint.
+func (m *MultiClient) SubscribeNewHeads(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return subscribeAny(m, func(s Subscriber) (ethereum.Subscription, error) {
+		return s.SubscribeNewHeads(ctx, ch)
+	})
+}
+
+// SubscribeLogs subscribes through the first endpoint that supports
+// subscriptions.
+func (m *MultiClient) SubscribeLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return subscribeAny(m, func(s Subscriber) (ethereum.Subscription, error) {
+		return s.SubscribeLogs(ctx, q, ch)
+	})
+}
+
+// SubscribeNewPendingTransactions subscribes through the first endpoint that
+// supports subscriptions.
+func (m *MultiClient) SubscribeNewPendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	return subscribeAny(m, func(s Subscriber) (ethereum.Subscription, error) {
+		return s.SubscribeNewPendingTransactions(ctx, ch)
+	})
+}
+
+// subscribeAny tries fn on each endpoint in order until one succeeds.
+func subscribeAny(m *MultiClient, fn func(Subscriber) (ethereum.Subscription, error)) (ethereum.Subscription, error) {
+	var errs []error
+	for _, e := range m.endpoints {
+		s, ok := e.Client.(Subscriber)
+		if !ok {
+			continue
+		}
+		sub, err := fn(s)
+		if err == nil {
+			return sub, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+	}
+	if len(errs) == 0 {
+		return nil, ErrSubscriptionsUnsupported
+	}
+	return nil, errors.Join(errs...)
+}
+
+// SubscribeNewHeads streams the header of each new chain head. Streamed data
+// is not cached.
+func (c *CachingClient) SubscribeNewHeads(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	s, ok := c.inner.(Subscriber)
+	if !ok {
+		return nil, ErrSubscriptionsUnsupported
+	}
+	return s.SubscribeNewHeads(ctx, ch)
+}
+
+// SubscribeLogs streams logs matching q as they are included in blocks.
+func (c *CachingClient) SubscribeLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	s, ok := c.inner.(Subscriber)
+	if !ok {
+		return nil, ErrSubscriptionsUnsupported
+	}
+	return s.SubscribeLogs(ctx, q, ch)
+}
+
+// SubscribeNewPendingTransactions streams the hashes of transactions
+// entering the node's transaction pool.
+func (c *CachingClient) SubscribeNewPendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	s, ok := c.inner.(Subscriber)
+	if !ok {
+		return nil, ErrSubscriptionsUnsupported
+	}
+	return s.SubscribeNewPendingTransactions(ctx, ch)
+}
@@ -0,0 +1,415 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets. A
+// final bucket holds everything slower.
+var LatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// debugBodyLimit caps the bytes of request or response bodies written to
+// the debug log, since traces can run to many megabytes.
+const debugBodyLimit = 64 << 10
+
+// DebugLevel selects what a Telemetry writes to its debug log.
+type DebugLevel int
+
+const (
+	DebugOff   DebugLevel = iota // nothing
+	DebugCalls                   // a line per request attempt with its latency, sizes and error
+	DebugWire                    // also the JSON-RPC bodies exchanged with HTTP endpoints
+)
+
+// Telemetry collects per-method statistics of the JSON-RPC requests sent by
+// the RPCClients it is given to (see RPCOptions.Telemetry) and, depending on
+// its debug level, logs them.
+//
+// Every attempt of a request is recorded on its own, under the JSON-RPC
+// method sent, so that retries show and latencies leave out the backoff and
+// rate limiting between attempts.
+type Telemetry struct {
+	debug io.Writer
+	level DebugLevel
+
+	mu    sync.Mutex
+	stats map[[2]string]*MethodStats
+}
+
+// NewTelemetry creates an empty Telemetry logging to debug at the given
+// level. A nil debug writer disables logging.
+func NewTelemetry(debug io.Writer, level DebugLevel) *Telemetry {
+	if debug == nil {
+		level = DebugOff
+	}
+	return &Telemetry{debug: debug, level: level, stats: make(map[[2]string]*MethodStats)}
+}
+
+// MethodStats are the statistics of one JSON-RPC method at one endpoint.
+//
+// Calls counts the attempts, Retries those of them that repeated a failed
+// attempt. Sizes are those of the HTTP request and response bodies; other
+// transports do not expose them and report zero. A batch is counted under
+// the methods it holds, e.g. "batch(eth_getTransactionByHash,eth_getTransactionReceipt)".
+type MethodStats struct {
+	Endpoint      string           `json:"endpoint"`
+	Method        string           `json:"method"`
+	Calls         uint64           `json:"calls"`
+	Retries       uint64           `json:"retries"`
+	Errors        uint64           `json:"errors"`
+	RequestBytes  uint64           `json:"requestBytes"`
+	ResponseBytes uint64           `json:"responseBytes"`
+	Latency       LatencyHistogram `json:"latency"`
+}
+
+// LatencyHistogram counts calls by latency. Counts[i] holds the calls no
+// slower than LatencyBuckets[i] (and slower than the previous bound); the
+// last count holds the rest.
+type LatencyHistogram struct {
+	Counts []uint64
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// observe adds a call of latency d.
+func (h *LatencyHistogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]uint64, len(LatencyBuckets)+1)
+	}
+	i := sort.Search(len(LatencyBuckets), func(i int) bool { return d <= LatencyBuckets[i] })
+	h.Counts[i]++
+	h.Total += d
+	if d > h.Max {
+		h.Max = d
+	}
+}
+
+// count returns the number of calls observed.
+func (h *LatencyHistogram) count() uint64 {
+	var n uint64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+// Mean returns the mean latency.
+func (h *LatencyHistogram) Mean() time.Duration {
+	n := h.count()
+	if n == 0 {
+		return 0
+	}
+	return h.Total / time.Duration(n)
+}
+
+// Quantile estimates the q-quantile (0 < q <= 1) of the latency as the upper
+// bound of the bucket it falls into, capped at the maximum.
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	n := h.count()
+	if n == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(n) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= rank && i < len(LatencyBuckets) {
+			return min(LatencyBuckets[i], h.Max)
+		}
+	}
+	return h.Max
+}
+
+// MarshalJSON encodes the histogram with latencies in milliseconds and the
+// bucket bounds alongside the counts.
+func (h LatencyHistogram) MarshalJSON() ([]byte, error) {
+	type bucket struct {
+		LE    string `json:"le"`
+		Count uint64 `json:"count"`
+	}
+	buckets := make([]bucket, 0, len(h.Counts))
+	for i, c := range h.Counts {
+		le := "+Inf"
+		if i < len(LatencyBuckets) {
+			le = LatencyBuckets[i].String()
+		}
+		buckets = append(buckets, bucket{LE: le, Count: c})
+	}
+	return json.Marshal(struct {
+		MeanMs  float64  `json:"meanMs"`
+		P50Ms   float64  `json:"p50Ms"`
+		P95Ms   float64  `json:"p95Ms"`
+		MaxMs   float64  `json:"maxMs"`
+		TotalMs float64  `json:"totalMs"`
+		Buckets []bucket `json:"buckets"`
+	}{
+		MeanMs:  milliseconds(h.Mean()),
+		P50Ms:   milliseconds(h.Quantile(0.5)),
+		P95Ms:   milliseconds(h.Quantile(0.95)),
+		MaxMs:   milliseconds(h.Max),
+		TotalMs: milliseconds(h.Total),
+		Buckets: buckets,
+	})
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Stats returns a copy of the statistics collected so far, ordered by
+// endpoint and method.
+func (t *Telemetry) Stats() []MethodStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]MethodStats, 0, len(t.stats))
+	for _, s := range t.stats {
+		copied := *s
+		copied.Latency.Counts = append([]uint64(nil), s.Latency.Counts...)
+		stats = append(stats, copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Endpoint != stats[j].Endpoint {
+			return stats[i].Endpoint < stats[j].Endpoint
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// Transport returns an http.RoundTripper that measures the JSON-RPC bodies
+// sent through base (nil uses http.DefaultTransport) for the attempt being
+// recorded, and logs them under the given endpoint name at DebugWire, with
+// secrets redacted.
+func (t *Telemetry) Transport(endpoint string, base http.RoundTripper, secrets []string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &telemetryTransport{telemetry: t, endpoint: endpoint, base: base, secrets: secrets}
+}
+
+// telemetryTransport is the http.RoundTripper of one endpoint.
+type telemetryTransport struct {
+	telemetry *Telemetry
+	endpoint  string
+	base      http.RoundTripper
+	secrets   []string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (tt *telemetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	method := requestMethod(reqBody)
+	ex := exchangeFrom(req.Context())
+	if ex != nil {
+		ex.method = method
+		ex.requestBytes += len(reqBody)
+	}
+	tt.log("-->", method, "", reqBody)
+
+	start := time.Now()
+	resp, err := tt.base.RoundTrip(req)
+	if err != nil {
+		tt.log("<--", method, redactString(err.Error(), tt.secrets), nil)
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		tt.log("<--", method, redactString(err.Error(), tt.secrets), nil)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if ex != nil {
+		ex.responseBytes += len(respBody)
+	}
+	tt.log("<--", method, fmt.Sprintf("%s in %s", resp.Status, time.Since(start).Round(time.Microsecond)), respBody)
+	return resp, nil
+}
+
+// log writes one side of an exchange to the debug log at DebugWire.
+func (tt *telemetryTransport) log(direction, method, status string, body []byte) {
+	if tt.telemetry.level < DebugWire {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(direction + " " + tt.endpoint + " " + method)
+	if status != "" {
+		b.WriteString(" " + status)
+	}
+	if body != nil {
+		b.WriteString(fmt.Sprintf(" (%d bytes)\n", len(body)))
+		b.WriteString(redactString(truncate(string(bytes.TrimSpace(body))), tt.secrets))
+	}
+	tt.telemetry.write(b.String())
+}
+
+// truncate caps text at debugBodyLimit bytes.
+func truncate(text string) string {
+	if len(text) > debugBodyLimit {
+		return fmt.Sprintf("%s... (%d more bytes)", text[:debugBodyLimit], len(text)-debugBodyLimit)
+	}
+	return text
+}
+
+// write appends a line to the debug log.
+func (t *Telemetry) write(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = io.WriteString(t.debug, line+"\n")
+}
+
+// exchange collects what is known of one attempt of a JSON-RPC request:
+// its method, and the sizes of the bodies the HTTP transport sent and
+// received. Other transports do not expose their messages, so the sizes
+// stay zero for them.
+type exchange struct {
+	method        string
+	requestBytes  int
+	responseBytes int
+}
+
+type exchangeKey struct{}
+
+// withExchange returns ctx carrying ex to the transport.
+func withExchange(ctx context.Context, ex *exchange) context.Context {
+	return context.WithValue(ctx, exchangeKey{}, ex)
+}
+
+// exchangeFrom returns the exchange carried by ctx, or nil.
+func exchangeFrom(ctx context.Context) *exchange {
+	ex, _ := ctx.Value(exchangeKey{}).(*exchange)
+	return ex
+}
+
+// nameRequest sets the method recorded for the attempt running under ctx,
+// e.g. to name a batch.
+func nameRequest(ctx context.Context, method string) {
+	if ex := exchangeFrom(ctx); ex != nil {
+		ex.method = method
+	}
+}
+
+// record accounts for one attempt of a JSON-RPC request at endpoint and
+// logs it. retry is the number of attempts that preceded it.
+func (t *Telemetry) record(endpoint string, ex *exchange, retry int, err error, latency time.Duration) {
+	// ethclient reports a null result as ethereum.NotFound; the endpoint
+	// answered without an error.
+	failed := err != nil && !errors.Is(err, ethereum.NotFound)
+	t.observe(endpoint, ex, retry > 0, failed, latency)
+	if t.level < DebugCalls {
+		return
+	}
+
+	line := fmt.Sprintf("%s %s in %s", endpoint, ex.method, latency.Round(time.Microsecond))
+	if ex.requestBytes > 0 || ex.responseBytes > 0 {
+		line += fmt.Sprintf(" (%d bytes sent, %d received)", ex.requestBytes, ex.responseBytes)
+	}
+	if retry > 0 {
+		line += fmt.Sprintf(", retry %d", retry)
+	}
+	if failed {
+		line += ": " + err.Error()
+	}
+	t.write(line)
+}
+
+// observe records an attempt.
+func (t *Telemetry) observe(endpoint string, ex *exchange, retried, failed bool, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := [2]string{endpoint, ex.method}
+	s, ok := t.stats[key]
+	if !ok {
+		s = &MethodStats{Endpoint: endpoint, Method: ex.method}
+		t.stats[key] = s
+	}
+	s.Calls++
+	if retried {
+		s.Retries++
+	}
+	if failed {
+		s.Errors++
+	}
+	s.RequestBytes += uint64(ex.requestBytes)
+	s.ResponseBytes += uint64(ex.responseBytes)
+	s.Latency.observe(latency)
+}
+
+// requestMethod names the JSON-RPC method of a request body, listing the
+// methods of a batch, e.g. "batch(eth_getTransactionByHash,eth_getTransactionReceipt)".
+func requestMethod(body []byte) string {
+	msgs, batch, err := parseMessages(body)
+	if err != nil {
+		return "unknown"
+	}
+	if !batch {
+		return msgs[0].Method
+	}
+	methods := make([]string, len(msgs))
+	for i, msg := range msgs {
+		methods[i] = msg.Method
+	}
+	return batchMethod(methods)
+}
+
+// batchMethod names a batch of requests of the given methods.
+func batchMethod(methods []string) string {
+	seen := make(map[string]bool)
+	var distinct []string
+	for _, method := range methods {
+		if !seen[method] {
+			seen[method] = true
+			distinct = append(distinct, method)
+		}
+	}
+	sort.Strings(distinct)
+	return "batch(" + strings.Join(distinct, ",") + ")"
+}
+
+// redactString replaces every occurrence of secrets in s.
+func redactString(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "xxxxx")
+		}
+	}
+	return s
+}
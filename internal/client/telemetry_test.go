@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// roundTripperFunc adapts a function to http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTelemetryReplayClient returns a client answering from the cassette at
+// path through transport (nil uses the replayer itself), recording in
+// telemetry.
+func newTelemetryReplayClient(t *testing.T, path string, telemetry *Telemetry, transport func(*Replayer) http.RoundTripper) *RPCClient {
+	t.Helper()
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultRPCOptions()
+	opts.Retry = RetryPolicy{MaxRetries: 1, InitialBackoff: time.Millisecond}
+	opts.Transport = replayer
+	if transport != nil {
+		opts.Transport = transport(replayer)
+	}
+	opts.Telemetry = telemetry
+	c, err := NewRPCClientWithOptions(context.Background(), ReplayURL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestTelemetry(t *testing.T) {
+	var log bytes.Buffer
+	telemetry := NewTelemetry(&log, DebugCalls)
+	c := newTelemetryReplayClient(t, transferCassette, telemetry, nil)
+	ctx := context.Background()
+
+	bundle, err := GetTransactionBundle(ctx, c, transferTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTransferBundle(t, bundle)
+	if _, err := c.ChainID(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetTransactionReceipt(ctx, common.Hash{1}); err == nil {
+		t.Fatal("unrecorded call succeeded")
+	}
+
+	stats := telemetry.Stats()
+	want := []struct {
+		method        string
+		calls, errors uint64
+	}{
+		{"batch(eth_getTransactionByHash,eth_getTransactionReceipt)", 1, 0},
+		{"eth_chainId", 1, 0},
+		{"eth_getBlockByHash", 1, 0},
+		{"eth_getTransactionReceipt", 1, 1},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got stats of %d methods, want %d: %+v", len(stats), len(want), stats)
+	}
+	for i, w := range want {
+		s := stats[i]
+		if s.Endpoint != ReplayURL || s.Method != w.method || s.Calls != w.calls || s.Errors != w.errors || s.Retries != 0 {
+			t.Errorf("stats[%d] = %s %s %d calls %d retries %d errors, want %s %s %d calls 0 retries %d errors",
+				i, s.Endpoint, s.Method, s.Calls, s.Retries, s.Errors, ReplayURL, w.method, w.calls, w.errors)
+		}
+		if s.RequestBytes == 0 || s.ResponseBytes == 0 {
+			t.Errorf("stats[%d] = %+v, want body sizes", i, s)
+		}
+	}
+	if lines := strings.Count(log.String(), "\n"); lines != 4 {
+		t.Errorf("debug log has %d lines, want 4:\n%s", lines, log.String())
+	}
+}
+
+func TestTelemetryWireSizes(t *testing.T) {
+	telemetry := NewTelemetry(nil, DebugOff)
+	var sent, received atomic.Int64
+	c := newTelemetryReplayClient(t, transferCassette, telemetry, func(r *Replayer) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent.Add(req.ContentLength)
+			resp, err := r.RoundTrip(req)
+			if err == nil {
+				received.Add(resp.ContentLength)
+			}
+			return resp, err
+		})
+	})
+	if _, err := c.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := telemetry.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("got stats of %d methods, want 1: %+v", len(stats), stats)
+	}
+	if s := stats[0]; int64(s.RequestBytes) != sent.Load() || int64(s.ResponseBytes) != received.Load() {
+		t.Errorf("recorded %d bytes sent and %d received, the transport saw %d and %d",
+			s.RequestBytes, s.ResponseBytes, sent.Load(), received.Load())
+	}
+}
+
+func TestTelemetryRetries(t *testing.T) {
+	var log bytes.Buffer
+	telemetry := NewTelemetry(&log, DebugCalls)
+	var failed atomic.Bool
+	c := newTelemetryReplayClient(t, transferCassette, telemetry, func(r *Replayer) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if failed.CompareAndSwap(false, true) {
+				return &http.Response{
+					Status:     "503 Service Unavailable",
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       io.NopCloser(strings.NewReader("busy")),
+					Request:    req,
+				}, nil
+			}
+			return r.RoundTrip(req)
+		})
+	})
+	if _, err := c.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := telemetry.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("got stats of %d methods, want 1: %+v", len(stats), stats)
+	}
+	if s := stats[0]; s.Method != "eth_chainId" || s.Calls != 2 || s.Retries != 1 || s.Errors != 1 {
+		t.Errorf("stats = %s %d calls %d retries %d errors, want eth_chainId 2 calls 1 retry 1 error",
+			s.Method, s.Calls, s.Retries, s.Errors)
+	}
+	if !strings.Contains(log.String(), "retry 1") {
+		t.Errorf("debug log does not show the retry:\n%s", log.String())
+	}
+}
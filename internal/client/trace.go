@@ -0,0 +1,208 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// TracerType selects the geth tracer used by debug_traceTransaction.
+type TracerType string
+
+const (
+	// TracerStructLog is geth's default opcode-level struct logger.
+	TracerStructLog TracerType = ""
+	// TracerCall is geth's native call tracer, producing a call tree.
+	TracerCall TracerType = "callTracer"
+	// TracerPrestate is geth's native prestate tracer, producing touched state.
+	TracerPrestate TracerType = "prestateTracer"
+)
+
+// DefaultTraceTimeout is the tracer timeout used when none is configured.
+// It matches geth's own default for debug_traceTransaction.
+const DefaultTraceTimeout = 5 * time.Second
+
+// TraceOptions configures a single debug_traceTransaction call.
+type TraceOptions struct {
+	// Tracer selects which geth tracer to run.
+	Tracer TracerType
+
+	// Timeout bounds how long the node may spend tracing. Zero uses
+	// DefaultTraceTimeout.
+	Timeout time.Duration
+
+	// Struct logger options (ignored by native tracers).
+	EnableMemory     bool
+	DisableStack     bool
+	DisableStorage   bool
+	EnableReturnData bool
+
+	// Call tracer options.
+	OnlyTopCall bool // do not descend into sub-calls
+	WithLog     bool // include emitted logs in each frame
+
+	// Prestate tracer options.
+	DiffMode bool // return pre/post state diff instead of plain prestate
+}
+
+// traceConfig is the JSON shape of geth's tracers.TraceConfig.
+type traceConfig struct {
+	Tracer           string          `json:"tracer,omitempty"`
+	Timeout          string          `json:"timeout,omitempty"`
+	TracerConfig     json.RawMessage `json:"tracerConfig,omitempty"`
+	EnableMemory     bool            `json:"enableMemory,omitempty"`
+	DisableStack     bool            `json:"disableStack,omitempty"`
+	DisableStorage   bool            `json:"disableStorage,omitempty"`
+	EnableReturnData bool            `json:"enableReturnData,omitempty"`
+}
+
+// config converts the options into the request payload understood by geth.
+func (o *TraceOptions) config() (*traceConfig, error) {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTraceTimeout
+	}
+	cfg := &traceConfig{
+		Tracer:  string(o.Tracer),
+		Timeout: timeout.String(),
+	}
+
+	var tracerConfig interface{}
+	switch o.Tracer {
+	case TracerStructLog:
+		cfg.EnableMemory = o.EnableMemory
+		cfg.DisableStack = o.DisableStack
+		cfg.DisableStorage = o.DisableStorage
+		cfg.EnableReturnData = o.EnableReturnData
+	case TracerCall:
+		if o.OnlyTopCall || o.WithLog {
+			tracerConfig = struct {
+				OnlyTopCall bool `json:"onlyTopCall,omitempty"`
+				WithLog     bool `json:"withLog,omitempty"`
+			}{o.OnlyTopCall, o.WithLog}
+		}
+	case TracerPrestate:
+		if o.DiffMode {
+			tracerConfig = struct {
+				DiffMode bool `json:"diffMode"`
+			}{true}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported tracer %q", o.Tracer)
+	}
+
+	if tracerConfig != nil {
+		raw, err := json.Marshal(tracerConfig)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TracerConfig = raw
+	}
+	return cfg, nil
+}
+
+// TraceResult holds the typed output of debug_traceTransaction.
+//
+// Exactly one of the result fields is populated, according to Tracer.
+type TraceResult struct {
+	Tracer TracerType
+
+	StructLogs *StructLogTrace // TracerStructLog
+	Call       *CallTrace      // TracerCall
+	Prestate   *PrestateTrace  // TracerPrestate
+}
+
+// StructLogTrace is the result of geth's default struct logger.
+type StructLogTrace struct {
+	Gas         uint64      `json:"gas"`
+	Failed      bool        `json:"failed"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []StructLog `json:"structLogs"`
+}
+
+// StructLog is a single executed opcode as reported by the struct logger.
+type StructLog struct {
+	PC      uint64            `json:"pc"`
+	Op      string            `json:"op"`
+	Gas     uint64            `json:"gas"`
+	GasCost uint64            `json:"gasCost"`
+	Depth   int               `json:"depth"`
+	Error   string            `json:"error,omitempty"`
+	Stack   []string          `json:"stack,omitempty"`
+	Memory  []string          `json:"memory,omitempty"`
+	Storage map[string]string `json:"storage,omitempty"`
+	Refund  uint64            `json:"refund,omitempty"`
+}
+
+// CallTrace is a single frame of geth's call tracer output. The root frame
+// describes the transaction itself; nested frames are in Calls.
+type CallTrace struct {
+	Type         string          `json:"type"`
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to,omitempty"`
+	Value        *hexutil.Big    `json:"value,omitempty"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Calls        []CallTrace     `json:"calls,omitempty"`
+	Logs         []CallLog       `json:"logs,omitempty"`
+}
+
+// CallLog is a log emitted within a call tracer frame (requires WithLog).
+type CallLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// PrestateAccount is the state of a single account as reported by the
+// prestate tracer.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// PrestateTrace is the result of geth's prestate tracer. In plain mode only
+// Pre is populated; in diff mode both Pre and Post are.
+type PrestateTrace struct {
+	Pre  map[common.Address]*PrestateAccount `json:"pre"`
+	Post map[common.Address]*PrestateAccount `json:"post,omitempty"`
+}
+
+// decodeTraceResult decodes raw debug_traceTransaction output for the given
+// tracer into a TraceResult.
+func decodeTraceResult(tracer TracerType, diffMode bool, raw json.RawMessage) (*TraceResult, error) {
+	result := &TraceResult{Tracer: tracer}
+	switch tracer {
+	case TracerStructLog:
+		result.StructLogs = new(StructLogTrace)
+		if err := json.Unmarshal(raw, result.StructLogs); err != nil {
+			return nil, fmt.Errorf("failed to decode struct logs: %w", err)
+		}
+	case TracerCall:
+		result.Call = new(CallTrace)
+		if err := json.Unmarshal(raw, result.Call); err != nil {
+			return nil, fmt.Errorf("failed to decode call trace: %w", err)
+		}
+	case TracerPrestate:
+		result.Prestate = new(PrestateTrace)
+		if diffMode {
+			if err := json.Unmarshal(raw, result.Prestate); err != nil {
+				return nil, fmt.Errorf("failed to decode prestate diff: %w", err)
+			}
+		} else if err := json.Unmarshal(raw, &result.Prestate.Pre); err != nil {
+			return nil, fmt.Errorf("failed to decode prestate: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported tracer %q", tracer)
+	}
+	return result, nil
+}
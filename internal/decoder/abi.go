@@ -0,0 +1,121 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ABISet holds the contract ABIs calldata is decoded with: either one ABI
+// for every contract, or ABIs keyed by contract address.
+type ABISet struct {
+	shared    *abi.ABI
+	byAddress map[common.Address]*abi.ABI
+	names     map[*abi.ABI]string // file each ABI was loaded from
+}
+
+// LoadABIs loads the ABI JSON file at path, which then applies to every
+// contract, or the ABI directory at path, whose files are named after the
+// address of their contract, e.g. 0xA0b8...eB48.json.
+//
+// Files may hold a bare ABI array or a compiler artifact with an "abi"
+// field, as written by Hardhat, Foundry and Truffle.
+func LoadABIs(path string) (*ABISet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	set := &ABISet{byAddress: make(map[common.Address]*abi.ABI), names: make(map[*abi.ABI]string)}
+	if !info.IsDir() {
+		contract, err := LoadABI(path)
+		if err != nil {
+			return nil, err
+		}
+		set.shared = contract
+		set.names[contract] = filepath.Base(path)
+		return set, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		address := strings.TrimSuffix(name, filepath.Ext(name))
+		if entry.IsDir() || filepath.Ext(name) != ".json" || !common.IsHexAddress(address) {
+			continue
+		}
+		contract, err := LoadABI(filepath.Join(path, name))
+		if err != nil {
+			return nil, err
+		}
+		set.byAddress[common.HexToAddress(address)] = contract
+		set.names[contract] = name
+	}
+	if len(set.byAddress) == 0 {
+		return nil, fmt.Errorf("%s: no ABI files named after a contract address", path)
+	}
+	return set, nil
+}
+
+// LoadABI reads a contract ABI from a JSON file.
+func LoadABI(path string) (*abi.ABI, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if err := json.Unmarshal(data, &artifact); err != nil {
+			return nil, fmt.Errorf("%s: invalid ABI: %w", path, err)
+		}
+		if artifact.ABI == nil {
+			return nil, fmt.Errorf("%s: invalid ABI: no \"abi\" field", path)
+		}
+		data = artifact.ABI
+	}
+	contract, err := abi.JSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid ABI: %w", path, err)
+	}
+	return &contract, nil
+}
+
+// Lookup returns the ABI to decode a call with the given selector to
+// address (nil if unknown) with, and the file it was loaded from: the ABI
+// of the address if there is one, else any ABI defining the selector.
+// Returns nil if no ABI applies.
+func (s *ABISet) Lookup(address *common.Address, selector []byte) (*abi.ABI, string) {
+	if s.shared != nil {
+		return s.shared, s.names[s.shared]
+	}
+	if address != nil {
+		if contract, ok := s.byAddress[*address]; ok {
+			return contract, s.names[contract]
+		}
+	}
+	// Fall back to the ABIs of other contracts, in a stable order
+	addresses := make([]common.Address, 0, len(s.byAddress))
+	for a := range s.byAddress {
+		addresses = append(addresses, a)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return bytes.Compare(addresses[i][:], addresses[j][:]) < 0 })
+	for _, a := range addresses {
+		contract := s.byAddress[a]
+		if _, err := contract.MethodById(selector); err == nil {
+			return contract, s.names[contract]
+		}
+	}
+	return nil, ""
+}
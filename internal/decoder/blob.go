@@ -0,0 +1,150 @@
+package decoder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// BlobBaseFee returns the blob base fee of a block with the given excess
+// blob gas under the blob parameters of its fork.
+func BlobBaseFee(excessBlobGas uint64, config chain.BlobConfig) *big.Int {
+	return fakeExponential(big.NewInt(params.BlobTxMinBlobGasprice), new(big.Int).SetUint64(excessBlobGas), new(big.Int).SetUint64(config.UpdateFraction))
+}
+
+// fakeExponential approximates factor * e ** (numerator / denominator) as
+// specified by EIP-4844.
+func fakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	output := new(big.Int)
+	accum := new(big.Int).Mul(factor, denominator)
+	for i := int64(1); accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+		accum.Mul(accum, numerator)
+		accum.Div(accum, denominator)
+		accum.Div(accum, big.NewInt(i))
+	}
+	return output.Div(output, denominator)
+}
+
+// BlobSidecar is the verification of the sidecar of a blob transaction.
+type BlobSidecar struct {
+	// Blobs has one entry per versioned hash of the transaction, in order.
+	Blobs []BlobCheck
+}
+
+// BlobCheck is the verification of the blob of one versioned hash.
+type BlobCheck struct {
+	VersionedHash string // 0x-prefixed
+	Commitment    string // KZG commitment (0x-prefixed), empty if the sidecar holds no blob for the hash
+
+	// Error says why the blob failed verification; empty if it passed.
+	Error string
+}
+
+// Valid reports whether the blobs of every versioned hash were found and
+// verified.
+func (s *BlobSidecar) Valid() bool {
+	for _, blob := range s.Blobs {
+		if blob.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifySidecar checks sc against the versioned hashes of t and records the
+// result in t.Sidecar. For each versioned hash the sidecar must hold a
+// commitment hashing to it, and the KZG proof must verify the blob against
+// that commitment. Blobs of the sidecar that t does not reference are
+// ignored, so the sidecars of a whole block may be given.
+func (t *Transaction) VerifySidecar(sc *types.BlobTxSidecar) error {
+	if t.Type != TransactionTypeBlob {
+		return errors.New("not a blob transaction")
+	}
+	if len(sc.Commitments) != len(sc.Blobs) || len(sc.Proofs) != len(sc.Blobs) {
+		return fmt.Errorf("invalid sidecar: %d blobs, %d commitments and %d proofs", len(sc.Blobs), len(sc.Commitments), len(sc.Proofs))
+	}
+
+	hasher := sha256.New()
+	byHash := make(map[common.Hash]int, len(sc.Commitments))
+	for i := range sc.Commitments {
+		byHash[kzg4844.CalcBlobHashV1(hasher, &sc.Commitments[i])] = i
+	}
+
+	result := &BlobSidecar{Blobs: make([]BlobCheck, len(t.BlobHashes))}
+	for i, hex := range t.BlobHashes {
+		check := BlobCheck{VersionedHash: hex}
+		hash := common.HexToHash(hex)
+		j, ok := byHash[hash]
+		switch {
+		case !kzg4844.IsValidVersionedHash(hash[:]):
+			check.Error = "unsupported versioned hash version"
+		case !ok:
+			check.Error = "no blob with this versioned hash in the sidecar"
+		default:
+			check.Commitment = fmt.Sprintf("%#x", sc.Commitments[j][:])
+			if err := kzg4844.VerifyBlobProof(&sc.Blobs[j], sc.Commitments[j], sc.Proofs[j]); err != nil {
+				check.Error = "invalid KZG proof: " + err.Error()
+			}
+		}
+		result.Blobs[i] = check
+	}
+	t.Sidecar = result
+	return nil
+}
+
+// ParseSidecar parses a blob sidecar file: either go-ethereum's form, an
+// object with "blobs", "commitments" and "proofs" arrays, or the blob
+// sidecars of a block as served by the beacon API
+// (/eth/v1/beacon/blob_sidecars/{block_id}), with or without the enclosing
+// "data" object.
+func ParseSidecar(data []byte) (*types.BlobTxSidecar, error) {
+	var beacon []beaconBlobSidecar
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &beacon); err != nil {
+			return nil, fmt.Errorf("invalid sidecar: %w", err)
+		}
+	} else {
+		var file struct {
+			Data        []beaconBlobSidecar  `json:"data"`
+			Blobs       []kzg4844.Blob       `json:"blobs"`
+			Commitments []kzg4844.Commitment `json:"commitments"`
+			Proofs      []kzg4844.Proof      `json:"proofs"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid sidecar: %w", err)
+		}
+		if file.Data == nil {
+			return &types.BlobTxSidecar{Blobs: file.Blobs, Commitments: file.Commitments, Proofs: file.Proofs}, nil
+		}
+		beacon = file.Data
+	}
+
+	sc := &types.BlobTxSidecar{
+		Blobs:       make([]kzg4844.Blob, len(beacon)),
+		Commitments: make([]kzg4844.Commitment, len(beacon)),
+		Proofs:      make([]kzg4844.Proof, len(beacon)),
+	}
+	for i, b := range beacon {
+		sc.Blobs[i], sc.Commitments[i], sc.Proofs[i] = b.Blob, b.Commitment, b.Proof
+	}
+	return sc, nil
+}
+
+// beaconBlobSidecar is the subset of a beacon API blob sidecar that
+// verification needs.
+type beaconBlobSidecar struct {
+	Blob       kzg4844.Blob       `json:"blob"`
+	Commitment kzg4844.Commitment `json:"kzg_commitment"`
+	Proof      kzg4844.Proof      `json:"kzg_proof"`
+}
@@ -0,0 +1,258 @@
+package decoder
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// Cases from go-ethereum's eip4844 tests.
+func TestFakeExponential(t *testing.T) {
+	tests := []struct {
+		factor, numerator, denominator int64
+		want                           int64
+	}{
+		{1, 0, 1, 1},
+		{38493, 0, 1000, 38493},
+		{0, 1234, 2345, 0},
+		{1, 2, 1, 6},
+		{1, 4, 2, 6},
+		{1, 3, 1, 16},
+		{1, 6, 2, 18},
+		{1, 4, 1, 49},
+		{1, 8, 2, 50},
+		{10, 8, 2, 542},
+		{11, 8, 2, 596},
+		{1, 5, 1, 136},
+		{1, 5, 2, 11},
+		{2, 5, 2, 23},
+		{1, 50000000, 2225652, 5709098764},
+	}
+	for _, tt := range tests {
+		got := fakeExponential(big.NewInt(tt.factor), big.NewInt(tt.numerator), big.NewInt(tt.denominator))
+		if got.Int64() != tt.want {
+			t.Errorf("fakeExponential(%d, %d, %d) = %v, want %d", tt.factor, tt.numerator, tt.denominator, got, tt.want)
+		}
+	}
+}
+
+func TestBlobBaseFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		config chain.BlobConfig
+		excess uint64
+		want   int64
+	}{
+		{"cancun zero", chain.BlobCancun, 0, 1},
+		{"cancun below 2 wei", chain.BlobCancun, 2314057, 1},
+		{"cancun at 2 wei", chain.BlobCancun, 2314058, 2},
+		{"cancun 10 MiB", chain.BlobCancun, 10 * 1024 * 1024, 23},
+		{"cancun 100M", chain.BlobCancun, 100_000_000, 10203769476395},
+		{"prague below 2 wei", chain.BlobPrague, 3471086, 1},
+		{"prague at 2 wei", chain.BlobPrague, 3471087, 2},
+		{"prague 10 MiB", chain.BlobPrague, 10 * 1024 * 1024, 8},
+		{"prague 100M", chain.BlobPrague, 100_000_000, 470442149},
+		{"bpo1 below 2 wei", chain.BlobBPO1, 5785141, 1},
+		{"bpo1 at 2 wei", chain.BlobBPO1, 5785142, 2},
+		{"bpo1 100M", chain.BlobBPO1, 100_000_000, 159773},
+		{"bpo2 below 2 wei", chain.BlobBPO2, 8099198, 1},
+		{"bpo2 at 2 wei", chain.BlobBPO2, 8099199, 2},
+		{"bpo2 100M", chain.BlobBPO2, 100_000_000, 5209},
+	}
+	for _, tt := range tests {
+		if got := BlobBaseFee(tt.excess, tt.config); got.Int64() != tt.want {
+			t.Errorf("%s: BlobBaseFee(%d) = %v, want %d", tt.name, tt.excess, got, tt.want)
+		}
+	}
+
+	// go-ethereum's calculation only knows Cancun's update fraction.
+	for _, excess := range []uint64{0, 131072, 2314058, 45_000_000, 393_216_000} {
+		if got, want := BlobBaseFee(excess, chain.BlobCancun), eip4844.CalcBlobFee(excess); got.Cmp(want) != 0 {
+			t.Errorf("BlobBaseFee(%d) = %v, go-ethereum computes %v", excess, got, want)
+		}
+	}
+}
+
+func TestBlobBaseFeeSource(t *testing.T) {
+	tx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Gas:        21000,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(100),
+		BlobFeeCap: uint256.NewInt(1_000_000),
+		BlobHashes: []common.Hash{{0x01}},
+	})
+	excess := uint64(100_000_000)
+	tests := []struct {
+		name    string
+		time    uint64
+		receipt *types.Receipt
+		want    int64
+	}{
+		{"cancun header", 1710338135, nil, 10203769476395},
+		{"prague header", 1746612311, nil, 470442149},
+		{"bpo1 header", 1765290071, nil, 159773},
+		{"bpo2 header", 1767747671, nil, 5209},
+		{"receipt", 1767747671, &types.Receipt{BlobGasUsed: 131072, BlobGasPrice: big.NewInt(7)}, 7},
+	}
+	for _, tt := range tests {
+		header := &types.Header{Number: big.NewInt(24_000_000), Time: tt.time, ExcessBlobGas: &excess}
+		decoded, err := NewEthereumDecoderForChain(chain.Mainnet).FromGoEthereumTransaction(tx, tt.receipt, header, common.Address{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if decoded.BlobGasFeeCap.Int64() != 1_000_000 || decoded.MaxFeePerBlobGas.Int64() != 1_000_000 {
+			t.Errorf("%s: blob fee cap %v, max fee per blob gas %v, want 1000000", tt.name, decoded.BlobGasFeeCap, decoded.MaxFeePerBlobGas)
+		}
+		if decoded.BlobBaseFee == nil || decoded.BlobBaseFee.Int64() != tt.want {
+			t.Errorf("%s: blob base fee %v, want %d", tt.name, decoded.BlobBaseFee, tt.want)
+		}
+		if want := tt.want * 131072; decoded.BlobFee.Int64() != want {
+			t.Errorf("%s: blob fee %v, want %d", tt.name, decoded.BlobFee, want)
+		}
+	}
+}
+
+// newTestSidecar returns a sidecar of n distinct blobs with their
+// commitments and proofs.
+func newTestSidecar(t *testing.T, n int) *types.BlobTxSidecar {
+	t.Helper()
+	sc := new(types.BlobTxSidecar)
+	for i := 0; i < n; i++ {
+		var blob kzg4844.Blob
+		// Only the low byte of each field element is set, keeping it below
+		// the BLS12-381 modulus. The elements differ, so that the blob is
+		// not a constant polynomial, whose proofs would all be the same.
+		for j := 31; j < len(blob); j += 32 {
+			blob[j] = byte((j/32 + 1) * (i + 1))
+		}
+		commitment, err := kzg4844.BlobToCommitment(&blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blob, commitment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc.Blobs = append(sc.Blobs, blob)
+		sc.Commitments = append(sc.Commitments, commitment)
+		sc.Proofs = append(sc.Proofs, proof)
+	}
+	return sc
+}
+
+func TestVerifySidecar(t *testing.T) {
+	sc := newTestSidecar(t, 2)
+	hashes := sc.BlobHashes()
+	unknown := hashes[0]
+	unknown[31] ^= 1
+	unversioned := hashes[0]
+	unversioned[0] = 0
+
+	tampered := newTestSidecar(t, 2)
+	tampered.Proofs[0], tampered.Proofs[1] = tampered.Proofs[1], tampered.Proofs[0]
+
+	tests := []struct {
+		name    string
+		sidecar *types.BlobTxSidecar
+		hashes  []common.Hash
+		errors  []string // prefix of each blob's error, empty if it verifies
+	}{
+		{"valid", sc, hashes, []string{"", ""}},
+		{"subset of the sidecar", sc, hashes[1:], []string{""}},
+		{"tampered proof", tampered, hashes, []string{"invalid KZG proof", "invalid KZG proof"}},
+		{"mismatched versioned hash", sc, []common.Hash{hashes[0], unknown}, []string{"", "no blob with this versioned hash"}},
+		{"unversioned hash", sc, []common.Hash{unversioned}, []string{"unsupported versioned hash version"}},
+	}
+	for _, tt := range tests {
+		tx := &Transaction{Type: TransactionTypeBlob}
+		for _, hash := range tt.hashes {
+			tx.BlobHashes = append(tx.BlobHashes, hash.Hex())
+		}
+		if err := tx.VerifySidecar(tt.sidecar); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(tx.Sidecar.Blobs) != len(tt.errors) {
+			t.Fatalf("%s: %d blob checks, want %d", tt.name, len(tx.Sidecar.Blobs), len(tt.errors))
+		}
+		valid := true
+		for i, check := range tx.Sidecar.Blobs {
+			if check.VersionedHash != tt.hashes[i].Hex() {
+				t.Errorf("%s: blob %d versioned hash %s, want %s", tt.name, i, check.VersionedHash, tt.hashes[i].Hex())
+			}
+			if !strings.HasPrefix(check.Error, tt.errors[i]) || (tt.errors[i] == "") != (check.Error == "") {
+				t.Errorf("%s: blob %d error %q, want %q", tt.name, i, check.Error, tt.errors[i])
+			}
+			valid = valid && tt.errors[i] == ""
+		}
+		if tx.Sidecar.Valid() != valid {
+			t.Errorf("%s: valid = %v, want %v", tt.name, tx.Sidecar.Valid(), valid)
+		}
+	}
+
+	short := &types.BlobTxSidecar{Blobs: sc.Blobs, Commitments: sc.Commitments, Proofs: sc.Proofs[:1]}
+	if err := (&Transaction{Type: TransactionTypeBlob}).VerifySidecar(short); err == nil {
+		t.Error("sidecar with a missing proof verified")
+	}
+	if err := (&Transaction{Type: TransactionTypeDynamicFee}).VerifySidecar(sc); err == nil {
+		t.Error("sidecar of a non-blob transaction verified")
+	}
+}
+
+func TestParseSidecar(t *testing.T) {
+	sc := newTestSidecar(t, 2)
+	geth, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var beacon []map[string]interface{}
+	for i := range sc.Blobs {
+		beacon = append(beacon, map[string]interface{}{
+			"index":          strconv.Itoa(i),
+			"blob":           hexutil.Bytes(sc.Blobs[i][:]),
+			"kzg_commitment": hexutil.Bytes(sc.Commitments[i][:]),
+			"kzg_proof":      hexutil.Bytes(sc.Proofs[i][:]),
+		})
+	}
+	beaconList, err := json.Marshal(beacon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	beaconResponse, err := json.Marshal(map[string]interface{}{"data": beacon})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"geth":            geth,
+		"beacon list":     beaconList,
+		"beacon response": beaconResponse,
+	} {
+		parsed, err := ParseSidecar(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(parsed.Blobs) != 2 || parsed.Blobs[1] != sc.Blobs[1] || parsed.Commitments[1] != sc.Commitments[1] || parsed.Proofs[1] != sc.Proofs[1] {
+			t.Errorf("%s: parsed sidecar differs", name)
+			continue
+		}
+		tx := &Transaction{Type: TransactionTypeBlob, BlobHashes: []string{sc.BlobHashes()[1].Hex()}}
+		if err := tx.VerifySidecar(parsed); err != nil || !tx.Sidecar.Valid() {
+			t.Errorf("%s: parsed sidecar does not verify: %v %+v", name, err, tx.Sidecar)
+		}
+	}
+
+	if _, err := ParseSidecar([]byte(`{"blobs": "0x00"}`)); err == nil {
+		t.Error("malformed sidecar parsed")
+	}
+}
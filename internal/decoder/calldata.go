@@ -0,0 +1,306 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// WithABI returns a copy of the decoder that decodes calldata against the
+// functions of contract.
+func (d *EthereumDecoder) WithABI(contract *abi.ABI) *EthereumDecoder {
+	copied := *d
+	copied.abi = contract
+	return &copied
+}
+
+// DecodeCalldata decodes the function selector and, if the decoder has an
+// ABI defining the function, its arguments.
+//
+// Calldata that does not match the ABI is not an error: Calldata.Error and
+// ErrorOffset tell where decoding failed, and the arguments decoded up to
+// that point are kept.
+func (d *EthereumDecoder) DecodeCalldata(calldata []byte) (*Calldata, error) {
+	result := &Calldata{Raw: calldata, Unknown: true}
+	if len(calldata) == 0 {
+		return result, nil
+	}
+	if len(calldata) < 4 {
+		result.Error = fmt.Sprintf("calldata of %d bytes is shorter than a function selector", len(calldata))
+		result.ErrorOffset = len(calldata)
+		return result, nil
+	}
+	result.Selector = hexutil.Encode(calldata[:4])
+	if d.abi == nil {
+		return result, nil
+	}
+	method, err := d.abi.MethodById(calldata[:4])
+	if err != nil {
+		result.Error = "no function with selector " + result.Selector + " in the ABI"
+		return result, nil
+	}
+	result.FunctionName = method.Name
+	result.Signature = method.Sig
+
+	inputs := make([]abi.Type, len(method.Inputs))
+	names := make([]string, len(method.Inputs))
+	for i, input := range method.Inputs {
+		inputs[i], names[i] = input.Type, input.Name
+	}
+	r := &abiReader{data: calldata, end: 4}
+	result.Arguments, err = r.sequence(4, inputs, names)
+	if err != nil {
+		var e *abiError
+		if !errors.As(err, &e) {
+			return nil, err
+		}
+		result.Error = e.msg
+		result.ErrorOffset = e.offset
+		return result, nil
+	}
+	result.Unknown = false
+	result.TrailingBytes = max(0, len(calldata)-r.end)
+	return result, nil
+}
+
+// abiError is a decoding failure at a byte offset into the calldata.
+type abiError struct {
+	offset int
+	msg    string
+}
+
+func (e *abiError) Error() string {
+	return fmt.Sprintf("%s (at byte %d)", e.msg, e.offset)
+}
+
+// abiReader decodes ABI-encoded values, rejecting the encodings Solidity's
+// decoder rejects: out-of-bounds offsets and lengths, and words with dirty
+// bits beyond the value of their type.
+type abiReader struct {
+	data []byte // the whole calldata, so that offsets are absolute
+	end  int    // end of the encoding read so far, including padding
+}
+
+// fail records err on arg and returns it.
+func fail(arg *Argument, offset int, format string, a ...interface{}) error {
+	err := &abiError{offset: offset, msg: fmt.Sprintf(format, a...)}
+	arg.Error = err.msg
+	return err
+}
+
+// word returns the 32-byte word at offset.
+func (r *abiReader) word(arg *Argument, offset int) ([]byte, error) {
+	if offset >= len(r.data) {
+		return nil, fail(arg, offset, "word is past the end of the calldata (%d bytes)", len(r.data))
+	}
+	if offset+32 > len(r.data) {
+		return nil, fail(arg, offset, "calldata ends %d bytes into the word", len(r.data)-offset)
+	}
+	r.end = max(r.end, offset+32)
+	return r.data[offset : offset+32], nil
+}
+
+// uint reads the word at offset as an offset or length, which must point
+// into the calldata.
+func (r *abiReader) uint(arg *Argument, offset int, what string) (int, error) {
+	w, err := r.word(arg, offset)
+	if err != nil {
+		return 0, err
+	}
+	v := new(big.Int).SetBytes(w)
+	if !v.IsInt64() || v.Int64() > int64(len(r.data)) {
+		return 0, fail(arg, offset, "%s %s exceeds the calldata size of %d bytes", what, v, len(r.data))
+	}
+	return int(v.Int64()), nil
+}
+
+// sequence decodes a tuple of values whose head starts at base: static
+// values in place, dynamic ones at an offset from base stored in the head.
+func (r *abiReader) sequence(base int, elems []abi.Type, names []string) ([]Argument, error) {
+	args := make([]Argument, 0, len(elems))
+	head := base
+	for i, t := range elems {
+		arg := Argument{Name: names[i], Type: t.String(), Offset: head}
+		var err error
+		if isDynamic(t) {
+			var offset int
+			offset, err = r.uint(&arg, head, "offset")
+			switch {
+			case err != nil:
+			case base+offset >= len(r.data):
+				err = fail(&arg, head, "offset %d points past the end of the calldata (%d bytes)", offset, len(r.data))
+			default:
+				arg.Offset = base + offset
+				err = r.value(&arg, base+offset, t)
+			}
+			head += 32
+		} else {
+			err = r.value(&arg, head, t)
+			head += headSize(t)
+		}
+		args = append(args, arg)
+		if err != nil {
+			return args, err
+		}
+	}
+	return args, nil
+}
+
+// value decodes the value of type t at offset into arg.
+func (r *abiReader) value(arg *Argument, offset int, t abi.Type) error {
+	switch t.T {
+	case abi.TupleTy:
+		elems := make([]abi.Type, len(t.TupleElems))
+		names := make([]string, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			elems[i], names[i] = *elem, t.TupleRawNames[i]
+		}
+		fields, err := r.sequence(offset, elems, names)
+		arg.Value = fields
+		return err
+
+	case abi.ArrayTy:
+		return r.elements(arg, offset, *t.Elem, t.Size)
+
+	case abi.SliceTy:
+		n, err := r.uint(arg, offset, "length")
+		if err != nil {
+			return err
+		}
+		// Elements take at least a word each
+		if n*headSize(*t.Elem) > len(r.data)-offset-32 {
+			return fail(arg, offset, "length %d exceeds the calldata size of %d bytes", n, len(r.data))
+		}
+		return r.elements(arg, offset+32, *t.Elem, n)
+
+	case abi.StringTy, abi.BytesTy:
+		n, err := r.uint(arg, offset, "length")
+		if err != nil {
+			return err
+		}
+		if offset+32+n > len(r.data) {
+			return fail(arg, offset, "length %d exceeds the calldata size of %d bytes", n, len(r.data))
+		}
+		data := r.data[offset+32 : offset+32+n]
+		r.end = max(r.end, offset+32+(n+31)/32*32)
+		if t.T == abi.StringTy {
+			arg.Value = string(data)
+		} else {
+			arg.Value = append([]byte(nil), data...)
+		}
+		return nil
+	}
+
+	w, err := r.word(arg, offset)
+	if err != nil {
+		return err
+	}
+	switch t.T {
+	case abi.UintTy:
+		v := new(big.Int).SetBytes(w)
+		if v.BitLen() > t.Size {
+			return fail(arg, offset, "value exceeds %s", t)
+		}
+		arg.Value = v
+	case abi.IntTy:
+		v := new(big.Int).SetBytes(w)
+		if w[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if v.Cmp(limit) >= 0 || v.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return fail(arg, offset, "value exceeds %s", t)
+		}
+		arg.Value = v
+	case abi.BoolTy:
+		if !isZero(w[:31]) || w[31] > 1 {
+			return fail(arg, offset, "bool is neither 0 nor 1")
+		}
+		arg.Value = w[31] == 1
+	case abi.AddressTy:
+		if !isZero(w[:12]) {
+			return fail(arg, offset, "address has non-zero high-order bytes")
+		}
+		arg.Value = common.BytesToAddress(w[12:])
+	case abi.FixedBytesTy, abi.HashTy:
+		size := t.Size
+		if t.T == abi.HashTy {
+			size = 32
+		}
+		if !isZero(w[size:]) {
+			return fail(arg, offset, "%s has non-zero padding", t)
+		}
+		arg.Value = append([]byte(nil), w[:size]...)
+	case abi.FunctionTy:
+		if !isZero(w[24:]) {
+			return fail(arg, offset, "function has non-zero padding")
+		}
+		arg.Value = append([]byte(nil), w[:24]...)
+	default:
+		return fail(arg, offset, "cannot decode type %s", t)
+	}
+	return nil
+}
+
+// elements decodes n elements of type elem laid out as a tuple at offset
+// into arg.
+func (r *abiReader) elements(arg *Argument, offset int, elem abi.Type, n int) error {
+	elems := make([]abi.Type, n)
+	names := make([]string, n)
+	for i := range elems {
+		elems[i], names[i] = elem, fmt.Sprintf("[%d]", i)
+	}
+	values, err := r.sequence(offset, elems, names)
+	arg.Value = values
+	return err
+}
+
+// isDynamic reports whether values of type t are encoded out of place.
+func isDynamic(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy:
+		return true
+	case abi.ArrayTy:
+		return isDynamic(*t.Elem)
+	case abi.TupleTy:
+		for _, elem := range t.TupleElems {
+			if isDynamic(*elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize returns the size of the head of type t in a tuple: the size of
+// a static value, or of the offset of a dynamic one.
+func headSize(t abi.Type) int {
+	if isDynamic(t) {
+		return 32
+	}
+	switch t.T {
+	case abi.ArrayTy:
+		return t.Size * headSize(*t.Elem)
+	case abi.TupleTy:
+		size := 0
+		for _, elem := range t.TupleElems {
+			size += headSize(*elem)
+		}
+		return size
+	}
+	return 32
+}
+
+// isZero reports whether b holds only zero bytes.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
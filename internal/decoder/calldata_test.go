@@ -0,0 +1,267 @@
+package decoder
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const roundTripABI = `[
+	{"type": "function", "name": "static", "inputs": [
+		{"name": "a", "type": "uint256"},
+		{"name": "b", "type": "int64"},
+		{"name": "c", "type": "address"},
+		{"name": "d", "type": "bool"},
+		{"name": "e", "type": "bytes4"}
+	]},
+	{"type": "function", "name": "dynamic", "inputs": [
+		{"name": "s", "type": "string"},
+		{"name": "b", "type": "bytes"},
+		{"name": "xs", "type": "uint256[]"},
+		{"name": "ys", "type": "int8[][]"}
+	]},
+	{"type": "function", "name": "nested", "inputs": [
+		{"name": "t", "type": "tuple", "components": [
+			{"name": "a", "type": "uint256"},
+			{"name": "inner", "type": "tuple", "components": [
+				{"name": "s", "type": "string"},
+				{"name": "addrs", "type": "address[]"}
+			]}
+		]},
+		{"name": "list", "type": "tuple[]", "components": [
+			{"name": "x", "type": "uint8"},
+			{"name": "y", "type": "bytes"}
+		]},
+		{"name": "pair", "type": "string[2]"},
+		{"name": "points", "type": "tuple[2]", "components": [
+			{"name": "x", "type": "int256"},
+			{"name": "y", "type": "int256"}
+		]}
+	]}
+]`
+
+type roundTripInner struct {
+	S     string
+	Addrs []common.Address
+}
+
+type roundTripOuter struct {
+	A     *big.Int
+	Inner roundTripInner
+}
+
+type roundTripItem struct {
+	X uint8
+	Y []byte
+}
+
+type roundTripPoint struct {
+	X *big.Int
+	Y *big.Int
+}
+
+// plain converts decoded arguments to the values of normalize.
+func plain(args []Argument) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.Value.(type) {
+		case []Argument:
+			values[i] = plain(v)
+		case *big.Int:
+			values[i] = v.String()
+		case common.Address:
+			values[i] = v.Hex()
+		case []byte:
+			values[i] = hexutil.Encode(v)
+		default:
+			values[i] = v
+		}
+	}
+	return values
+}
+
+// normalize converts the Go values abi.Pack encodes to comparable values:
+// numbers in decimal, addresses and bytes in hex, and tuples and arrays as
+// lists.
+func normalize(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch x := v.Interface().(type) {
+	case *big.Int:
+		return x.String()
+	case common.Address:
+		return x.Hex()
+	case []byte:
+		return hexutil.Encode(x)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()).String()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()).String()
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = normalize(v.Index(i))
+		}
+		return values
+	case reflect.Struct:
+		values := make([]interface{}, v.NumField())
+		for i := range values {
+			values[i] = normalize(v.Field(i))
+		}
+		return values
+	}
+	return v.Interface()
+}
+
+func TestDecodeCalldataRoundTrip(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(roundTripABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := NewEthereumDecoder().WithABI(&contract)
+
+	tests := []struct {
+		method string
+		args   []interface{}
+	}{
+		{"static", []interface{}{
+			new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)),
+			int64(-42),
+			common.HexToAddress("0x00000000000000000000000000000000deadbeef"),
+			true,
+			[4]byte{0xa9, 0x05, 0x9c, 0xbb},
+		}},
+		{"dynamic", []interface{}{
+			"hello, world",
+			[]byte{0x00, 0x01, 0x02},
+			[]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+			[][]int8{{-1, 2}, {}, {127, -128, 0}},
+		}},
+		{"dynamic", []interface{}{
+			"",
+			[]byte{},
+			[]*big.Int{},
+			[][]int8{},
+		}},
+		{"dynamic", []interface{}{
+			strings.Repeat("a long string spanning several words ", 4),
+			make([]byte, 65),
+			[]*big.Int{big.NewInt(0)},
+			[][]int8{{}},
+		}},
+		{"nested", []interface{}{
+			roundTripOuter{
+				A: big.NewInt(7),
+				Inner: roundTripInner{S: "inner", Addrs: []common.Address{
+					common.HexToAddress("0x1111111111111111111111111111111111111111"),
+					common.HexToAddress("0x2222222222222222222222222222222222222222"),
+				}},
+			},
+			[]roundTripItem{{X: 1, Y: []byte("one")}, {X: 255, Y: nil}},
+			[2]string{"left", "right"},
+			[2]roundTripPoint{{X: big.NewInt(-1), Y: big.NewInt(1)}, {X: big.NewInt(0), Y: big.NewInt(-1000)}},
+		}},
+		{"nested", []interface{}{
+			roundTripOuter{A: big.NewInt(0), Inner: roundTripInner{Addrs: []common.Address{}}},
+			[]roundTripItem{},
+			[2]string{"", ""},
+			[2]roundTripPoint{{X: big.NewInt(0), Y: big.NewInt(0)}, {X: big.NewInt(0), Y: big.NewInt(0)}},
+		}},
+	}
+
+	for _, tt := range tests {
+		data, err := contract.Pack(tt.method, tt.args...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method, err)
+		}
+		calldata, err := dec.DecodeCalldata(data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method, err)
+		}
+		if calldata.Error != "" || calldata.Unknown {
+			t.Errorf("%s: %s (at byte %d)", tt.method, calldata.Error, calldata.ErrorOffset)
+			continue
+		}
+		if calldata.Signature != contract.Methods[tt.method].Sig {
+			t.Errorf("%s: signature %s, want %s", tt.method, calldata.Signature, contract.Methods[tt.method].Sig)
+		}
+		if calldata.TrailingBytes != 0 {
+			t.Errorf("%s: %d trailing bytes", tt.method, calldata.TrailingBytes)
+		}
+		got, want := plain(calldata.Arguments), normalize(reflect.ValueOf(tt.args))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: decoded %v, want %v", tt.method, got, want)
+		}
+
+		// A truncated encoding either fails to decode or decodes the same,
+		// when only the padding of the last bytes or string was cut.
+		for n := 4; n < len(data); n++ {
+			truncated, err := dec.DecodeCalldata(data[:n])
+			if err != nil {
+				t.Fatalf("%s truncated to %d bytes: %v", tt.method, n, err)
+			}
+			if truncated.Error == "" {
+				if got := plain(truncated.Arguments); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s truncated to %d bytes: decoded %v, want %v", tt.method, n, got, want)
+				}
+				continue
+			}
+			if truncated.ErrorOffset < 4 || truncated.ErrorOffset > n {
+				t.Errorf("%s truncated to %d bytes: error offset %d out of range", tt.method, n, truncated.ErrorOffset)
+			}
+		}
+	}
+}
+
+func TestDecodeCalldataTruncated(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(roundTripABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := NewEthereumDecoder().WithABI(&contract)
+	data, err := contract.Pack("dynamic", "abc", []byte{1}, []*big.Int{big.NewInt(1), big.NewInt(2)}, [][]int8{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n      int
+		offset int
+		err    string
+	}{
+		{3, 3, "shorter than a function selector"},
+		{4 + 16, 4, "calldata ends 16 bytes into the word"},
+		{4 + 32, 4, "offset 128 exceeds the calldata size"},
+		// The string is at 4+128: its length, then its data
+		{4 + 128, 4, "points past the end of the calldata"},
+		{4 + 128 + 16, 132, "calldata ends 16 bytes into the word"},
+		{4 + 128 + 33, 132, "length 3 exceeds the calldata size"},
+		// The array of the third argument is at 4+256: its length, then
+		// its elements
+		{4 + 256 + 32 + 32, 260, "length 2 exceeds the calldata size"},
+	}
+	for _, tt := range tests {
+		calldata, err := dec.DecodeCalldata(data[:tt.n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(calldata.Error, tt.err) || calldata.ErrorOffset != tt.offset {
+			t.Errorf("truncated to %d bytes: %q at byte %d, want %q at byte %d", tt.n, calldata.Error, calldata.ErrorOffset, tt.err, tt.offset)
+		}
+	}
+}
@@ -26,7 +26,8 @@ const (
 	TransactionTypeLegacy TransactionType = iota
 	TransactionTypeAccessList
 	TransactionTypeDynamicFee
-	TransactionTypeBlob // EIP-4844
+	TransactionTypeBlob    // EIP-4844
+	TransactionTypeSetCode // EIP-7702
 )
 
 // AccessListEntry is a normalized representation of an access list item.
@@ -35,6 +36,44 @@ type AccessListEntry struct {
 	StorageKeys []string // 32-byte storage keys (0x-prefixed)
 }
 
+// Authorization is a signed EIP-7702 authorization of a set-code
+// transaction, by which Authority delegates its code to Address.
+type Authorization struct {
+	ChainID *big.Int // zero authorizes on every chain
+	Address string   // delegation target (0x-prefixed); the zero address clears a delegation
+	Nonce   uint64   // nonce of the authority the authorization is valid at
+	YParity uint8
+	R       *big.Int
+	S       *big.Int
+
+	// Authority is the recovered signer (0x-prefixed), or empty if the
+	// signature is invalid.
+	Authority string
+
+	// Invalid is the reason the authorization is skipped on execution, or
+	// empty if it applies, provided Nonce is the authority's nonce.
+	Invalid string
+}
+
+// Signature is the ECDSA signature of a transaction.
+type Signature struct {
+	V       *big.Int // as encoded: the y-parity, 27 + y-parity, or chain ID * 2 + 35 + y-parity (EIP-155)
+	R       *big.Int
+	S       *big.Int
+	YParity uint8
+
+	// Protected reports whether the signature covers a chain ID: with
+	// EIP-155 for legacy transactions, always for typed ones.
+	Protected bool
+
+	// LowS reports whether s is in the lower half of the curve order, as
+	// required since Homestead (EIP-2).
+	LowS bool
+
+	// SigningHash is the hash that was signed (0x-prefixed).
+	SigningHash string
+}
+
 // Transaction is a decoded, execution-layer oriented Ethereum transaction.
 //
 // All monetary values are represented as *big.Int to avoid precision loss.
@@ -59,16 +98,75 @@ type Transaction struct {
 	AccessList            []AccessListEntry
 	Input                 []byte // raw calldata
 	BlobGasUsed           uint64
-	BlobGasFeeCap         *big.Int // EIP-4844 blob fee cap
-	MaxFeePerBlobGas      *big.Int // EIP-4844 max fee per blob gas
-	EstimatedIntrinsicGas uint64   // intrinsic gas cost (decoded, not executed)
+	BlobGasFeeCap         *big.Int     // EIP-4844 blob fee cap
+	MaxFeePerBlobGas      *big.Int     // EIP-4844 max fee per blob gas, the same as BlobGasFeeCap
+	EstimatedIntrinsicGas uint64       // intrinsic gas cost (decoded, not executed), Intrinsic.Total
+	Intrinsic             IntrinsicGas // itemized intrinsic gas under the rules of Fork
+	Fork                  string       // hardfork whose rules apply, e.g. "Cancun"
+
+	// Signature is the sender's signature.
+	Signature *Signature
+
+	// Authorizations are the EIP-7702 authorizations of a set-code
+	// transaction.
+	Authorizations []Authorization
+
+	// Blob transaction fields (EIP-4844) beyond the gas fields above.
+	BlobHashes  []string     // versioned hashes (0x-prefixed)
+	BlobBaseFee *big.Int     // blob base fee of the containing block, nil if pending
+	BlobFee     *big.Int     // blob fee paid, nil if pending
+	Sidecar     *BlobSidecar // sidecar verification, nil without a sidecar
+}
+
+// IntrinsicGas itemizes the gas a transaction is charged before execution
+// under the rules of a fork. Counts are given alongside the gas they cost.
+type IntrinsicGas struct {
+	Base     uint64 // every transaction
+	Creation uint64 // contract creation (Homestead)
+
+	ZeroBytes    uint64
+	NonZeroBytes uint64
+	Calldata     uint64 // non-zero bytes are cheaper since Istanbul (EIP-2028)
+
+	AccessListAddresses uint64
+	AccessListKeys      uint64
+	AccessList          uint64 // EIP-2930 (Berlin)
+
+	InitcodeWords uint64
+	Initcode      uint64 // EIP-3860 (Shanghai)
+
+	Authorizations    uint64
+	AuthorizationList uint64 // EIP-7702 (Prague), as if every authority were a new account
+
+	// Total is the sum of the charges above.
+	Total uint64
+
+	// Floor is the minimum gas a transaction uses, priced by its calldata
+	// (EIP-7623, Prague). Zero before Prague.
+	Floor uint64
+}
+
+// Minimum returns the least gas limit the transaction is valid with.
+func (g IntrinsicGas) Minimum() uint64 {
+	return max(g.Total, g.Floor)
 }
 
 // Argument represents a single decoded calldata argument.
+//
+// Tuples and arrays hold their components, or elements named "[0]", "[1]",
+// ..., as a []Argument Value.
 type Argument struct {
 	Name  string      // optional best-effort name (from ABI/metadata if available)
 	Type  string      // canonical Solidity type, e.g. "uint256", "address[]"
-	Value interface{} // decoded Go value (string, *big.Int, []byte, slices, etc.)
+	Value interface{} // decoded Go value (string, *big.Int, bool, common.Address, []byte, []Argument)
+
+	// Offset is the byte offset of the value in the calldata; for dynamic
+	// types, of its data rather than of the offset pointing to it.
+	Offset int
+
+	// Error says why the value could not be decoded, on the argument where
+	// decoding failed.
+	Error string
 }
 
 // Calldata represents decoded calldata, including selector and arguments.
@@ -82,6 +180,10 @@ type Calldata struct {
 	// Optional resolved function name (best-effort from ABI or selector DB).
 	FunctionName string
 
+	// Canonical function signature, e.g. "transfer(address,uint256)", when
+	// resolved.
+	Signature string
+
 	// Decoded arguments. When ABI information is unavailable, this may be empty
 	// and Unknown will be set to true.
 	Arguments []Argument
@@ -89,4 +191,13 @@ type Calldata struct {
 	// Unknown indicates that we could not confidently decode this calldata
 	// (e.g. missing ABI or malformed input).
 	Unknown bool
+
+	// Error says why the calldata does not match the ABI, and ErrorOffset is
+	// the byte offset into Raw where decoding failed.
+	Error       string
+	ErrorOffset int
+
+	// TrailingBytes counts the bytes after the decoded arguments, which
+	// contracts ignore but an exact match would not leave.
+	TrailingBytes int
 }
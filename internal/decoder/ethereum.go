@@ -2,26 +2,135 @@ package decoder
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/luckify/getho/internal/chain"
 )
 
 // EthereumDecoder implements the Decoder interface for go-ethereum types.
-type EthereumDecoder struct{}
+type EthereumDecoder struct {
+	chain *chain.Chain // nil assumes every fork is active
+	abi   *abi.ABI     // calldata is decoded against; nil decodes selectors only
+}
+
+var _ Decoder = (*EthereumDecoder)(nil)
 
 // NewEthereumDecoder creates a new decoder for go-ethereum transaction types.
+// Transactions are interpreted under the latest fork rules; use
+// NewEthereumDecoderForChain to apply the rules of their block.
 func NewEthereumDecoder() *EthereumDecoder {
 	return &EthereumDecoder{}
 }
 
+// NewEthereumDecoderForChain creates a decoder that interprets transactions
+// under the fork rules of c at their block.
+func NewEthereumDecoderForChain(c *chain.Chain) *EthereumDecoder {
+	return &EthereumDecoder{chain: c}
+}
+
+// chainFor returns the chain a transaction with the given chain ID is
+// interpreted on: the decoder's, else the registered chain of the ID.
+func (d *EthereumDecoder) chainFor(chainID *big.Int) *chain.Chain {
+	if d.chain != nil {
+		return d.chain
+	}
+	if c, ok := chain.ByID(chainID); ok {
+		return c
+	}
+	return chain.Unknown(chainID)
+}
+
+// DecodeTransaction decodes a signed transaction in its binary encoding (see
+// ParseTransaction) and recovers its sender. EIP-7702 set-code transactions,
+// which go-ethereum cannot decode, are supported as well.
+//
+// The transaction has not necessarily been included in a block, so it is
+// interpreted under the latest fork rules of its chain.
+func (d *EthereumDecoder) DecodeTransaction(data []byte) (*Transaction, error) {
+	return d.DecodeTransactionAt(data, nil, nil)
+}
+
+// DecodeTransactionAt is like DecodeTransaction for a transaction included
+// in the block of header, with the given receipt. Both are nil for pending
+// transactions.
+func (d *EthereumDecoder) DecodeTransactionAt(data []byte, receipt *types.Receipt, header *types.Header) (*Transaction, error) {
+	envelope, err := unwrapEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	if envelope[0] == SetCodeTxType {
+		return d.decodeSetCodeTx(envelope, receipt, header)
+	}
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(envelope); err != nil {
+		return nil, fmt.Errorf("invalid transaction encoding: %w", err)
+	}
+	sender, err := d.sender(tx, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %w", err)
+	}
+	return d.FromGoEthereumTransaction(tx, receipt, header, sender)
+}
+
+// ParseTransaction decodes a signed transaction as returned by
+// eth_getRawTransactionByHash and accepted by eth_sendRawTransaction: a
+// legacy RLP list or an EIP-2718 typed envelope. Blob transactions may be in
+// their network form, with the blobs, commitments and proofs of their
+// sidecar. Typed envelopes wrapped in an RLP string, as found in block
+// bodies, are accepted too.
+func ParseTransaction(data []byte) (*types.Transaction, error) {
+	envelope, err := unwrapEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(envelope); err != nil {
+		return nil, fmt.Errorf("invalid transaction encoding: %w", err)
+	}
+	return tx, nil
+}
+
+// unwrapEnvelope returns the binary encoding of a transaction, removing the
+// RLP string wrapping typed envelopes in block bodies.
+func unwrapEnvelope(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty transaction data")
+	}
+	if data[0] < 0x80 || data[0] >= 0xc0 {
+		return data, nil
+	}
+	envelope, rest, err := rlp.SplitString(data)
+	if err == nil && len(rest) > 0 {
+		err = rlp.ErrMoreThanOneValue
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction encoding: %w", err)
+	}
+	if len(envelope) == 0 {
+		return nil, errors.New("empty transaction data")
+	}
+	return envelope, nil
+}
+
 // FromGoEthereumTransaction converts a go-ethereum types.Transaction into
 // our internal Transaction model.
-func (d *EthereumDecoder) FromGoEthereumTransaction(tx *types.Transaction, receipt *types.Receipt, from common.Address) (*Transaction, error) {
+//
+// header is the header of the containing block and selects the fork rules
+// applied; it is nil for pending transactions, which are judged by the
+// latest scheduled fork.
+func (d *EthereumDecoder) FromGoEthereumTransaction(tx *types.Transaction, receipt *types.Receipt, header *types.Header, from common.Address) (*Transaction, error) {
 	if tx == nil {
 		return nil, errors.New("transaction cannot be nil")
 	}
+	c := d.chainFor(tx.ChainId())
+	rules := c.Rules(blockOf(header))
 
 	// Get transaction hash
 	txHash := tx.Hash()
@@ -36,7 +145,7 @@ func (d *EthereumDecoder) FromGoEthereumTransaction(tx *types.Transaction, recei
 	}
 
 	// Build access list
-	accessList := d.buildAccessList(tx)
+	accessList := d.buildAccessList(tx.AccessList())
 
 	// Get value
 	value := tx.Value()
@@ -46,25 +155,30 @@ func (d *EthereumDecoder) FromGoEthereumTransaction(tx *types.Transaction, recei
 
 	// Build transaction
 	result := &Transaction{
-		Hash:                  txHash.Hex(),
-		From:                  from.Hex(),
-		To:                    d.getToAddress(tx),
-		Nonce:                 tx.Nonce(),
-		Value:                 value,
-		GasLimit:              tx.Gas(),
-		Type:                  txType,
-		ChainID:               chainID,
-		AccessList:            accessList,
-		Input:                 tx.Data(),
-		EstimatedIntrinsicGas: d.estimateIntrinsicGas(tx),
+		Hash:       txHash.Hex(),
+		From:       from.Hex(),
+		To:         d.getToAddress(tx),
+		Nonce:      tx.Nonce(),
+		Value:      value,
+		GasLimit:   tx.Gas(),
+		Type:       txType,
+		ChainID:    chainID,
+		AccessList: accessList,
+		Input:      tx.Data(),
+		Intrinsic:  d.intrinsicGas(tx.Data(), tx.To() == nil, tx.AccessList(), 0, rules),
+		Fork:       chain.ForkName(rules),
+		Signature:  signatureOf(tx),
 	}
+	result.EstimatedIntrinsicGas = result.Intrinsic.Total
 
 	// Set gas price fields based on transaction type
 	d.setGasPriceFields(result, tx, receipt)
 
 	// Set blob gas fields for EIP-4844 transactions
 	if tx.Type() == types.BlobTxType {
-		d.setBlobGasFields(result, tx, receipt)
+		if err := d.setBlobGasFields(result, tx, receipt, header, c); err != nil {
+			return nil, err
+		}
 	}
 
 	return result, nil
@@ -81,14 +195,15 @@ func (d *EthereumDecoder) determineTransactionType(tx *types.Transaction) Transa
 		return TransactionTypeDynamicFee
 	case types.BlobTxType:
 		return TransactionTypeBlob
+	case SetCodeTxType:
+		return TransactionTypeSetCode
 	default:
 		return TransactionTypeLegacy
 	}
 }
 
 // buildAccessList converts go-ethereum access list to our format.
-func (d *EthereumDecoder) buildAccessList(tx *types.Transaction) []AccessListEntry {
-	accessList := tx.AccessList()
+func (d *EthereumDecoder) buildAccessList(accessList types.AccessList) []AccessListEntry {
 	if len(accessList) == 0 {
 		return nil
 	}
@@ -135,58 +250,120 @@ func (d *EthereumDecoder) setGasPriceFields(result *Transaction, tx *types.Trans
 	}
 }
 
-// setBlobGasFields sets blob gas fields for EIP-4844 transactions.
-func (d *EthereumDecoder) setBlobGasFields(result *Transaction, _ *types.Transaction, receipt *types.Receipt) {
-	// For blob transactions, we can get blob fee cap from the transaction
-	// Note: go-ethereum v1.14.0 may have different API for blob transactions
-	// For now, we'll set these from the receipt if available
-	if receipt != nil {
+// setBlobGasFields sets blob gas fields for EIP-4844 transactions. The blob
+// base fee is the blob gas price of the receipt, or is derived from the
+// excess blob gas of the containing block under the blob schedule of c
+// without one. A sidecar carried by the transaction (network form) is
+// verified.
+func (d *EthereumDecoder) setBlobGasFields(result *Transaction, tx *types.Transaction, receipt *types.Receipt, header *types.Header, c *chain.Chain) error {
+	result.BlobGasFeeCap = tx.BlobGasFeeCap()
+	result.MaxFeePerBlobGas = tx.BlobGasFeeCap()
+	result.BlobGasUsed = tx.BlobGas()
+	if receipt != nil && receipt.BlobGasUsed > 0 {
 		result.BlobGasUsed = receipt.BlobGasUsed
-		// Blob fee cap would need to be extracted from the transaction wrapper
-		// This is a simplified version - full implementation would parse blob tx wrapper
-		if receipt.BlobGasUsed > 0 {
-			// Placeholder: blob fee cap would come from transaction data
-			// For now, we leave it nil if not available
+	}
+	for _, hash := range tx.BlobHashes() {
+		result.BlobHashes = append(result.BlobHashes, hash.Hex())
+	}
+
+	if receipt != nil && receipt.BlobGasPrice != nil {
+		result.BlobBaseFee = receipt.BlobGasPrice
+	} else if header != nil && header.ExcessBlobGas != nil {
+		if config, ok := c.Blob(blockOf(header)); ok {
+			result.BlobBaseFee = BlobBaseFee(*header.ExcessBlobGas, config)
 		}
 	}
+	if result.BlobBaseFee != nil {
+		result.BlobFee = new(big.Int).Mul(result.BlobBaseFee, new(big.Int).SetUint64(result.BlobGasUsed))
+	}
+
+	if sc := tx.BlobTxSidecar(); sc != nil {
+		return result.VerifySidecar(sc)
+	}
+	return nil
 }
 
-// estimateIntrinsicGas estimates the intrinsic gas cost of a transaction.
-// This is a simplified version - full calculation would consider data, access list, etc.
-func (d *EthereumDecoder) estimateIntrinsicGas(tx *types.Transaction) uint64 {
-	// Base intrinsic gas
-	gas := uint64(21000)
+// EIP-7623 prices calldata for the gas floor in tokens: one per zero byte
+// and four per non-zero byte.
+const (
+	txTokenPerNonZeroByte = 4
+	txCostFloorPerToken   = 10
+)
+
+// perEmptyAccountCost is the intrinsic gas of an EIP-7702 authorization.
+// Authorities that already exist are refunded part of it during execution.
+const perEmptyAccountCost = 25000
+
+// intrinsicGas itemizes the intrinsic gas of a transaction under the given
+// fork rules, as the state transition charges it.
+func (d *EthereumDecoder) intrinsicGas(data []byte, creation bool, accessList types.AccessList, authorizations int, rules params.Rules) IntrinsicGas {
+	g := IntrinsicGas{Base: params.TxGas}
+	if creation && rules.IsHomestead {
+		g.Creation = params.TxGasContractCreation - params.TxGas
+	}
 
-	// Add gas for data (4 gas per zero byte, 16 gas per non-zero byte)
-	data := tx.Data()
 	for _, b := range data {
 		if b == 0 {
-			gas += 4
-		} else {
-			gas += 16
+			g.ZeroBytes++
 		}
 	}
+	g.NonZeroBytes = uint64(len(data)) - g.ZeroBytes
+	nonZeroGas := params.TxDataNonZeroGasFrontier
+	if rules.IsIstanbul {
+		nonZeroGas = params.TxDataNonZeroGasEIP2028
+	}
+	g.Calldata = g.ZeroBytes*params.TxDataZeroGas + g.NonZeroBytes*nonZeroGas
 
-	// Add gas for access list
-	if tx.Type() == types.AccessListTxType || tx.Type() == types.DynamicFeeTxType {
-		accessList := tx.AccessList()
-		gas += uint64(len(accessList)) * 2400 // Per address
+	if rules.IsBerlin {
 		for _, entry := range accessList {
-			gas += uint64(len(entry.StorageKeys)) * 1900 // Per storage key
+			g.AccessListAddresses++
+			g.AccessListKeys += uint64(len(entry.StorageKeys))
 		}
+		g.AccessList = g.AccessListAddresses*params.TxAccessListAddressGas + g.AccessListKeys*params.TxAccessListStorageKeyGas
+	}
+
+	if creation && rules.IsShanghai {
+		g.InitcodeWords = toWordSize(uint64(len(data)))
+		g.Initcode = g.InitcodeWords * params.InitCodeWordGas
+	}
+
+	if rules.IsPrague {
+		g.Authorizations = uint64(authorizations)
+		g.AuthorizationList = g.Authorizations * perEmptyAccountCost
 	}
 
-	// Contract creation costs extra
-	if tx.To() == nil {
-		gas += 32000
+	g.Total = g.Base + g.Creation + g.Calldata + g.AccessList + g.Initcode + g.AuthorizationList
+	if rules.IsPrague {
+		tokens := g.ZeroBytes + g.NonZeroBytes*txTokenPerNonZeroByte
+		g.Floor = params.TxGas + tokens*txCostFloorPerToken
 	}
+	return g
+}
+
+// toWordSize returns the number of 32-byte words needed for size bytes.
+func toWordSize(size uint64) uint64 {
+	return (size + 31) / 32
+}
 
-	return gas
+// blockOf returns the number and time of header, or nil for no header.
+func blockOf(header *types.Header) (*big.Int, uint64) {
+	if header == nil {
+		return nil, 0
+	}
+	return header.Number, header.Time
 }
 
-// GetSender extracts the sender address from a transaction.
-// This uses EIP-155 replay protection to recover the sender.
+// GetSender recovers the sender of a transaction with the latest signer of
+// its chain, or under Frontier rules for an unprotected legacy transaction
+// that only pre-Homestead rules accept. Use GetSenderAt for transactions
+// included in a block.
 func GetSender(tx *types.Transaction) (common.Address, error) {
-	signer := types.LatestSignerForChainID(tx.ChainId())
-	return types.Sender(signer, tx)
+	return NewEthereumDecoder().sender(tx, nil)
+}
+
+// GetSenderAt extracts the sender address from a transaction using the
+// signer that was valid on c at the block of header (nil for pending
+// transactions).
+func GetSenderAt(tx *types.Transaction, c *chain.Chain, header *types.Header) (common.Address, error) {
+	return NewEthereumDecoderForChain(c).sender(tx, header)
 }
@@ -0,0 +1,233 @@
+package decoder
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+)
+
+// Fork rules, each enabling the forks before it.
+var (
+	frontierRules  = params.Rules{}
+	homesteadRules = params.Rules{IsHomestead: true}
+	istanbulRules  = params.Rules{IsHomestead: true, IsEIP150: true, IsEIP155: true, IsEIP158: true,
+		IsByzantium: true, IsConstantinople: true, IsPetersburg: true, IsIstanbul: true}
+	berlinRules   = with(istanbulRules, func(r *params.Rules) { r.IsBerlin = true })
+	shanghaiRules = with(berlinRules, func(r *params.Rules) { r.IsLondon, r.IsMerge, r.IsShanghai = true, true, true })
+	pragueRules   = with(shanghaiRules, func(r *params.Rules) { r.IsCancun, r.IsPrague = true, true })
+)
+
+func with(rules params.Rules, enable func(*params.Rules)) params.Rules {
+	enable(&rules)
+	return rules
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	// 100 bytes of initcode, 40 of them zero: 4 words.
+	initcode := append(bytes.Repeat([]byte{0x60}, 60), make([]byte, 40)...)
+	accessList := types.AccessList{
+		{Address: common.Address{1}, StorageKeys: []common.Hash{{1}, {2}}},
+		{Address: common.Address{2}, StorageKeys: []common.Hash{{3}}},
+	}
+	calldataHeavy := bytes.Repeat([]byte{0xff}, 1000)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		creation   bool
+		accessList types.AccessList
+		rules      params.Rules
+		want       IntrinsicGas
+		minimum    uint64
+	}{
+		{
+			name: "frontier creation", data: initcode, creation: true, rules: frontierRules,
+			want: IntrinsicGas{Base: 21000, ZeroBytes: 40, NonZeroBytes: 60, Calldata: 40*4 + 60*68,
+				Total: 21000 + 40*4 + 60*68},
+		},
+		{
+			name: "homestead creation", data: initcode, creation: true, rules: homesteadRules,
+			want: IntrinsicGas{Base: 21000, Creation: 32000, ZeroBytes: 40, NonZeroBytes: 60, Calldata: 40*4 + 60*68,
+				Total: 53000 + 40*4 + 60*68},
+		},
+		{
+			name: "istanbul calldata", data: initcode, rules: istanbulRules,
+			want: IntrinsicGas{Base: 21000, ZeroBytes: 40, NonZeroBytes: 60, Calldata: 40*4 + 60*16,
+				Total: 21000 + 40*4 + 60*16},
+		},
+		{
+			name: "access list before berlin", accessList: accessList, rules: istanbulRules,
+			want: IntrinsicGas{Base: 21000, Total: 21000},
+		},
+		{
+			name: "berlin access list", accessList: accessList, rules: berlinRules,
+			want: IntrinsicGas{Base: 21000, AccessListAddresses: 2, AccessListKeys: 3, AccessList: 2*2400 + 3*1900,
+				Total: 21000 + 2*2400 + 3*1900},
+		},
+		{
+			name: "shanghai creation", data: initcode, creation: true, accessList: accessList, rules: shanghaiRules,
+			want: IntrinsicGas{Base: 21000, Creation: 32000, ZeroBytes: 40, NonZeroBytes: 60, Calldata: 40*4 + 60*16,
+				AccessListAddresses: 2, AccessListKeys: 3, AccessList: 2*2400 + 3*1900, InitcodeWords: 4, Initcode: 4 * 2,
+				Total: 53000 + 40*4 + 60*16 + 2*2400 + 3*1900 + 4*2},
+		},
+		{
+			// The floor prices the 1000 non-zero bytes at 4 tokens of 10 gas.
+			name: "prague calldata floor", data: calldataHeavy, rules: pragueRules,
+			want: IntrinsicGas{Base: 21000, NonZeroBytes: 1000, Calldata: 1000 * 16,
+				Total: 21000 + 1000*16, Floor: 21000 + 1000*4*10},
+			minimum: 21000 + 1000*4*10,
+		},
+		{
+			name: "prague creation under the floor", data: initcode, creation: true, accessList: accessList, rules: pragueRules,
+			want: IntrinsicGas{Base: 21000, Creation: 32000, ZeroBytes: 40, NonZeroBytes: 60, Calldata: 40*4 + 60*16,
+				AccessListAddresses: 2, AccessListKeys: 3, AccessList: 2*2400 + 3*1900, InitcodeWords: 4, Initcode: 4 * 2,
+				Total: 53000 + 40*4 + 60*16 + 2*2400 + 3*1900 + 4*2, Floor: 21000 + (40+60*4)*10},
+		},
+	}
+	d := NewEthereumDecoder()
+	for _, tt := range tests {
+		got := d.intrinsicGas(tt.data, tt.creation, tt.accessList, 0, tt.rules)
+		if got != tt.want {
+			t.Errorf("%s: intrinsic gas %+v, want %+v", tt.name, got, tt.want)
+		}
+		minimum := tt.minimum
+		if minimum == 0 {
+			minimum = tt.want.Total
+		}
+		if got.Minimum() != minimum {
+			t.Errorf("%s: minimum gas %d, want %d", tt.name, got.Minimum(), minimum)
+		}
+
+		// go-ethereum's calculation knows every charge up to Shanghai, but
+		// charges access lists whatever the fork.
+		want, err := core.IntrinsicGas(tt.data, tt.accessList, tt.creation, tt.rules.IsHomestead, tt.rules.IsIstanbul, tt.rules.IsShanghai)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.rules.IsBerlin || len(tt.accessList) == 0 {
+			if got.Total != want {
+				t.Errorf("%s: total %d, go-ethereum computes %d", tt.name, got.Total, want)
+			}
+		}
+	}
+}
+
+func TestDecodeTransactionRoundTrip(t *testing.T) {
+	to := common.Address{0xaa}
+	chainID := big.NewInt(1)
+	latest := types.LatestSignerForChainID(chainID)
+	sc := newTestSidecar(t, 2)
+
+	legacy, sender := signedTx(t, types.HomesteadSigner{}, &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(1)})
+	eip155, _ := signedTx(t, latest, &types.LegacyTx{Nonce: 2, GasPrice: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(2)})
+	accessList, _ := signedTx(t, latest, &types.AccessListTx{ChainID: chainID, Nonce: 3, GasPrice: big.NewInt(1e9), Gas: 30000, To: &to,
+		AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}}})
+	dynamicFee, _ := signedTx(t, latest, &types.DynamicFeeTx{ChainID: chainID, Nonce: 4, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(4)})
+	blobTx := &types.BlobTx{ChainID: uint256.NewInt(1), Nonce: 5, GasTipCap: uint256.NewInt(1), GasFeeCap: uint256.NewInt(1e9), Gas: 21000, To: to,
+		BlobFeeCap: uint256.NewInt(1e9), BlobHashes: sc.BlobHashes()}
+	blob, _ := signedTx(t, latest, blobTx)
+	blobWithSidecar := blob.WithBlobTxSidecar(sc)
+
+	encode := func(tx *types.Transaction) []byte {
+		data, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	wrap := func(data []byte) []byte {
+		wrapped, err := rlp.EncodeToBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wrapped
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		tx        *types.Transaction // as decoded, without a sidecar
+		typ       TransactionType
+		protected bool
+		sidecar   bool // whether a verified sidecar is expected
+	}{
+		{"legacy", encode(legacy), legacy, TransactionTypeLegacy, false, false},
+		{"legacy EIP-155", encode(eip155), eip155, TransactionTypeLegacy, true, false},
+		{"access list", encode(accessList), accessList, TransactionTypeAccessList, true, false},
+		{"access list in a block body", wrap(encode(accessList)), accessList, TransactionTypeAccessList, true, false},
+		{"dynamic fee", encode(dynamicFee), dynamicFee, TransactionTypeDynamicFee, true, false},
+		{"dynamic fee in a block body", wrap(encode(dynamicFee)), dynamicFee, TransactionTypeDynamicFee, true, false},
+		{"blob", encode(blob), blob, TransactionTypeBlob, true, false},
+		{"blob in a block body", wrap(encode(blob)), blob, TransactionTypeBlob, true, false},
+		{"blob in network form", encode(blobWithSidecar), blob, TransactionTypeBlob, true, true},
+	}
+	for _, tt := range tests {
+		parsed, err := ParseTransaction(tt.data)
+		if err != nil {
+			t.Fatalf("%s: ParseTransaction: %v", tt.name, err)
+		}
+		if parsed.Hash() != tt.tx.Hash() {
+			t.Errorf("%s: parsed hash %s, want %s", tt.name, parsed.Hash().Hex(), tt.tx.Hash().Hex())
+		}
+		if enc := encode(parsed.WithoutBlobTxSidecar()); !bytes.Equal(enc, encode(tt.tx)) {
+			t.Errorf("%s: re-encoded as %x, want %x", tt.name, enc, encode(tt.tx))
+		}
+
+		decoded, err := NewEthereumDecoder().DecodeTransaction(tt.data)
+		if err != nil {
+			t.Fatalf("%s: DecodeTransaction: %v", tt.name, err)
+		}
+		if decoded.Hash != tt.tx.Hash().Hex() || decoded.From != sender.Hex() || decoded.To != to.Hex() {
+			t.Errorf("%s: decoded %s from %s to %s, want %s from %s to %s", tt.name,
+				decoded.Hash, decoded.From, decoded.To, tt.tx.Hash().Hex(), sender.Hex(), to.Hex())
+		}
+		if decoded.Type != tt.typ || decoded.Nonce != tt.tx.Nonce() || decoded.Value.Cmp(tt.tx.Value()) != 0 || decoded.GasLimit != tt.tx.Gas() {
+			t.Errorf("%s: decoded type %d nonce %d value %v gas %d, want %d, %d, %v, %d", tt.name,
+				decoded.Type, decoded.Nonce, decoded.Value, decoded.GasLimit, tt.typ, tt.tx.Nonce(), tt.tx.Value(), tt.tx.Gas())
+		}
+		if decoded.Signature == nil || decoded.Signature.Protected != tt.protected {
+			t.Errorf("%s: signature %+v, want protected %v", tt.name, decoded.Signature, tt.protected)
+		}
+		if len(decoded.BlobHashes) != len(tt.tx.BlobHashes()) {
+			t.Errorf("%s: %d blob hashes, want %d", tt.name, len(decoded.BlobHashes), len(tt.tx.BlobHashes()))
+		}
+		if got := decoded.Sidecar != nil; got != tt.sidecar {
+			t.Errorf("%s: sidecar verified = %v, want %v", tt.name, got, tt.sidecar)
+		} else if tt.sidecar && !decoded.Sidecar.Valid() {
+			t.Errorf("%s: sidecar does not verify: %+v", tt.name, decoded.Sidecar)
+		}
+	}
+}
+
+func TestDecodeTransactionInvalid(t *testing.T) {
+	to := common.Address{0xaa}
+	tx, _ := signedTx(t, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{ChainID: big.NewInt(1), Gas: 21000, To: &to})
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":                     nil,
+		"empty RLP string":          {0x80},
+		"trailing bytes":            append(wrapped, 0x00),
+		"truncated":                 data[:len(data)-1],
+		"truncated in a block body": wrapped[:len(wrapped)-1],
+		"unknown type":              append([]byte{0x7f}, data[1:]...),
+	} {
+		if _, err := NewEthereumDecoder().DecodeTransaction(data); err == nil {
+			t.Errorf("%s: decoded", name)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package decoder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ParseSignature returns the function of a canonical signature such as
+// "transfer(address,uint256)", e.g. from a selector database. Its arguments
+// and tuple components are unnamed.
+func ParseSignature(signature string) (*abi.Method, error) {
+	open := strings.IndexByte(signature, '(')
+	if open <= 0 || !strings.HasSuffix(signature, ")") || strings.ContainsAny(signature, " \t") {
+		return nil, fmt.Errorf("invalid function signature: %s", signature)
+	}
+	name := signature[:open]
+	params, err := splitTypes(signature[open+1 : len(signature)-1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid function signature %s: %w", signature, err)
+	}
+	inputs := make(abi.Arguments, len(params))
+	for i, param := range params {
+		t, err := parseType(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid function signature %s: %w", signature, err)
+		}
+		inputs[i] = abi.Argument{Type: t}
+	}
+	// go-ethereum keeps the spelling of types, e.g. "uint0256", in the
+	// signature it builds; spell them out to compare.
+	canonical := make([]string, len(inputs))
+	for i, input := range inputs {
+		canonical[i] = canonicalType(input.Type)
+	}
+	if sig := name + "(" + strings.Join(canonical, ",") + ")"; sig != signature {
+		return nil, fmt.Errorf("function signature %s is not canonical (%s)", signature, sig)
+	}
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil)
+	return &method, nil
+}
+
+// canonicalType returns the canonical spelling of t, as hashed into
+// selectors.
+func canonicalType(t abi.Type) string {
+	switch t.T {
+	case abi.IntTy:
+		return fmt.Sprintf("int%d", t.Size)
+	case abi.UintTy:
+		return fmt.Sprintf("uint%d", t.Size)
+	case abi.FixedBytesTy:
+		return fmt.Sprintf("bytes%d", t.Size)
+	case abi.ArrayTy:
+		return fmt.Sprintf("%s[%d]", canonicalType(*t.Elem), t.Size)
+	case abi.SliceTy:
+		return canonicalType(*t.Elem) + "[]"
+	case abi.TupleTy:
+		elems := make([]string, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			elems[i] = canonicalType(*elem)
+		}
+		return "(" + strings.Join(elems, ",") + ")"
+	}
+	return t.String()
+}
+
+// splitTypes splits a comma-separated list of types, leaving the commas of
+// tuples alone.
+func splitTypes(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var types []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				types = append(types, list[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 {
+			return nil, fmt.Errorf("unbalanced parentheses")
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return append(types, list[start:]), nil
+}
+
+// parseType returns the ABI type of a canonical type such as "uint256[]" or
+// "(address,bytes)[2]".
+func parseType(s string) (abi.Type, error) {
+	m, err := typeMarshaling(s)
+	if err != nil {
+		return abi.Type{}, err
+	}
+	t, err := abi.NewType(m.Type, "", m.Components)
+	if err != nil {
+		return abi.Type{}, err
+	}
+	if err := checkSizes(t); err != nil {
+		return abi.Type{}, err
+	}
+	unname(&t)
+	return t, nil
+}
+
+// checkSizes rejects the integer sizes go-ethereum accepts but Solidity
+// does not: those that are not multiples of 8 up to 256.
+func checkSizes(t abi.Type) error {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if t.Size%8 != 0 || t.Size > 256 {
+			return fmt.Errorf("invalid type %s", t)
+		}
+	case abi.SliceTy, abi.ArrayTy:
+		return checkSizes(*t.Elem)
+	case abi.TupleTy:
+		for _, elem := range t.TupleElems {
+			if err := checkSizes(*elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// typeMarshaling converts a canonical type into its JSON ABI form. Tuple
+// components get placeholder names, which go-ethereum requires.
+func typeMarshaling(s string) (abi.ArgumentMarshaling, error) {
+	if !strings.HasPrefix(s, "(") {
+		return abi.ArgumentMarshaling{Type: s}, nil
+	}
+	end := strings.LastIndexByte(s, ')')
+	components, err := splitTypes(s[1:end])
+	if err != nil {
+		return abi.ArgumentMarshaling{}, err
+	}
+	m := abi.ArgumentMarshaling{Type: "tuple" + s[end+1:]}
+	for i, component := range components {
+		c, err := typeMarshaling(component)
+		if err != nil {
+			return abi.ArgumentMarshaling{}, err
+		}
+		c.Name = fmt.Sprintf("c%d", i)
+		m.Components = append(m.Components, c)
+	}
+	return m, nil
+}
+
+// unname removes the placeholder names of tuple components.
+func unname(t *abi.Type) {
+	if t.Elem != nil {
+		unname(t.Elem)
+	}
+	for i, elem := range t.TupleElems {
+		t.TupleRawNames[i] = ""
+		unname(elem)
+	}
+}
+
+// DecodeCalldataCandidates decodes calldata against each candidate
+// signature of its selector, as found in a selector database, and returns
+// the results best first: signatures the calldata decodes cleanly against,
+// exact fits before those leaving trailing bytes, then the others by how far
+// decoding got. Signatures that are invalid or of another selector are
+// skipped.
+func (d *EthereumDecoder) DecodeCalldataCandidates(calldata []byte, signatures []string) []*Calldata {
+	var results []*Calldata
+	for _, signature := range signatures {
+		method, err := ParseSignature(signature)
+		if err != nil {
+			continue
+		}
+		contract := &abi.ABI{Methods: map[string]abi.Method{method.Name: *method}}
+		result, err := d.WithABI(contract).DecodeCalldata(calldata)
+		if err != nil || result.Signature == "" {
+			continue
+		}
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		if a.Error == "" {
+			return a.TrailingBytes < b.TrailingBytes
+		}
+		return a.ErrorOffset > b.ErrorOffset
+	})
+	return results
+}
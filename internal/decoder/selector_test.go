@@ -0,0 +1,154 @@
+package decoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		signature string
+		inputs    []string // canonical input types
+		err       string
+	}{
+		{"transfer(address,uint256)", []string{"address", "uint256"}, ""},
+		{"fallback()", nil, ""},
+		{"swap((address,bytes)[2],uint8[][],(uint256,(bool,string)))", []string{"(address,bytes)[2]", "uint8[][]", "(uint256,(bool,string))"}, ""},
+		{"f(uint0256)", nil, "not canonical (f(uint256))"},
+		{"f(bytes04,bool[02])", nil, "not canonical (f(bytes4,bool[2]))"},
+		{"f((int008,address)[])", nil, "not canonical (f((int8,address)[]))"},
+		{"f(tuple)", nil, "not canonical (f(()))"},
+		{"transfer(address,uint)", nil, "unsupported arg type: uint"},
+		{"transfer(address, uint256)", nil, "invalid function signature"},
+		{"transfer(address to,uint256 amount)", nil, "invalid function signature"},
+		{"transfer", nil, "invalid function signature"},
+		{"(address)", nil, "invalid function signature"},
+		{"f((uint256,address)", nil, "unbalanced parentheses"},
+		{"f(uint7)", nil, "invalid type"},
+		{"f(uint264)", nil, "invalid"},
+		{"f((int12)[])", nil, "invalid type"},
+		{"f(bytes33)", nil, "invalid"},
+	}
+	for _, tt := range tests {
+		method, err := ParseSignature(tt.signature)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.signature, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.signature, err)
+			continue
+		}
+		if method.Sig != tt.signature || method.Name != tt.signature[:strings.IndexByte(tt.signature, '(')] {
+			t.Errorf("%s: method %s named %s", tt.signature, method.Sig, method.Name)
+		}
+		var inputs []string
+		for _, input := range method.Inputs {
+			inputs = append(inputs, input.Type.String())
+			if input.Name != "" || strings.Join(input.Type.TupleRawNames, "") != "" {
+				t.Errorf("%s: named input %q %q", tt.signature, input.Name, input.Type.TupleRawNames)
+			}
+		}
+		if !reflect.DeepEqual(inputs, tt.inputs) {
+			t.Errorf("%s: inputs %q, want %q", tt.signature, inputs, tt.inputs)
+		}
+	}
+}
+
+func TestDecodeCalldataCandidates(t *testing.T) {
+	// Signatures of the selector 0xa9059cbb in 4byte.directory.
+	signatures := []string{
+		"many_msg_babbage(bytes1)",
+		"join_tg_invmru_haha_fd06787(address,bool)",
+		"transfer(bytes4[9],bytes5[6],int48[11])",
+		"func_2093253501(bytes)",
+		"transfer(address,uint256)",
+		"transfer(address, uint256)", // invalid
+		"approve(address,uint256)",   // another selector
+	}
+	selector := common.FromHex("0xa9059cbb")
+	word := func(b ...byte) []byte { return common.LeftPadBytes(b, 32) }
+	calldata := func(words ...[]byte) []byte {
+		data := append([]byte{}, selector...)
+		for _, w := range words {
+			data = append(data, w...)
+		}
+		return data
+	}
+	bytes1 := append([]byte{0xab}, make([]byte, 31)...)
+
+	tests := []struct {
+		name     string
+		calldata []byte
+		want     []string // signatures, best first
+	}{
+		{
+			// A transfer of 1000 to 0x..b2: only the bool of join_tg is
+			// out of range, after the address.
+			name:     "transfer",
+			calldata: calldata(word(0xb2), word(0x03, 0xe8)),
+			want: []string{
+				"transfer(address,uint256)",
+				"join_tg_invmru_haha_fd06787(address,bool)",
+				"many_msg_babbage(bytes1)",
+				"transfer(bytes4[9],bytes5[6],int48[11])",
+				"func_2093253501(bytes)",
+			},
+		},
+		{
+			// Ties keep the database order.
+			name:     "transfer of 1",
+			calldata: calldata(word(0xb2), word(1)),
+			want: []string{
+				"join_tg_invmru_haha_fd06787(address,bool)",
+				"transfer(address,uint256)",
+				"many_msg_babbage(bytes1)",
+				"transfer(bytes4[9],bytes5[6],int48[11])",
+				"func_2093253501(bytes)",
+			},
+		},
+		{
+			// One byte of bytes fits exactly; the others leave a word.
+			name:     "bytes",
+			calldata: calldata(word(0x20), word(1), bytes1),
+			want: []string{
+				"func_2093253501(bytes)",
+				"join_tg_invmru_haha_fd06787(address,bool)",
+				"transfer(address,uint256)",
+				"many_msg_babbage(bytes1)",
+				"transfer(bytes4[9],bytes5[6],int48[11])",
+			},
+		},
+		{
+			// A clean bytes1 leaves trailing bytes, which beats failing.
+			name:     "bytes1",
+			calldata: calldata(bytes1, word(1)),
+			want: []string{
+				"many_msg_babbage(bytes1)",
+				"transfer(bytes4[9],bytes5[6],int48[11])",
+				"join_tg_invmru_haha_fd06787(address,bool)",
+				"func_2093253501(bytes)",
+				"transfer(address,uint256)",
+			},
+		},
+	}
+	d := NewEthereumDecoder()
+	for _, tt := range tests {
+		var got []string
+		for _, result := range d.DecodeCalldataCandidates(tt.calldata, signatures) {
+			got = append(got, result.Signature)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: candidates\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+
+	if got := d.DecodeCalldataCandidates(selector, []string{"approve(address,uint256)"}); len(got) != 0 {
+		t.Errorf("candidates of another selector = %d, want none", len(got))
+	}
+}
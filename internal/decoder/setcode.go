@@ -0,0 +1,169 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// SetCodeTxType is the EIP-2718 type of EIP-7702 set-code transactions.
+// go-ethereum's types.Transaction does not support it yet, so these
+// transactions are decoded here.
+const SetCodeTxType = 0x04
+
+// authorizationMagic prefixes the signed payload of an authorization.
+const authorizationMagic = 0x05
+
+// setCodeTx is the RLP payload of a set-code transaction.
+type setCodeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         common.Address // set-code transactions cannot create contracts
+	Value      *big.Int
+	Data       []byte
+	AccessList types.AccessList
+	AuthList   []setCodeAuthorization
+	V, R, S    *big.Int
+}
+
+// setCodeAuthorization is the RLP encoding of an authorization.
+type setCodeAuthorization struct {
+	ChainID *big.Int
+	Address common.Address
+	Nonce   uint64
+	V       uint8
+	R, S    *big.Int
+}
+
+// sigHash returns the hash the sender signed.
+func (tx *setCodeTx) sigHash() common.Hash {
+	return prefixedRLPHash(SetCodeTxType, []interface{}{
+		tx.ChainID, tx.Nonce, tx.GasTipCap, tx.GasFeeCap, tx.Gas,
+		tx.To, tx.Value, tx.Data, tx.AccessList, tx.AuthList,
+	})
+}
+
+// sigHash returns the hash the authority signed.
+func (a *setCodeAuthorization) sigHash() common.Hash {
+	return prefixedRLPHash(authorizationMagic, []interface{}{a.ChainID, a.Address, a.Nonce})
+}
+
+// prefixedRLPHash returns the Keccak-256 hash of prefix followed by the RLP
+// encoding of x.
+func prefixedRLPHash(prefix byte, x interface{}) common.Hash {
+	enc, _ := rlp.EncodeToBytes(x)
+	return crypto.Keccak256Hash([]byte{prefix}, enc)
+}
+
+// recoverSigner returns the address that produced the signature (v, r, s)
+// of hash, where v is the y-parity. High s values are rejected, as for
+// transactions since Homestead.
+func recoverSigner(hash common.Hash, v *big.Int, r, s *big.Int) (common.Address, error) {
+	if v == nil || r == nil || s == nil || !v.IsUint64() || v.Uint64() > 1 ||
+		!crypto.ValidateSignatureValues(byte(v.Uint64()), r, s, true) {
+		return common.Address{}, types.ErrInvalidSig
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[64] = byte(v.Uint64())
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// decodeSetCodeTx decodes the binary encoding of a set-code transaction,
+// which go-ethereum cannot represent, straight into a Transaction.
+func (d *EthereumDecoder) decodeSetCodeTx(envelope []byte, receipt *types.Receipt, header *types.Header) (*Transaction, error) {
+	var tx setCodeTx
+	if err := rlp.DecodeBytes(envelope[1:], &tx); err != nil {
+		return nil, fmt.Errorf("invalid set-code transaction encoding: %w", err)
+	}
+	if tx.ChainID == nil || tx.GasTipCap == nil || tx.GasFeeCap == nil || tx.Value == nil {
+		return nil, errors.New("invalid set-code transaction encoding: missing fields")
+	}
+	rules := d.chainFor(tx.ChainID).Rules(blockOf(header))
+
+	sender, err := recoverSigner(tx.sigHash(), tx.V, tx.R, tx.S)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %w", err)
+	}
+
+	result := &Transaction{
+		Hash:                 crypto.Keccak256Hash(envelope).Hex(),
+		From:                 sender.Hex(),
+		To:                   tx.To.Hex(),
+		Nonce:                tx.Nonce,
+		Value:                tx.Value,
+		GasLimit:             tx.Gas,
+		MaxFeePerGas:         tx.GasFeeCap,
+		MaxPriorityFeePerGas: tx.GasTipCap,
+		Type:                 TransactionTypeSetCode,
+		ChainID:              tx.ChainID,
+		AccessList:           d.buildAccessList(tx.AccessList),
+		Input:                tx.Data,
+		Intrinsic:            d.intrinsicGas(tx.Data, false, tx.AccessList, len(tx.AuthList), rules),
+		Fork:                 chain.ForkName(rules),
+		Signature: &Signature{
+			V:           tx.V,
+			R:           tx.R,
+			S:           tx.S,
+			YParity:     uint8(tx.V.Uint64()),
+			Protected:   true,
+			LowS:        isLowS(tx.S),
+			SigningHash: tx.sigHash().Hex(),
+		},
+	}
+	result.EstimatedIntrinsicGas = result.Intrinsic.Total
+	if receipt != nil {
+		result.EffectiveGasPrice = receipt.EffectiveGasPrice
+	}
+
+	result.Authorizations = make([]Authorization, len(tx.AuthList))
+	for i := range tx.AuthList {
+		auth := &tx.AuthList[i]
+		result.Authorizations[i] = Authorization{
+			ChainID: auth.ChainID,
+			Address: auth.Address.Hex(),
+			Nonce:   auth.Nonce,
+			YParity: auth.V,
+			R:       auth.R,
+			S:       auth.S,
+		}
+		authority, err := recoverSigner(auth.sigHash(), new(big.Int).SetUint64(uint64(auth.V)), auth.R, auth.S)
+		if err == nil {
+			result.Authorizations[i].Authority = authority.Hex()
+		}
+		result.Authorizations[i].Invalid = auth.invalid(tx.ChainID, err)
+	}
+	return result, nil
+}
+
+// invalid returns the reason an authorization of a transaction on chain
+// chainID is skipped, given the error recovering its authority, or an empty
+// string. Checks against the authority's state are left out.
+func (a *setCodeAuthorization) invalid(chainID *big.Int, recoverErr error) string {
+	switch {
+	case a.ChainID == nil:
+		return "missing chain ID"
+	case a.ChainID.Sign() != 0 && a.ChainID.Cmp(chainID) != 0:
+		return fmt.Sprintf("chain ID %v is neither 0 nor the transaction's chain ID %v", a.ChainID, chainID)
+	case a.Nonce == math.MaxUint64:
+		return "nonce overflows"
+	case recoverErr != nil:
+		return "invalid signature"
+	}
+	return ""
+}
@@ -0,0 +1,226 @@
+package decoder
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// setCodeFixture is a set-code transaction on chain 1 from the account of
+// setCodeSenderKey, calling 0xaa with calldata 0xdead00ef. Its first
+// authorization, valid on every chain, is signed with setCodeAuthorityKeys[0]
+// and its second, valid on chain 1 at nonce 7, with setCodeAuthorityKeys[1].
+// Both delegate to 0x7702.
+const setCodeFixture = "0x04f9012a010384773594008506fc23ac00830186a09400000000000000000000000000000000000000aa8084dead00efc0f8b8f85a809400000000000000000000000000000000000077028080a0bdb04be298d40cd0f12fc7531be4854747adf65c61cba5c6f6509d6fcb80cb1ba0481eeee2770436fe1fca3efb20706f6928b25a94dfdd18cbaece796971d27ebaf85a019400000000000000000000000000000000000077020701a0f4a47c28af6237aa742adc06a47362b106af05205a23026f4b0a3d8fbb8fb076a012c9c871ee1a2e97a9d4679cd41413a115cb33efc01218de66fb84bc601b4ec401a059c5c4309c17c896bd6116483b00304ac5d8f542e57dc4f9621a9cd9f71b4471a06e690007e3d02f38f04bd548ef4c96002b95c1d02699fcaf23aadf91eae269bc"
+
+const setCodeSenderKey = "45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8"
+
+var setCodeAuthorityKeys = []string{
+	"8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a",
+	"0202020202020202020202020202020202020202020202020202020202020202",
+}
+
+// setCodeVariant returns the fixture changed by edit and signed again.
+// Authorizations are signed again by the key of the same index.
+func setCodeVariant(t *testing.T, edit func(tx *setCodeTx)) []byte {
+	t.Helper()
+	var tx setCodeTx
+	if err := rlp.DecodeBytes(hexutil.MustDecode(setCodeFixture)[1:], &tx); err != nil {
+		t.Fatal(err)
+	}
+	edit(&tx)
+	for i := range tx.AuthList {
+		auth := &tx.AuthList[i]
+		sig := sign(t, auth.sigHash(), setCodeAuthorityKeys[i])
+		auth.V, auth.R, auth.S = sig[64], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	}
+	sig := sign(t, tx.sigHash(), setCodeSenderKey)
+	tx.V, tx.R, tx.S = big.NewInt(int64(sig[64])), new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	enc, err := rlp.EncodeToBytes(&tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte{SetCodeTxType}, enc...)
+}
+
+func sign(t *testing.T, hash common.Hash, hexKey string) []byte {
+	t.Helper()
+	key, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func address(t *testing.T, hexKey string) string {
+	t.Helper()
+	key, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto.PubkeyToAddress(key.PublicKey).Hex()
+}
+
+func TestDecodeSetCodeTx(t *testing.T) {
+	fixture := hexutil.MustDecode(setCodeFixture)
+	authorities := []string{address(t, setCodeAuthorityKeys[0]), address(t, setCodeAuthorityKeys[1])}
+
+	tests := []struct {
+		name string
+		data []byte
+
+		// Results for valid encodings.
+		hash        string
+		authorities []string
+		invalid     []string
+		intrinsic   uint64
+
+		// Error for invalid encodings.
+		err string
+	}{
+		{
+			name:        "fixture",
+			data:        fixture,
+			hash:        "0xbd129cd2d6c8c1bd36835b20c78d3c31df1902f9fb7a6e2bfcf246d5ba82fb63",
+			authorities: authorities,
+			invalid:     []string{"", ""},
+			intrinsic:   21000 + 3*16 + 4 + 2*25000,
+		},
+		{
+			name: "authorization for another chain",
+			data: setCodeVariant(t, func(tx *setCodeTx) {
+				tx.AuthList[1].ChainID = big.NewInt(5)
+			}),
+			authorities: authorities,
+			invalid:     []string{"", "chain ID 5 is neither 0 nor the transaction's chain ID 1"},
+			intrinsic:   21000 + 3*16 + 4 + 2*25000,
+		},
+		{
+			name: "authorization with maximum nonce",
+			data: setCodeVariant(t, func(tx *setCodeTx) {
+				tx.AuthList[0].Nonce = ^uint64(0)
+			}),
+			authorities: authorities,
+			invalid:     []string{"nonce overflows", ""},
+			intrinsic:   21000 + 3*16 + 4 + 2*25000,
+		},
+		{
+			name: "no authorizations",
+			data: setCodeVariant(t, func(tx *setCodeTx) {
+				tx.AuthList = nil
+			}),
+			authorities: []string{},
+			invalid:     []string{},
+			intrinsic:   21000 + 3*16 + 4,
+		},
+		{name: "type byte only", data: fixture[:1], err: "invalid set-code transaction encoding"},
+		{name: "truncated", data: fixture[:len(fixture)-10], err: "invalid set-code transaction encoding"},
+		{name: "truncated authorization list", data: fixture[:40], err: "invalid set-code transaction encoding"},
+		{name: "trailing bytes", data: append(append([]byte{}, fixture...), 0x80), err: "invalid set-code transaction encoding"},
+		{
+			name: "too few fields",
+			data: append([]byte{SetCodeTxType}, mustEncode(t, []interface{}{big.NewInt(1), uint64(0)})...),
+			err:  "invalid set-code transaction encoding",
+		},
+		{
+			name: "contract creation",
+			data: append([]byte{SetCodeTxType}, mustEncode(t, []interface{}{
+				big.NewInt(1), uint64(0), big.NewInt(1), big.NewInt(1), uint64(21000), []byte{},
+				big.NewInt(0), []byte{}, []interface{}{}, []interface{}{}, uint64(0), big.NewInt(1), big.NewInt(1),
+			})...),
+			err: "invalid set-code transaction encoding",
+		},
+		{
+			name: "invalid sender signature",
+			data: func() []byte {
+				data := append([]byte{}, fixture...)
+				data[len(data)-32] = 0xff // s in the upper half of the curve order
+				return data
+			}(),
+			err: "failed to recover sender",
+		},
+	}
+
+	dec := NewEthereumDecoderForChain(chain.Unknown(big.NewInt(1)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := dec.DecodeTransaction(tt.data)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := crypto.Keccak256Hash(tt.data).Hex(); tx.Hash != want {
+				t.Errorf("hash = %s, want %s", tx.Hash, want)
+			}
+			if tt.hash != "" && tx.Hash != tt.hash {
+				t.Errorf("hash = %s, want %s", tx.Hash, tt.hash)
+			}
+			if want := address(t, setCodeSenderKey); tx.From != want {
+				t.Errorf("sender = %s, want %s", tx.From, want)
+			}
+			if tx.Type != TransactionTypeSetCode {
+				t.Errorf("type = %d, want %d", tx.Type, TransactionTypeSetCode)
+			}
+			if tx.EstimatedIntrinsicGas != tt.intrinsic {
+				t.Errorf("intrinsic gas = %d, want %d", tx.EstimatedIntrinsicGas, tt.intrinsic)
+			}
+			if len(tx.Authorizations) != len(tt.authorities) {
+				t.Fatalf("%d authorizations, want %d", len(tx.Authorizations), len(tt.authorities))
+			}
+			for i, auth := range tx.Authorizations {
+				if auth.Authority != tt.authorities[i] {
+					t.Errorf("authority %d = %s, want %s", i, auth.Authority, tt.authorities[i])
+				}
+				if auth.Invalid != tt.invalid[i] {
+					t.Errorf("authorization %d invalid = %q, want %q", i, auth.Invalid, tt.invalid[i])
+				}
+				if auth.Address != "0x0000000000000000000000000000000000007702" {
+					t.Errorf("authorization %d delegates to %s", i, auth.Address)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeSetCodeTxInvalidAuthoritySignature(t *testing.T) {
+	var tx setCodeTx
+	if err := rlp.DecodeBytes(hexutil.MustDecode(setCodeFixture)[1:], &tx); err != nil {
+		t.Fatal(err)
+	}
+	tx.AuthList[0].V = 2
+	sig := sign(t, tx.sigHash(), setCodeSenderKey)
+	tx.V, tx.R, tx.S = big.NewInt(int64(sig[64])), new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	data := append([]byte{SetCodeTxType}, mustEncode(t, &tx)...)
+
+	decoded, err := NewEthereumDecoder().DecodeTransaction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth := decoded.Authorizations[0]; auth.Authority != "" || auth.Invalid != "invalid signature" {
+		t.Errorf("authorization with y-parity 2: authority %q, invalid %q", auth.Authority, auth.Invalid)
+	}
+}
+
+func mustEncode(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	enc, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
@@ -0,0 +1,61 @@
+package decoder
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// secp256k1HalfN is half the order of secp256k1, the largest s value
+// accepted since Homestead (EIP-2).
+var secp256k1HalfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// sender recovers the sender of tx with the signer valid on its chain at the
+// block of header (nil for pending transactions).
+//
+// Without a block, an unsigned chain ID and a high s value may still be a
+// genuine pre-Homestead transaction, which every later signer rejects; it is
+// recovered under Frontier rules instead.
+func (d *EthereumDecoder) sender(tx *types.Transaction, header *types.Header) (common.Address, error) {
+	from, err := types.Sender(d.chainFor(tx.ChainId()).Signer(blockOf(header)), tx)
+	if errors.Is(err, types.ErrInvalidSig) && header == nil && tx.Type() == types.LegacyTxType && !tx.Protected() {
+		return types.Sender(types.FrontierSigner{}, tx)
+	}
+	return from, err
+}
+
+// signatureOf returns the signature of tx and the hash it signs.
+func signatureOf(tx *types.Transaction) *Signature {
+	v, r, s := tx.RawSignatureValues()
+	sig := &Signature{V: v, R: r, S: s, Protected: tx.Protected(), LowS: isLowS(s)}
+
+	var hash common.Hash
+	switch {
+	case tx.Type() != types.LegacyTxType:
+		sig.YParity = uint8(v.Uint64())
+		hash = types.LatestSignerForChainID(tx.ChainId()).Hash(tx)
+	case sig.Protected:
+		// v = chainID * 2 + 35 + y-parity (EIP-155)
+		y := new(big.Int).Sub(v, new(big.Int).Lsh(tx.ChainId(), 1))
+		sig.YParity = uint8(y.Sub(y, big.NewInt(35)).Uint64())
+		hash = types.NewEIP155Signer(tx.ChainId()).Hash(tx)
+	default:
+		// v = 27 + y-parity; a few early transactions carry the bare parity
+		y := v.Uint64()
+		if y >= 27 {
+			y -= 27
+		}
+		sig.YParity = uint8(y)
+		hash = types.HomesteadSigner{}.Hash(tx)
+	}
+	sig.SigningHash = hash.Hex()
+	return sig
+}
+
+// isLowS reports whether s is in the lower half of the curve order.
+func isLowS(s *big.Int) bool {
+	return s != nil && s.Cmp(secp256k1HalfN) <= 0
+}
@@ -0,0 +1,122 @@
+package decoder
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/luckify/getho/internal/chain"
+)
+
+// mainnetBlock returns a header of a mainnet block by number. Its time only
+// matters from Shanghai on.
+func mainnetBlock(number int64) *types.Header {
+	return &types.Header{Number: big.NewInt(number)}
+}
+
+// Mainnet blocks under successive signers.
+var (
+	frontierBlock  = mainnetBlock(1_000)
+	homesteadBlock = mainnetBlock(1_150_000)
+	eip155Block    = mainnetBlock(2_675_000)
+	berlinBlock    = mainnetBlock(12_244_000)
+	londonBlock    = mainnetBlock(12_965_000)
+)
+
+func signedTx(t *testing.T, signer types.Signer, inner types.TxData) (*types.Transaction, common.Address) {
+	t.Helper()
+	key, err := crypto.HexToECDSA(setCodeSenderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTx(inner), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// highS returns tx with its signature replaced by the equally valid one
+// with s in the upper half of the curve order, as only Frontier accepted.
+func highS(tx *types.Transaction) *types.Transaction {
+	v, r, s := tx.RawSignatureValues()
+	s = new(big.Int).Sub(crypto.S256().Params().N, s)
+	yParity := 1 - byte(v.Uint64()-27) // negating s flips the parity of the recovered point
+	tx, err := tx.WithSignature(types.FrontierSigner{}, append(append(common.LeftPadBytes(r.Bytes(), 32), common.LeftPadBytes(s.Bytes(), 32)...), yParity))
+	if err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+func TestSignature(t *testing.T) {
+	to := common.Address{0xaa}
+	legacy := &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(1)}
+	dynamic := &types.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1e9), Gas: 21000, To: &to}
+
+	homesteadTx, sender := signedTx(t, types.HomesteadSigner{}, legacy)
+	eip155Tx, _ := signedTx(t, types.NewEIP155Signer(big.NewInt(1)), legacy)
+	dynamicTx, _ := signedTx(t, types.NewLondonSigner(big.NewInt(1)), dynamic)
+
+	tests := []struct {
+		name        string
+		tx          *types.Transaction
+		protected   bool
+		lowS        bool
+		signingHash common.Hash
+		v           func(yParity uint64) uint64
+	}{
+		{"pre-EIP-155 legacy", homesteadTx, false, true, types.HomesteadSigner{}.Hash(homesteadTx),
+			func(y uint64) uint64 { return 27 + y }},
+		{"EIP-155 legacy", eip155Tx, true, true, types.NewEIP155Signer(big.NewInt(1)).Hash(eip155Tx),
+			func(y uint64) uint64 { return 1*2 + 35 + y }},
+		{"EIP-1559", dynamicTx, true, true, types.NewLondonSigner(big.NewInt(1)).Hash(dynamicTx),
+			func(y uint64) uint64 { return y }},
+		{"high s", highS(homesteadTx), false, false, types.HomesteadSigner{}.Hash(homesteadTx),
+			func(y uint64) uint64 { return 27 + y }},
+	}
+	for _, tt := range tests {
+		sig := signatureOf(tt.tx)
+		if sig.Protected != tt.protected || sig.LowS != tt.lowS {
+			t.Errorf("%s: protected %v, low s %v, want %v, %v", tt.name, sig.Protected, sig.LowS, tt.protected, tt.lowS)
+		}
+		if sig.SigningHash != tt.signingHash.Hex() {
+			t.Errorf("%s: signing hash %s, want %s", tt.name, sig.SigningHash, tt.signingHash.Hex())
+		}
+		if sig.YParity > 1 || sig.V.Uint64() != tt.v(uint64(sig.YParity)) {
+			t.Errorf("%s: v %v with y-parity %d", tt.name, sig.V, sig.YParity)
+		}
+	}
+
+	senderTests := []struct {
+		name    string
+		tx      *types.Transaction
+		header  *types.Header
+		wantErr error
+	}{
+		{"pre-EIP-155 legacy at homestead", homesteadTx, homesteadBlock, nil},
+		{"pre-EIP-155 legacy at london", homesteadTx, londonBlock, nil},
+		{"EIP-155 legacy before spurious dragon", eip155Tx, homesteadBlock, types.ErrInvalidSig},
+		{"EIP-155 legacy at spurious dragon", eip155Tx, eip155Block, nil},
+		{"EIP-1559 before london", dynamicTx, berlinBlock, types.ErrTxTypeNotSupported},
+		{"EIP-1559 at london", dynamicTx, londonBlock, nil},
+		{"EIP-1559 pending", dynamicTx, nil, nil},
+		{"high s at frontier", highS(homesteadTx), frontierBlock, nil},
+		{"high s at homestead", highS(homesteadTx), homesteadBlock, types.ErrInvalidSig},
+		{"high s pending", highS(homesteadTx), nil, nil}, // may be a genuine Frontier transaction
+	}
+	for _, tt := range senderTests {
+		from, err := GetSenderAt(tt.tx, chain.Mainnet, tt.header)
+		switch {
+		case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.wantErr)
+		case tt.wantErr == nil && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr == nil && from != sender:
+			t.Errorf("%s: sender %s, want %s", tt.name, from.Hex(), sender.Hex())
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package sigdb maps 4-byte function selectors to the signatures they may
+// stand for, offline: from a database embedded in getho and a local one
+// extended by importing 4byte.directory or openchain.xyz dumps.
+package sigdb
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// embedded holds the selectors of widely used functions, one
+// "<selector> <signature>" line each.
+//
+//go:embed selectors.txt
+var embedded string
+
+// Entry is a function signature and its selector.
+type Entry struct {
+	Selector  [4]byte
+	Signature string // canonical, e.g. "transfer(address,uint256)"
+}
+
+// NewEntry returns the entry of a signature, computing its selector.
+func NewEntry(signature string) Entry {
+	var e Entry
+	copy(e.Selector[:], crypto.Keccak256([]byte(signature))[:4])
+	e.Signature = signature
+	return e
+}
+
+// DB is a selector database. A selector can have several signatures, as
+// selectors are only 4 bytes of a hash.
+type DB struct {
+	sigs map[[4]byte][]string
+}
+
+// New returns an empty database.
+func New() *DB {
+	return &DB{sigs: make(map[[4]byte][]string)}
+}
+
+// Load returns the embedded database extended with the local one, if any.
+func Load() (*DB, error) {
+	db := New()
+	entries, _, err := parseText([]byte(embedded))
+	if err != nil {
+		return nil, fmt.Errorf("embedded selector database: %w", err)
+	}
+	for _, e := range entries {
+		db.Add(e)
+	}
+
+	path, err := LocalPath()
+	if err != nil {
+		return db, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entries, _, err = parseText(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, e := range entries {
+		db.Add(e)
+	}
+	return db, nil
+}
+
+// Add adds an entry, reporting whether it was new.
+func (db *DB) Add(e Entry) bool {
+	for _, sig := range db.sigs[e.Selector] {
+		if sig == e.Signature {
+			return false
+		}
+	}
+	db.sigs[e.Selector] = append(db.sigs[e.Selector], e.Signature)
+	return true
+}
+
+// Lookup returns the signatures of a selector, in the order they were
+// added: embedded ones first.
+func (db *DB) Lookup(selector []byte) []string {
+	var key [4]byte
+	if len(selector) < len(key) {
+		return nil
+	}
+	copy(key[:], selector)
+	return db.sigs[key]
+}
+
+// LocalPath returns the location of the local selector database:
+// $GETHO_SELECTORS if set, otherwise getho/selectors.txt in the user
+// configuration directory.
+func LocalPath() (string, error) {
+	if path := os.Getenv("GETHO_SELECTORS"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "getho", "selectors.txt"), nil
+}
+
+// Import adds the entries that db does not hold yet to the local database
+// and to db, and returns how many were added.
+func Import(db *DB, entries []Entry) (int, error) {
+	path, err := LocalPath()
+	if err != nil {
+		return 0, err
+	}
+	var b strings.Builder
+	added := 0
+	for _, e := range entries {
+		if db.Add(e) {
+			fmt.Fprintf(&b, "%x %s\n", e.Selector, e.Signature)
+			added++
+		}
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return added, f.Close()
+}
+
+// Parse reads a signature dump in one of these formats:
+//
+//   - 4byte.directory API responses (/api/v1/signatures/), or a bare array
+//     of their results;
+//   - openchain.xyz signature lookups (/signature-database/v1/lookup),
+//     whose function signatures are taken;
+//   - text, one signature per line, optionally preceded by its selector and
+//     a space, tab or comma. Lines starting with # are ignored.
+//
+// Signatures that are malformed or do not hash to the given selector are
+// skipped and counted.
+func Parse(data []byte) (entries []Entry, skipped int, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || (data[0] != '{' && data[0] != '[') {
+		return parseText(data)
+	}
+
+	type fourByteResult struct {
+		TextSignature string `json:"text_signature"`
+		HexSignature  string `json:"hex_signature"`
+	}
+	var results []fourByteResult
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, 0, fmt.Errorf("invalid 4byte dump: %w", err)
+		}
+	} else {
+		var dump struct {
+			Results []fourByteResult `json:"results"`
+			Result  struct {
+				Function map[string][]struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &dump); err != nil {
+			return nil, 0, fmt.Errorf("invalid signature dump: %w", err)
+		}
+		results = dump.Results
+		selectors := make([]string, 0, len(dump.Result.Function))
+		for selector := range dump.Result.Function {
+			selectors = append(selectors, selector)
+		}
+		sort.Strings(selectors)
+		for _, selector := range selectors {
+			for _, sig := range dump.Result.Function[selector] {
+				results = append(results, fourByteResult{TextSignature: sig.Name, HexSignature: selector})
+			}
+		}
+	}
+
+	for _, r := range results {
+		if e, ok := checkEntry(r.HexSignature, r.TextSignature); ok {
+			entries = append(entries, e)
+		} else {
+			skipped++
+		}
+	}
+	return entries, skipped, nil
+}
+
+// parseText reads the text format described on Parse.
+func parseText(data []byte) (entries []Entry, skipped int, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		selector, signature := "", line
+		if i := strings.IndexAny(line, " \t,"); i >= 0 && !strings.Contains(line[:i], "(") {
+			selector, signature = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if e, ok := checkEntry(selector, signature); ok {
+			entries = append(entries, e)
+		} else {
+			skipped++
+		}
+	}
+	return entries, skipped, scanner.Err()
+}
+
+// checkEntry returns the entry of signature if it is well-formed and hashes
+// to selector (0x-prefixed or not; empty to skip the check).
+func checkEntry(selector, signature string) (Entry, bool) {
+	if !wellFormed(signature) {
+		return Entry{}, false
+	}
+	e := NewEntry(signature)
+	if selector == "" {
+		return e, true
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(selector, "0x"))
+	return e, err == nil && bytes.Equal(b, e.Selector[:])
+}
+
+// wellFormed reports whether signature looks like a canonical function
+// signature: an identifier followed by parenthesized types, without spaces
+// or names.
+func wellFormed(signature string) bool {
+	open := strings.IndexByte(signature, '(')
+	if open <= 0 || !strings.HasSuffix(signature, ")") || strings.ContainsAny(signature, " \t") {
+		return false
+	}
+	for i, c := range signature[:open] {
+		if !(c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	depth := 0
+	for _, c := range signature[open:] {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
@@ -0,0 +1,147 @@
+package sigdb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Signatures of the transfer(address,uint256) selector, 0xa9059cbb, and of
+// approve(address,uint256), 0x095ea7b3, as found in 4byte.directory.
+const (
+	transferSig  = "transfer(address,uint256)"
+	collisionSig = "many_msg_babbage(bytes1)"
+	approveSig   = "approve(address,uint256)"
+	szaboSig     = "sign_szabo_bytecode(bytes16,uint128)"
+)
+
+func signatures(entries []Entry) []string {
+	var sigs []string
+	for _, e := range entries {
+		sigs = append(sigs, e.Signature)
+	}
+	return sigs
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		dump    string
+		want    []string
+		skipped int
+	}{
+		{
+			name: "4byte.directory response",
+			dump: `{"count": 3, "next": null, "results": [
+				{"id": 145, "text_signature": "transfer(address,uint256)", "hex_signature": "0xa9059cbb"},
+				{"id": 31780, "text_signature": "many_msg_babbage(bytes1)", "hex_signature": "0xa9059cbb"},
+				{"id": 2, "text_signature": "approve(address,uint256)", "hex_signature": "0xa9059cbb"}
+			]}`,
+			want:    []string{transferSig, collisionSig},
+			skipped: 1, // approve does not hash to 0xa9059cbb
+		},
+		{
+			name: "4byte.directory results",
+			dump: `[
+				{"text_signature": "approve(address,uint256)", "hex_signature": "0x095ea7b3"},
+				{"text_signature": "approve(address to, uint256 amount)", "hex_signature": "0x095ea7b3"}
+			]`,
+			want:    []string{approveSig},
+			skipped: 1,
+		},
+		{
+			name: "openchain.xyz lookup",
+			dump: `{"ok": true, "result": {"event": {}, "function": {
+				"0xa9059cbb": [{"name": "transfer(address,uint256)", "filtered": false}],
+				"0x095ea7b3": [{"name": "approve(address,uint256)", "filtered": false}, {"name": "sign_szabo_bytecode(bytes16,uint128)", "filtered": true}]
+			}}}`,
+			want: []string{approveSig, szaboSig, transferSig}, // by selector
+		},
+		{
+			name: "text",
+			dump: "# selectors\n" +
+				"a9059cbb transfer(address,uint256)\n" +
+				"0xa9059cbb\tmany_msg_babbage(bytes1)\n" +
+				"095ea7b3,approve(address,uint256)\n" +
+				"\n" +
+				"sign_szabo_bytecode(bytes16,uint128)\n" +
+				"23b872dd transfer(address,uint256)\n" + // another selector's
+				"a9059cbb transfer(address to,uint256 amount)\n" +
+				"balanceOf\n",
+			want:    []string{transferSig, collisionSig, approveSig, szaboSig},
+			skipped: 3,
+		},
+		{name: "empty", dump: " \n"},
+	}
+	for _, tt := range tests {
+		entries, skipped, err := Parse([]byte(tt.dump))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := signatures(entries); !reflect.DeepEqual(got, tt.want) || skipped != tt.skipped {
+			t.Errorf("%s: %q with %d skipped, want %q with %d", tt.name, got, skipped, tt.want, tt.skipped)
+		}
+		for _, e := range entries {
+			if e != NewEntry(e.Signature) {
+				t.Errorf("%s: selector %x of %s", tt.name, e.Selector, e.Signature)
+			}
+		}
+	}
+
+	for _, dump := range []string{`{"results": 1}`, `[{"text_signature": 1}]`, `{`} {
+		if _, _, err := Parse([]byte(dump)); err == nil {
+			t.Errorf("Parse(%s): no error", dump)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "getho", "selectors.txt")
+	t.Setenv("GETHO_SELECTORS", path)
+
+	db, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Lookup([]byte{0xa9, 0x05, 0x9c, 0xbb}); !reflect.DeepEqual(got, []string{transferSig}) {
+		t.Fatalf("embedded signatures of 0xa9059cbb = %q", got)
+	}
+
+	// Embedded signatures and duplicates are not written.
+	entries := []Entry{NewEntry(transferSig), NewEntry(collisionSig), NewEntry(szaboSig), NewEntry(collisionSig)}
+	added, err := Import(db, entries)
+	if err != nil || added != 2 {
+		t.Fatalf("Import = %d, %v, want 2 added", added, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a9059cbb " + collisionSig + "\n095ea7b3 " + szaboSig + "\n"; string(data) != want {
+		t.Errorf("local database = %q, want %q", data, want)
+	}
+	if added, err := Import(db, entries); err != nil || added != 0 {
+		t.Errorf("second Import = %d, %v, want none added", added, err)
+	}
+
+	// A fresh load has the local signatures after the embedded ones.
+	if db, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Lookup([]byte{0xa9, 0x05, 0x9c, 0xbb, 0x00}); !reflect.DeepEqual(got, []string{transferSig, collisionSig}) {
+		t.Errorf("signatures of 0xa9059cbb = %q, want %q", got, []string{transferSig, collisionSig})
+	}
+	if got := db.Lookup([]byte{0x09, 0x5e}); got != nil {
+		t.Errorf("Lookup of 2 bytes = %q, want none", got)
+	}
+
+	if err := os.WriteFile(path, []byte("a9059cbb transfer(address,uint256)\n"+strings.Repeat("x", 1<<21)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Load of an unreadable local database: error %v", err)
+	}
+}
@@ -0,0 +1,537 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/luckify/getho/internal/client"
+	"github.com/luckify/getho/internal/decoder"
+)
+
+// API selects the JSON-RPC namespace a ClientTracer gets traces from.
+type API string
+
+const (
+	// APIAuto uses geth's debug namespace and falls back to the trace
+	// namespace on endpoints that do not serve it.
+	APIAuto API = "auto"
+	// APIGeth uses debug_traceTransaction.
+	APIGeth API = "geth"
+	// APIParity uses the Parity-style trace_transaction and
+	// trace_replayTransaction of Erigon and Nethermind.
+	APIParity API = "parity"
+)
+
+// ParseAPI parses a trace API name, defaulting to APIAuto when empty.
+func ParseAPI(s string) (API, error) {
+	switch API(s) {
+	case "", APIAuto:
+		return APIAuto, nil
+	case APIGeth, APIParity:
+		return API(s), nil
+	default:
+		return "", fmt.Errorf("unknown trace API %q (expected geth, parity or auto)", s)
+	}
+}
+
+// ClientTracer implements the Tracer interface on top of a client.Client,
+// using geth's debug_traceTransaction or, on clients implementing
+// client.ParityTracer, the Parity-style trace namespace.
+type ClientTracer struct {
+	client client.Client
+	opts   client.TraceOptions
+	api    API
+}
+
+// NewClientTracer creates a tracer that traces transactions through c,
+// choosing the trace API automatically.
+//
+// opts selects the geth tracer; nil uses the call tracer. Only the call
+// tracer and the struct logger produce call frames.
+func NewClientTracer(c client.Client, opts *client.TraceOptions) *ClientTracer {
+	return NewClientTracerForAPI(c, opts, APIAuto)
+}
+
+// NewClientTracerForAPI creates a tracer that traces transactions through c
+// with the given trace API.
+//
+// With APIParity the call tracer maps to trace_transaction, the struct
+// logger to the trace and vmTrace of trace_replayTransaction and the
+// prestate tracer to its stateDiff. Tracer timeouts are not supported there.
+func NewClientTracerForAPI(c client.Client, opts *client.TraceOptions, api API) *ClientTracer {
+	t := &ClientTracer{client: c, opts: client.TraceOptions{Tracer: client.TracerCall}, api: api}
+	if opts != nil {
+		t.opts = *opts
+	}
+	return t
+}
+
+// Trace generates an execution trace for a transaction hash.
+func (t *ClientTracer) Trace(ctx context.Context, txHash string) (*Trace, error) {
+	hash := common.HexToHash(txHash)
+	if t.api == APIParity {
+		return t.traceParity(ctx, hash)
+	}
+	trace, err := t.traceGeth(ctx, hash)
+	if t.fallBack(err) {
+		return t.traceParity(ctx, hash)
+	}
+	return trace, err
+}
+
+// Prestate returns the accounts touched by a transaction, as reported by
+// geth's prestate tracer or derived from a Parity stateDiff. DiffMode in the
+// tracer's options selects pre/post output.
+func (t *ClientTracer) Prestate(ctx context.Context, txHash string) (*client.PrestateTrace, error) {
+	hash := common.HexToHash(txHash)
+	if t.api == APIParity {
+		return t.prestateParity(ctx, hash)
+	}
+	state, err := t.prestateGeth(ctx, hash)
+	if t.fallBack(err) {
+		return t.prestateParity(ctx, hash)
+	}
+	return state, err
+}
+
+// fallBack reports whether a failed geth trace should be retried with the
+// trace namespace.
+func (t *ClientTracer) fallBack(err error) bool {
+	if t.api != APIAuto || !errors.Is(err, client.ErrMethodUnsupported) {
+		return false
+	}
+	_, ok := t.client.(client.ParityTracer)
+	return ok
+}
+
+// traceGeth traces a transaction with debug_traceTransaction.
+func (t *ClientTracer) traceGeth(ctx context.Context, hash common.Hash) (*Trace, error) {
+	result, err := t.client.TraceTransaction(ctx, hash, &t.opts)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("transaction not found: %s", hash.Hex())
+	}
+
+	switch {
+	case result.Call != nil:
+		return FromCallTrace(hash, result.Call), nil
+	case result.StructLogs != nil:
+		root, err := t.rootFrame(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		return FromStructLogs(hash, root, result.StructLogs), nil
+	default:
+		return nil, errors.New("tracer result does not contain call frames")
+	}
+}
+
+// traceParity traces a transaction with the trace namespace.
+func (t *ClientTracer) traceParity(ctx context.Context, hash common.Hash) (*Trace, error) {
+	p, err := t.parityTracer()
+	if err != nil {
+		return nil, err
+	}
+
+	var trace *Trace
+	switch t.opts.Tracer {
+	case client.TracerCall:
+		traces, err := p.TraceParityTransaction(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		if traces == nil {
+			return nil, fmt.Errorf("transaction not found: %s", hash.Hex())
+		}
+		trace = FromParityTraces(hash, traces)
+	case client.TracerStructLog:
+		replay, err := p.ReplayParityTransaction(ctx, hash, client.ParityTraceCalls, client.ParityTraceVM)
+		if err != nil {
+			return nil, err
+		}
+		if replay == nil {
+			return nil, fmt.Errorf("transaction not found: %s", hash.Hex())
+		}
+		trace = FromParityTraces(hash, replay.Trace)
+		AttachVMTrace(trace, replay.VMTrace)
+	default:
+		return nil, errors.New("tracer result does not contain call frames")
+	}
+	if len(trace.Frames) == 0 {
+		return nil, errors.New("trace contains no call frames")
+	}
+
+	if t.opts.OnlyTopCall {
+		trace.Frames = trace.Frames[:1]
+	}
+	if err := t.withReceiptGas(ctx, hash, trace); err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
+
+// rootFrame returns the frame of the transaction itself, with the sender,
+// recipient and value that struct logs do not report. The recipient of a
+// contract creation is the created contract.
+func (t *ClientTracer) rootFrame(ctx context.Context, hash common.Hash) (CallFrame, error) {
+	bundle, err := client.GetTransactionBundle(ctx, t.client, hash)
+	if errors.Is(err, types.ErrTxTypeNotSupported) {
+		return t.rawRootFrame(ctx, hash)
+	}
+	if err != nil {
+		return CallFrame{}, err
+	}
+	if bundle == nil {
+		return CallFrame{}, fmt.Errorf("transaction not found: %s", hash.Hex())
+	}
+
+	tx := bundle.Transaction
+	from, err := decoder.GetSenderAt(tx, nil, bundle.Header)
+	if err != nil {
+		return CallFrame{}, fmt.Errorf("failed to recover sender: %w", err)
+	}
+	root := CallFrame{Type: CallTypeCall, From: from.Hex(), Value: tx.Value()}
+	if tx.To() != nil {
+		root.To = tx.To().Hex()
+	} else {
+		root.Type = CallTypeCreate
+		if bundle.Receipt != nil {
+			root.To = bundle.Receipt.ContractAddress.Hex()
+		}
+	}
+	return root, nil
+}
+
+// rawRootFrame is rootFrame for transaction types that go-ethereum cannot
+// decode. These cannot create contracts.
+func (t *ClientTracer) rawRootFrame(ctx context.Context, hash common.Hash) (CallFrame, error) {
+	raw, err := client.GetRawTransaction(ctx, t.client, hash)
+	if err != nil {
+		return CallFrame{}, err
+	}
+	if raw == nil {
+		return CallFrame{}, fmt.Errorf("transaction not found: %s", hash.Hex())
+	}
+	tx, err := decoder.NewEthereumDecoder().DecodeTransaction(raw)
+	if err != nil {
+		return CallFrame{}, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	return CallFrame{Type: CallTypeCall, From: tx.From, To: tx.To, Value: tx.Value}, nil
+}
+
+// withReceiptGas sets the root frame's gas figures of a Parity-style trace,
+// which exclude intrinsic gas, to the transaction's gas limit and the
+// receipt's gas used, as geth reports them.
+func (t *ClientTracer) withReceiptGas(ctx context.Context, hash common.Hash, trace *Trace) error {
+	bundle, err := client.GetTransactionBundle(ctx, t.client, hash)
+	if errors.Is(err, types.ErrTxTypeNotSupported) {
+		return t.withRawReceiptGas(ctx, hash, trace)
+	}
+	if err != nil {
+		return err
+	}
+	if bundle == nil || bundle.Receipt == nil {
+		return nil
+	}
+	trace.Frames[0].GasLimit = bundle.Transaction.Gas()
+	trace.Frames[0].GasUsed = bundle.Receipt.GasUsed
+	trace.TotalGasUsed = bundle.Receipt.GasUsed
+	return nil
+}
+
+// withRawReceiptGas is withReceiptGas for transaction types that go-ethereum
+// cannot decode, such as EIP-7702 set-code transactions: the transaction is
+// fetched in its binary encoding and decoded by getho.
+func (t *ClientTracer) withRawReceiptGas(ctx context.Context, hash common.Hash, trace *Trace) error {
+	raw, err := client.GetRawTransaction(ctx, t.client, hash)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return nil
+	}
+	receipt, err := t.client.GetTransactionReceipt(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if receipt == nil {
+		return nil
+	}
+	tx, err := decoder.NewEthereumDecoder().DecodeTransaction(raw)
+	if err != nil {
+		return fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	trace.Frames[0].GasLimit = tx.GasLimit
+	trace.Frames[0].GasUsed = receipt.GasUsed
+	trace.TotalGasUsed = receipt.GasUsed
+	return nil
+}
+
+// prestateGeth runs geth's prestate tracer.
+func (t *ClientTracer) prestateGeth(ctx context.Context, hash common.Hash) (*client.PrestateTrace, error) {
+	opts := t.opts
+	opts.Tracer = client.TracerPrestate
+	result, err := t.client.TraceTransaction(ctx, hash, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("transaction not found: %s", hash.Hex())
+	}
+	return result.Prestate, nil
+}
+
+// prestateParity derives prestate output from a Parity stateDiff.
+func (t *ClientTracer) prestateParity(ctx context.Context, hash common.Hash) (*client.PrestateTrace, error) {
+	p, err := t.parityTracer()
+	if err != nil {
+		return nil, err
+	}
+	replay, err := p.ReplayParityTransaction(ctx, hash, client.ParityTraceStateDiff)
+	if err != nil {
+		return nil, err
+	}
+	if replay == nil {
+		return nil, fmt.Errorf("transaction not found: %s", hash.Hex())
+	}
+	return client.PrestateFromStateDiff(replay.StateDiff, t.opts.DiffMode)
+}
+
+// parityTracer returns the client's Parity-style tracing methods.
+func (t *ClientTracer) parityTracer() (client.ParityTracer, error) {
+	p, ok := t.client.(client.ParityTracer)
+	if !ok {
+		return nil, errors.New("the trace namespace requires a JSON-RPC endpoint")
+	}
+	return p, nil
+}
+
+// FromCallTrace converts geth call tracer output into a Trace, flattening
+// the call tree in depth-first order.
+//
+// The call tracer does not report individual opcodes, so OpcodeStats only
+// counts direct sub-calls and emitted logs (when captured with logs).
+func FromCallTrace(txHash common.Hash, root *client.CallTrace) *Trace {
+	trace := &Trace{
+		TxHash:       txHash.Hex(),
+		TotalGasUsed: uint64(root.GasUsed),
+		Error:        callError(root),
+	}
+
+	var walk func(frame *client.CallTrace, depth int)
+	walk = func(frame *client.CallTrace, depth int) {
+		cf := CallFrame{
+			Type:     CallType(strings.ToUpper(frame.Type)),
+			From:     frame.From.Hex(),
+			Depth:    depth,
+			GasLimit: uint64(frame.Gas),
+			GasUsed:  uint64(frame.GasUsed),
+			Error:    callError(frame),
+		}
+		if frame.To != nil {
+			cf.To = frame.To.Hex()
+		}
+		if frame.Value != nil {
+			cf.Value = frame.Value.ToInt()
+		}
+		cf.Opcodes.Logs = uint64(len(frame.Logs))
+		cf.Opcodes.Calls = uint64(len(frame.Calls))
+		trace.Frames = append(trace.Frames, cf)
+
+		for i := range frame.Calls {
+			walk(&frame.Calls[i], depth+1)
+		}
+	}
+	walk(root, 0)
+
+	return trace
+}
+
+// callError combines a call frame's error and decoded revert reason.
+func callError(frame *client.CallTrace) string {
+	if frame.RevertReason != "" {
+		return frame.Error + ": " + frame.RevertReason
+	}
+	return frame.Error
+}
+
+// FromStructLogs converts geth struct logger output into a Trace. root is
+// the frame of the transaction itself, whose type, addresses and value the
+// struct logs do not report; its gas figures and opcode counts are filled in.
+//
+// Call frames are reconstructed from depth changes between consecutive
+// opcodes. The callee address and value are read from the stack of the
+// calling opcode, and the address of a created contract from the stack once
+// the creation returned, so they are only available if the trace captured
+// stacks. The caller of a frame is the contract whose code made the call in
+// the context of its storage: within DELEGATECALL and CALLCODE frames, that
+// of the frame they were entered from.
+// Per-frame gas usage is derived from remaining gas and is approximate for
+// frames that end abnormally.
+func FromStructLogs(txHash common.Hash, root CallFrame, logs *client.StructLogTrace) *Trace {
+	trace := &Trace{
+		TxHash:       txHash.Hex(),
+		TotalGasUsed: logs.Gas,
+	}
+	if logs.Failed {
+		trace.Error = "execution failed"
+	}
+	if len(logs.StructLogs) == 0 {
+		return trace
+	}
+
+	// open holds indices into trace.Frames for the current call stack, and
+	// callers the opcode that entered each of its frames.
+	var open []int
+	var callers []*client.StructLog
+	var last *client.StructLog
+
+	// contexts holds, for each frame, the index of the frame whose callee
+	// the frame executes as. Senders are resolved from it once the
+	// addresses of created contracts are known.
+	var contexts []int
+
+	// unwind closes the frames deeper than depth. The innermost one ends
+	// with the last opcode; each frame it returns into ends with the call
+	// that entered the frame above, whose gas came back to it.
+	unwind := func(depth int, next *client.StructLog) {
+		op, returned := last, uint64(0)
+		for len(open) > 0 && trace.Frames[open[len(open)-1]].Depth > depth {
+			frame := &trace.Frames[open[len(open)-1]]
+			if op != nil {
+				remaining := op.Gas - min(op.GasCost, op.Gas) + returned
+				if frame.GasLimit > remaining {
+					frame.GasUsed = frame.GasLimit - remaining
+				}
+				if op.Error != "" && frame.Error == "" {
+					frame.Error = op.Error
+				}
+			}
+			returned = frame.GasLimit - frame.GasUsed
+			op = callers[len(callers)-1]
+			open, callers = open[:len(open)-1], callers[:len(callers)-1]
+
+			// A creating opcode leaves the new contract's address on the
+			// stack of the next opcode of its frame.
+			isCreate := frame.Type == CallTypeCreate || frame.Type == CallTypeCreate2
+			if isCreate && next != nil && len(next.Stack) > 0 && trace.Frames[open[len(open)-1]].Depth == depth {
+				if address := stackAddress(next.Stack[len(next.Stack)-1]); address != (common.Address{}).Hex() {
+					frame.To = address
+				}
+			}
+		}
+	}
+
+	first := &logs.StructLogs[0]
+	root.Depth, root.GasLimit, root.GasUsed, root.Opcodes = 0, first.Gas, 0, OpcodeStats{}
+	if root.Type == "" {
+		root.Type = CallTypeCall
+	}
+	trace.Frames = append(trace.Frames, root)
+	open, callers, contexts = append(open, 0), append(callers, nil), append(contexts, 0)
+
+	// senders holds, for each frame, the frame whose callee made the call;
+	// the root frame has none.
+	senders := []int{-1}
+	for i := range logs.StructLogs {
+		log := &logs.StructLogs[i]
+		depth := log.Depth - 1
+
+		// Returned from one or more frames.
+		if depth < trace.Frames[open[len(open)-1]].Depth {
+			unwind(depth, log)
+		}
+		// Entered a new frame via the previous opcode.
+		if last != nil && depth > trace.Frames[open[len(open)-1]].Depth {
+			parent := open[len(open)-1]
+			trace.Frames = append(trace.Frames, newStructLogFrame(last, depth, log.Gas))
+			idx := len(trace.Frames) - 1
+			senders = append(senders, contexts[parent])
+			if last.Op == "DELEGATECALL" || last.Op == "CALLCODE" {
+				contexts = append(contexts, contexts[parent])
+			} else {
+				contexts = append(contexts, idx)
+			}
+			open, callers = append(open, idx), append(callers, last)
+		}
+
+		countOpcode(&trace.Frames[open[len(open)-1]].Opcodes, log.Op)
+		last = log
+	}
+	unwind(-1, nil)
+
+	for i := 1; i < len(trace.Frames); i++ {
+		trace.Frames[i].From = trace.Frames[senders[i]].To
+	}
+	if logs.Failed && trace.Frames[0].Error != "" {
+		trace.Error = trace.Frames[0].Error
+	}
+	return trace
+}
+
+// newStructLogFrame creates the frame entered by the call opcode in caller.
+// FromStructLogs fills in its sender.
+func newStructLogFrame(caller *client.StructLog, depth int, gas uint64) CallFrame {
+	frame := CallFrame{
+		Type:     CallType(caller.Op),
+		Depth:    depth,
+		GasLimit: gas,
+	}
+
+	// Stack items are reported bottom to top; call arguments sit at the top.
+	stack := caller.Stack
+	arg := func(n int) string {
+		if len(stack) <= n {
+			return ""
+		}
+		return stack[len(stack)-1-n]
+	}
+	switch caller.Op {
+	case "CALL", "CALLCODE":
+		frame.To = stackAddress(arg(1))
+		if v, ok := new(big.Int).SetString(strings.TrimPrefix(arg(2), "0x"), 16); ok {
+			frame.Value = v
+		}
+	case "DELEGATECALL", "STATICCALL":
+		frame.To = stackAddress(arg(1))
+	case "CREATE", "CREATE2":
+		if v, ok := new(big.Int).SetString(strings.TrimPrefix(arg(0), "0x"), 16); ok {
+			frame.Value = v
+		}
+	}
+	return frame
+}
+
+// stackAddress converts a stack word into a checksummed address.
+func stackAddress(word string) string {
+	if word == "" {
+		return ""
+	}
+	return common.HexToAddress(word).Hex()
+}
+
+// countOpcode updates stats with a single executed opcode.
+func countOpcode(stats *OpcodeStats, op string) {
+	stats.Total++
+	switch {
+	case op == "CALL" || op == "CALLCODE" || op == "DELEGATECALL" || op == "STATICCALL":
+		stats.Calls++
+	case op == "SLOAD":
+		stats.SLoads++
+	case op == "SSTORE":
+		stats.SStores++
+	case strings.HasPrefix(op, "LOG"):
+		stats.Logs++
+	case op == "REVERT":
+		stats.Reverts++
+	case op == "INVALID" || strings.HasPrefix(op, "opcode "):
+		stats.Invalids++
+	}
+}
@@ -0,0 +1,149 @@
+package tracer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/luckify/getho/internal/client"
+)
+
+// Addresses of the synthetic traces of the tests of this package.
+var (
+	sender   = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	proxy    = common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	impl     = common.HexToAddress("0x00000000000000000000000000000000000000c3")
+	token    = common.HexToAddress("0x00000000000000000000000000000000000000d4")
+	created  = common.HexToAddress("0x00000000000000000000000000000000000000e5")
+	registry = common.HexToAddress("0x00000000000000000000000000000000000000f6")
+)
+
+// checkFrames compares frames with want field by field.
+func checkFrames(t *testing.T, frames, want []CallFrame) {
+	t.Helper()
+	if len(frames) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(frames), len(want))
+	}
+	for i, w := range want {
+		f := frames[i]
+		if f.Type != w.Type || f.From != w.From || f.To != w.To || f.Depth != w.Depth || f.Error != w.Error {
+			t.Errorf("frame %d = %s %s -> %s depth %d error %q, want %s %s -> %s depth %d error %q",
+				i, f.Type, f.From, f.To, f.Depth, f.Error, w.Type, w.From, w.To, w.Depth, w.Error)
+		}
+		if f.GasLimit != w.GasLimit || f.GasUsed != w.GasUsed {
+			t.Errorf("frame %d gas = %d/%d, want %d/%d", i, f.GasUsed, f.GasLimit, w.GasUsed, w.GasLimit)
+		}
+		if (f.Value == nil) != (w.Value == nil) || (f.Value != nil && f.Value.Cmp(w.Value) != 0) {
+			t.Errorf("frame %d value = %v, want %v", i, f.Value, w.Value)
+		}
+		if f.Opcodes != w.Opcodes {
+			t.Errorf("frame %d opcodes = %+v, want %+v", i, f.Opcodes, w.Opcodes)
+		}
+	}
+}
+
+// word returns an address as a stack word.
+func word(a common.Address) string {
+	return common.BytesToHash(a[:]).Hex()
+}
+
+func TestFromStructLogs(t *testing.T) {
+	// The sender calls a proxy, which delegates to its implementation; the
+	// implementation calls a token, as the proxy. The proxy then creates a
+	// contract whose constructor calls a registry.
+	logs := &client.StructLogTrace{
+		Gas: 101000,
+		StructLogs: []client.StructLog{
+			{Op: "PUSH1", Gas: 100000, GasCost: 3, Depth: 1},
+			{Op: "DELEGATECALL", Gas: 99997, GasCost: 60000, Depth: 1,
+				Stack: []string{"0x0", "0x0", "0x0", "0x0", word(impl), "0xe678"}},
+			{Op: "PUSH1", Gas: 59000, GasCost: 3, Depth: 2},
+			{Op: "CALL", Gas: 58997, GasCost: 30000, Depth: 2,
+				Stack: []string{"0x0", "0x0", "0x0", "0x0", "0x5", word(token), "0x7148"}},
+			{Op: "STOP", Gas: 28000, Depth: 3},
+			{Op: "STOP", Gas: 56997, Depth: 2},
+			{Op: "CREATE", Gas: 39000, GasCost: 32000, Depth: 1,
+				Stack: []string{"0x20", "0x0", "0x0"}},
+			{Op: "PUSH1", Gas: 30000, GasCost: 3, Depth: 2},
+			{Op: "CALL", Gas: 29997, GasCost: 10000, Depth: 2,
+				Stack: []string{"0x0", "0x0", "0x0", "0x0", "0x0", word(registry), "0x2328"}},
+			{Op: "STOP", Gas: 8900, Depth: 3},
+			{Op: "RETURN", Gas: 18000, Depth: 2},
+			{Op: "STOP", Gas: 20000, Depth: 1, Stack: []string{word(created)}},
+		},
+	}
+	root := CallFrame{Type: CallTypeCall, From: sender.Hex(), To: proxy.Hex(), Value: big.NewInt(1)}
+	trace := FromStructLogs(common.Hash{1}, root, logs)
+
+	if trace.TotalGasUsed != 101000 || trace.Error != "" {
+		t.Errorf("total gas used = %d, error = %q, want 101000 and none", trace.TotalGasUsed, trace.Error)
+	}
+	checkFrames(t, trace.Frames, []CallFrame{
+		{Type: CallTypeCall, From: sender.Hex(), To: proxy.Hex(), Value: big.NewInt(1), GasLimit: 100000, GasUsed: 80000,
+			Opcodes: OpcodeStats{Total: 4, Calls: 1}},
+		{Type: CallTypeDelegateCall, From: proxy.Hex(), To: impl.Hex(), Depth: 1, GasLimit: 59000, GasUsed: 2003,
+			Opcodes: OpcodeStats{Total: 3, Calls: 1}},
+		// Called by the implementation's code, as the proxy.
+		{Type: CallTypeCall, From: proxy.Hex(), To: token.Hex(), Value: big.NewInt(5), Depth: 2, GasLimit: 28000,
+			Opcodes: OpcodeStats{Total: 1}},
+		{Type: CallTypeCreate, From: proxy.Hex(), To: created.Hex(), Value: new(big.Int), Depth: 1, GasLimit: 30000, GasUsed: 12000,
+			Opcodes: OpcodeStats{Total: 3, Calls: 1}},
+		// Called by the constructor, before the created address was known.
+		{Type: CallTypeCall, From: created.Hex(), To: registry.Hex(), Value: new(big.Int), Depth: 2, GasLimit: 8900,
+			Opcodes: OpcodeStats{Total: 1}},
+	})
+}
+
+func TestFromStructLogsFailed(t *testing.T) {
+	logs := &client.StructLogTrace{
+		Gas:    30000,
+		Failed: true,
+		StructLogs: []client.StructLog{
+			{Op: "PUSH1", Gas: 9000, GasCost: 3, Depth: 1},
+			{Op: "REVERT", Gas: 8997, Depth: 1, Error: "execution reverted"},
+		},
+	}
+	root := CallFrame{Type: CallTypeCreate, From: sender.Hex(), To: created.Hex()}
+	trace := FromStructLogs(common.Hash{1}, root, logs)
+
+	if trace.Error != "execution reverted" {
+		t.Errorf("error = %q, want execution reverted", trace.Error)
+	}
+	checkFrames(t, trace.Frames, []CallFrame{
+		{Type: CallTypeCreate, From: sender.Hex(), To: created.Hex(), GasLimit: 9000, GasUsed: 3,
+			Error: "execution reverted", Opcodes: OpcodeStats{Total: 2, Reverts: 1}},
+	})
+}
+
+func TestFromCallTrace(t *testing.T) {
+	call := &client.CallTrace{
+		Type: "CALL", From: sender, To: &proxy, Value: (*hexutil.Big)(big.NewInt(1)),
+		Gas: 100000, GasUsed: 80000,
+		Error: "execution reverted", RevertReason: "paused",
+		Calls: []client.CallTrace{
+			{
+				Type: "DELEGATECALL", From: proxy, To: &impl, Gas: 59000, GasUsed: 2003,
+				Logs: []client.CallLog{{Address: proxy}, {Address: proxy}},
+				Calls: []client.CallTrace{
+					{Type: "STATICCALL", From: proxy, To: &token, Gas: 28000, GasUsed: 1000},
+				},
+			},
+			{Type: "CREATE2", From: proxy, To: &created, Value: (*hexutil.Big)(new(big.Int)), Gas: 30000, GasUsed: 30000, Error: "out of gas"},
+		},
+	}
+	trace := FromCallTrace(common.Hash{1}, call)
+
+	if trace.TotalGasUsed != 80000 || trace.Error != "execution reverted: paused" {
+		t.Errorf("total gas used = %d, error = %q, want 80000 and execution reverted: paused", trace.TotalGasUsed, trace.Error)
+	}
+	checkFrames(t, trace.Frames, []CallFrame{
+		{Type: CallTypeCall, From: sender.Hex(), To: proxy.Hex(), Value: big.NewInt(1), GasLimit: 100000, GasUsed: 80000,
+			Error: "execution reverted: paused", Opcodes: OpcodeStats{Calls: 2}},
+		{Type: CallTypeDelegateCall, From: proxy.Hex(), To: impl.Hex(), Depth: 1, GasLimit: 59000, GasUsed: 2003,
+			Opcodes: OpcodeStats{Calls: 1, Logs: 2}},
+		{Type: CallTypeStaticCall, From: proxy.Hex(), To: token.Hex(), Depth: 2, GasLimit: 28000, GasUsed: 1000},
+		{Type: CallTypeCreate2, From: proxy.Hex(), To: created.Hex(), Value: new(big.Int), Depth: 1, GasLimit: 30000, GasUsed: 30000,
+			Error: "out of gas"},
+	})
+}
@@ -0,0 +1,196 @@
+package tracer
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/luckify/getho/internal/client"
+)
+
+// CallTypeSelfDestruct is a SELFDESTRUCT, which Parity-style traces report
+// as a frame of its own.
+const CallTypeSelfDestruct CallType = "SELFDESTRUCT"
+
+// errReverted is geth's error of a frame that ended in REVERT.
+const errReverted = "execution reverted"
+
+// parityErrors maps the error messages of Parity-style traces to geth's
+// wording, so that traces read the same whichever client produced them.
+var parityErrors = map[string]string{
+	"reverted":             errReverted,
+	"out of gas":           "out of gas",
+	"bad instruction":      "invalid opcode",
+	"bad jump destination": "invalid jump destination",
+	"stack underflow":      "stack underflow",
+	"out of stack":         "max call depth exceeded",
+}
+
+// FromParityTraces converts Parity-style trace output (trace_transaction or
+// the trace of trace_replayTransaction), as served by Erigon and Nethermind,
+// into a Trace.
+//
+// Parity-style frames are already flattened in depth-first order. Their gas
+// figures exclude the transaction's intrinsic gas, so the root frame's gas
+// and TotalGasUsed are lower than geth reports; ClientTracer replaces them
+// with the transaction's gas limit and the receipt's gas used. Logs are not
+// reported, and OpcodeStats only counts direct sub-calls unless a vmTrace is
+// attached with AttachVMTrace. Reverted frames without a result have no
+// GasUsed until then.
+func FromParityTraces(txHash common.Hash, traces []client.ParityTrace) *Trace {
+	trace := &Trace{TxHash: txHash.Hex()}
+
+	for i := range traces {
+		pt := &traces[i]
+		cf := CallFrame{
+			From:     pt.Action.From.Hex(),
+			Depth:    len(pt.TraceAddress),
+			GasLimit: uint64(pt.Action.Gas),
+			Error:    parityError(pt.Error),
+		}
+		// Failed frames come without a result from most clients. All but
+		// reverted frames consume their whole gas anyway; what a reverted
+		// frame used is only known from a vmTrace.
+		if pt.Result != nil {
+			cf.GasUsed = uint64(pt.Result.GasUsed)
+		} else if pt.Error != "" && cf.Error != errReverted {
+			cf.GasUsed = cf.GasLimit
+		}
+		if pt.Action.Value != nil {
+			cf.Value = pt.Action.Value.ToInt()
+		}
+
+		switch pt.Type {
+		case "call":
+			cf.Type = CallTypeCall
+			if pt.Action.CallType != "" {
+				cf.Type = CallType(strings.ToUpper(pt.Action.CallType))
+			}
+			if pt.Action.To != nil {
+				cf.To = pt.Action.To.Hex()
+			}
+			// Parity reports the caller's value for delegate calls, geth
+			// reports none.
+			if cf.Type == CallTypeDelegateCall || cf.Type == CallTypeStaticCall {
+				cf.Value = nil
+			}
+		case "create":
+			cf.Type = CallTypeCreate
+			if pt.Action.CreationMethod == "create2" {
+				cf.Type = CallTypeCreate2
+			}
+			if pt.Result != nil && pt.Result.Address != nil {
+				cf.To = pt.Result.Address.Hex()
+			}
+		case "suicide":
+			cf.Type = CallTypeSelfDestruct
+			if pt.Action.Address != nil {
+				cf.From = pt.Action.Address.Hex()
+			}
+			if pt.Action.RefundAddress != nil {
+				cf.To = pt.Action.RefundAddress.Hex()
+			}
+			if pt.Action.Balance != nil {
+				cf.Value = pt.Action.Balance.ToInt()
+			}
+		default:
+			// Block rewards are not part of any transaction.
+			continue
+		}
+		cf.Opcodes.Calls = uint64(pt.Subtraces)
+		trace.Frames = append(trace.Frames, cf)
+	}
+
+	if len(trace.Frames) > 0 {
+		trace.TotalGasUsed = trace.Frames[0].GasUsed
+		trace.Error = trace.Frames[0].Error
+	}
+	return trace
+}
+
+// parityError converts a Parity-style error message to geth's wording.
+func parityError(msg string) string {
+	if geth, ok := parityErrors[strings.ToLower(msg)]; ok {
+		return geth
+	}
+	return msg
+}
+
+// AttachVMTrace fills in the OpcodeStats of trace's frames from a Parity
+// vmTrace of the same transaction, and the gas used by reverted frames that
+// came without a result from the gas left after their last opcode.
+//
+// A vmTrace nests the code executions of frames rather than listing frames,
+// so its sub-traces are matched to child frames in order. Calls into
+// precompiles have a frame but, with some clients, no sub-trace; they are
+// skipped when the counts differ.
+func AttachVMTrace(trace *Trace, root *client.ParityVMTrace) {
+	if root == nil || len(trace.Frames) == 0 {
+		return
+	}
+
+	var walk func(vmTrace *client.ParityVMTrace, idx int)
+	walk = func(vmTrace *client.ParityVMTrace, idx int) {
+		stats := &trace.Frames[idx].Opcodes
+		*stats = OpcodeStats{}
+
+		subs := 0
+		for _, op := range vmTrace.Ops {
+			if op.Sub != nil {
+				subs++
+			}
+		}
+		children := childFrames(trace, idx)
+		if len(children) != subs {
+			children = withoutPrecompiles(trace, children)
+		}
+
+		next := 0
+		for _, op := range vmTrace.Ops {
+			name := op.Op
+			if name == "" && op.PC < uint64(len(vmTrace.Code)) {
+				name = vm.OpCode(vmTrace.Code[op.PC]).String()
+			}
+			countOpcode(stats, name)
+			if op.Sub != nil && next < len(children) {
+				walk(op.Sub, children[next])
+				next++
+			}
+		}
+
+		frame := &trace.Frames[idx]
+		if n := len(vmTrace.Ops); frame.Error == errReverted && frame.GasUsed == 0 && n > 0 {
+			if ex := vmTrace.Ops[n-1].Ex; ex != nil && ex.Used <= frame.GasLimit {
+				frame.GasUsed = frame.GasLimit - ex.Used
+			}
+		}
+	}
+	walk(root, 0)
+}
+
+// childFrames returns the indices of the direct children of frame idx.
+func childFrames(trace *Trace, idx int) []int {
+	var children []int
+	depth := trace.Frames[idx].Depth
+	for i := idx + 1; i < len(trace.Frames) && trace.Frames[i].Depth > depth; i++ {
+		if trace.Frames[i].Depth == depth+1 {
+			children = append(children, i)
+		}
+	}
+	return children
+}
+
+// withoutPrecompiles drops frames that call a precompiled contract.
+func withoutPrecompiles(trace *Trace, frames []int) []int {
+	precompiles := make(map[string]bool, len(vm.PrecompiledAddressesCancun))
+	for _, addr := range vm.PrecompiledAddressesCancun {
+		precompiles[addr.Hex()] = true
+	}
+	var kept []int
+	for _, i := range frames {
+		if !precompiles[trace.Frames[i].To] {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
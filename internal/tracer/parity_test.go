@@ -0,0 +1,110 @@
+package tracer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/luckify/getho/internal/client"
+)
+
+// parityCassette is a synthetic trace_transaction session, written by hand
+// in the format of Erigon's responses rather than recorded; its hashes and
+// addresses are made up. The transaction creates a contract with CREATE2,
+// makes a call that reverts after a successful delegate call, and reads the
+// created contract.
+const parityCassette = "testdata/parity.jsonl"
+
+var parityTx = common.HexToHash("0x5b7a3a1c4d0e7d3b4a6ccb0d0c7fb2e1c0b1e9f6d1d8a2f3b4c5d6e7f8091a2b")
+
+// newReplayClient returns a client answering from the cassette at path.
+func newReplayClient(t *testing.T, path string) *client.RPCClient {
+	t.Helper()
+	replayer, err := client.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := client.DefaultRPCOptions()
+	opts.Transport = replayer
+	c, err := client.NewRPCClientWithOptions(context.Background(), client.ReplayURL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestFromParityTraces(t *testing.T) {
+	c := newReplayClient(t, parityCassette)
+	traces, err := c.TraceParityTransaction(context.Background(), parityTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := FromParityTraces(parityTx, traces)
+
+	// The fixture uses the addresses of the synthetic struct logs.
+	value := big.NewInt(10_000_000_000_000_000) // 0.01 ether
+	want := []CallFrame{
+		{Type: CallTypeCall, From: sender.Hex(), To: proxy.Hex(), Value: value, Depth: 0, GasLimit: 0x2d4cc, GasUsed: 0x1d4c0, Opcodes: OpcodeStats{Calls: 3}},
+		{Type: CallTypeCreate2, From: proxy.Hex(), To: created.Hex(), Value: new(big.Int), Depth: 1, GasLimit: 0x1f4a0, GasUsed: 0x7d0c},
+		// The reverted call comes without a result, so its gas used is
+		// unknown; its delegate call succeeded before the revert.
+		{Type: CallTypeCall, From: proxy.Hex(), To: token.Hex(), Value: value, Depth: 1, GasLimit: 0x9c40, Opcodes: OpcodeStats{Calls: 1}, Error: "execution reverted"},
+		{Type: CallTypeDelegateCall, From: token.Hex(), To: impl.Hex(), Depth: 2, GasLimit: 0x8fc0, GasUsed: 0x1388},
+		{Type: CallTypeStaticCall, From: proxy.Hex(), To: created.Hex(), Depth: 1, GasLimit: 0x2710, GasUsed: 0x3e8},
+	}
+
+	if trace.TxHash != parityTx.Hex() {
+		t.Errorf("tx hash = %s, want %s", trace.TxHash, parityTx.Hex())
+	}
+	if trace.TotalGasUsed != 0x1d4c0 || trace.Error != "" {
+		t.Errorf("total gas used = %d, error = %q, want %d and none", trace.TotalGasUsed, trace.Error, 0x1d4c0)
+	}
+	checkFrames(t, trace.Frames, want)
+}
+
+func TestTraceParityTransactionNotFound(t *testing.T) {
+	c := newReplayClient(t, parityCassette)
+	traces, err := c.TraceParityTransaction(context.Background(), common.HexToHash("0xbad"))
+	if err != nil || traces != nil {
+		t.Errorf("TraceParityTransaction = %v, %v, want nil, nil", traces, err)
+	}
+}
+
+func TestFromParityTracesFailedGas(t *testing.T) {
+	to := common.HexToAddress("0x01")
+	traces := []client.ParityTrace{
+		{Type: "call", Action: client.ParityAction{CallType: "call", To: &to, Gas: 50000}, Result: &client.ParityTraceResult{GasUsed: 30000}, Subtraces: 2},
+		{Type: "call", Action: client.ParityAction{CallType: "call", To: &to, Gas: 10000}, Error: "Reverted", TraceAddress: []int{0}},
+		{Type: "call", Action: client.ParityAction{CallType: "call", To: &to, Gas: 8000}, Error: "Out of gas", TraceAddress: []int{1}},
+	}
+	trace := FromParityTraces(common.Hash{1}, traces)
+	if got := trace.Frames[1].GasUsed; got != 0 {
+		t.Errorf("reverted frame gas used = %d, want 0 without a vmTrace", got)
+	}
+	if got := trace.Frames[2].GasUsed; got != 8000 {
+		t.Errorf("out of gas frame gas used = %d, want its gas limit 8000", got)
+	}
+
+	// The reverted frame had 7000 gas left after its REVERT.
+	AttachVMTrace(trace, &client.ParityVMTrace{Ops: []client.ParityVMOp{
+		{Op: "CALL", Cost: 10000, Ex: &client.ParityVMExec{}, Sub: &client.ParityVMTrace{Ops: []client.ParityVMOp{
+			{Op: "PUSH1", Cost: 3, Ex: &client.ParityVMExec{Used: 9997}},
+			{Op: "REVERT", Ex: &client.ParityVMExec{Used: 7000}},
+		}}},
+		{Op: "CALL", Cost: 8000, Ex: &client.ParityVMExec{}, Sub: &client.ParityVMTrace{Ops: []client.ParityVMOp{
+			{Op: "INVALID"},
+		}}},
+		{Op: "STOP", Ex: &client.ParityVMExec{Used: 20000}},
+	}})
+	if got := trace.Frames[1].GasUsed; got != 3000 {
+		t.Errorf("reverted frame gas used = %d, want 3000 from the vmTrace", got)
+	}
+	if got := trace.Frames[2].GasUsed; got != 8000 {
+		t.Errorf("out of gas frame gas used = %d, want 8000", got)
+	}
+	if got := trace.Frames[1].Opcodes; got.Total != 2 || got.Reverts != 1 {
+		t.Errorf("reverted frame opcodes = %+v, want 2 with 1 revert", got)
+	}
+}
@@ -1,6 +1,9 @@
 package tracer
 
-import "math/big"
+import (
+	"context"
+	"math/big"
+)
 
 // Tracer provides execution tracing capabilities.
 //
@@ -8,7 +11,7 @@ import "math/big"
 // normalized, execution-layer focused model.
 type Tracer interface {
 	// Trace generates an opcode-level execution trace for a transaction hash.
-	Trace(txHash string) (*Trace, error)
+	Trace(ctx context.Context, txHash string) (*Trace, error)
 }
 
 // CallType represents the high-level kind of EVM call frame.